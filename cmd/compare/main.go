@@ -1,528 +1,570 @@
-// cmd/compare/main.go
-// Outil de benchmark comparatif LevelDB vs CouchDB
-
-package main
-
-import (
-    "bytes"
-    "encoding/json"
-    "flag"
-    "fmt"
-    "io"
-    "log"
-    "net/http"
-    "os"
-    "path/filepath"
-    "runtime"
-    "time"
-    
-    "leveldb-tp/pkg/leveldb"
-)
-
-type BenchmarkResult struct {
-    Database      string
-    WriteTime     time.Duration
-    ReadTime      time.Duration
-    BatchTime     time.Duration
-    SearchTime    time.Duration
-    DiskSizeMB    float64
-    MemoryUsageMB float64
-    WriteOpsPerSec float64
-    ReadOpsPerSec  float64
-}
-
-func main() {
-    var (
-        dataset  = flag.Int("dataset", 5000, "Nombre de documents pour le test")
-        db       = flag.String("db", "both", "Base à tester: leveldb|couchdb|both")
-        node     = flag.String("node", "node1", "Nœud LevelDB à utiliser")
-        couchURL = flag.String("couch", "http://localhost:5987", "URL CouchDB")
-        compare  = flag.Bool("compare", true, "Afficher tableau comparatif")
-    )
-    flag.Parse()
-    
-    log.SetFlags(0)
-    
-    fmt.Println("╔════════════════════════════════════════════════════╗")
-    fmt.Printf("║   Benchmark LevelDB vs CouchDB (%d docs)    ║\n", *dataset)
-    fmt.Println("╚════════════════════════════════════════════════════╝")
-    fmt.Println()
-    
-    var levelResult, couchResult BenchmarkResult
-    var couchAvailable bool
-    
-    // Test de connexion CouchDB si nécessaire
-    if *db == "couchdb" || *db == "both" {
-        fmt.Println("🔍 Test de connexion CouchDB...")
-        if err := testCouchDBConnection(*couchURL); err != nil {
-            fmt.Printf("❌ ERREUR: Impossible de se connecter à CouchDB (%s)\n", *couchURL)
-            fmt.Printf("   Détails: %v\n", err)
-            fmt.Println()
-            
-            if *db == "couchdb" {
-                fmt.Println("💡 Vérifiez que:")
-                fmt.Println("   • CouchDB est démarré")
-                fmt.Println("   • L'URL est correcte (défaut: http://localhost:5987)")
-                fmt.Println("   • Les credentials sont valides (admin/ecommerce2024)")
-                os.Exit(1)
-            } else {
-                fmt.Println("⚠️  Benchmark CouchDB ignoré, uniquement LevelDB sera testé")
-                fmt.Println()
-                couchAvailable = false
-            }
-        } else {
-            fmt.Println("✅ Connexion CouchDB réussie!")
-            fmt.Println()
-            couchAvailable = true
-        }
-    }
-    
-    if *db == "leveldb" || *db == "both" {
-        fmt.Println("🔧 Benchmark LevelDB...")
-        fmt.Println()
-        levelResult = benchmarkLevelDB(*node, *dataset)
-        printResults(levelResult)
-        fmt.Println()
-    }
-    
-    if (*db == "couchdb" || *db == "both") && couchAvailable {
-        fmt.Println("🔧 Benchmark CouchDB...")
-        fmt.Println()
-        couchResult = benchmarkCouchDB(*couchURL, *dataset)
-        printResults(couchResult)
-        fmt.Println()
-    }
-    
-    if *compare && *db == "both" && couchAvailable {
-        fmt.Println("📊 Comparaison détaillée...")
-        fmt.Println()
-        compareResults(levelResult, couchResult)
-    }
-}
-
-// testCouchDBConnection vérifie la connexion à CouchDB
-func testCouchDBConnection(baseURL string) error {
-    client := &http.Client{
-        Timeout: 5 * time.Second,
-    }
-    
-    // Test 1: Vérifier que le serveur répond
-    req, err := http.NewRequest("GET", baseURL, nil)
-    if err != nil {
-        return fmt.Errorf("création requête impossible: %v", err)
-    }
-    
-    resp, err := client.Do(req)
-    if err != nil {
-        return fmt.Errorf("serveur inaccessible: %v", err)
-    }
-    defer resp.Body.Close()
-    
-    if resp.StatusCode != http.StatusOK {
-        return fmt.Errorf("serveur répond avec status %d", resp.StatusCode)
-    }
-    
-    // Test 2: Vérifier l'authentification
-    req, err = http.NewRequest("GET", baseURL+"/_all_dbs", nil)
-    if err != nil {
-        return err
-    }
-    req.SetBasicAuth("admin", "ecommerce2024")
-    
-    resp, err = client.Do(req)
-    if err != nil {
-        return fmt.Errorf("erreur lors de la requête: %v", err)
-    }
-    defer resp.Body.Close()
-    
-    if resp.StatusCode == http.StatusUnauthorized {
-        return fmt.Errorf("authentification échouée (vérifiez les credentials)")
-    }
-    
-    if resp.StatusCode != http.StatusOK {
-        return fmt.Errorf("erreur serveur (status %d)", resp.StatusCode)
-    }
-    
-    return nil
-}
-
-// benchmarkLevelDB exécute tous les tests sur LevelDB
-func benchmarkLevelDB(node string, dataset int) BenchmarkResult {
-    nodePath := filepath.Join("leveldb-stores", node)
-    client, err := leveldb.NewClient(nodePath)
-    if err != nil {
-        log.Fatalf("Erreur ouverture LevelDB: %v", err)
-    }
-    defer client.Close()
-    
-    result := BenchmarkResult{
-        Database: "LevelDB",
-    }
-    
-    // Mesure mémoire initiale - Forcer GC pour une mesure propre
-    runtime.GC()
-    var m1 runtime.MemStats
-    runtime.ReadMemStats(&m1)
-    initialAlloc := m1.Alloc
-    
-    // Test 1: Écriture séquentielle
-    fmt.Printf("  [1/4] Écriture séquentielle de %d documents...\n", dataset)
-    start := time.Now()
-    
-    for i := 0; i < dataset; i++ {
-        key := fmt.Sprintf("bench:order:%05d", i)
-        data := map[string]interface{}{
-            "order_id": key,
-            "amount":   100.50 + float64(i)*0.1,
-            "region":   getRegion(i),
-            "status":   getStatus(i),
-            "items":    i % 5,
-        }
-        
-        if err := client.Put(key, data); err != nil {
-            log.Printf("Erreur écriture %s: %v", key, err)
-        }
-        
-        if (i+1)%1000 == 0 {
-            elapsed := time.Since(start)
-            opsPerSec := float64(i+1) / elapsed.Seconds()
-            fmt.Printf("    %d docs | %.0f ops/sec\n", i+1, opsPerSec)
-        }
-    }
-    
-    result.WriteTime = time.Since(start)
-    result.WriteOpsPerSec = float64(dataset) / result.WriteTime.Seconds()
-    
-    // Test 2: Lecture aléatoire
-    readCount := dataset / 5 // 20% du dataset
-    fmt.Printf("  [2/4] Lecture aléatoire de %d documents...\n", readCount)
-    start = time.Now()
-    
-    readErrors := 0
-    for i := 0; i < readCount; i++ {
-        key := fmt.Sprintf("bench:order:%05d", i*5)
-        if _, err := client.Get(key); err != nil {
-            readErrors++
-        }
-    }
-    
-    result.ReadTime = time.Since(start)
-    result.ReadOpsPerSec = float64(readCount) / result.ReadTime.Seconds()
-    
-    if readErrors > 0 {
-        fmt.Printf("    Avertissement: %d erreurs de lecture\n", readErrors)
-    }
-    
-    // Test 3: Batch insert
-    batchSize := 1000
-    fmt.Printf("  [3/4] Insertion batch de %d documents...\n", batchSize)
-    
-    batchEntries := make(map[string]interface{})
-    for i := 0; i < batchSize; i++ {
-        key := fmt.Sprintf("bench:batch:%05d", i)
-        batchEntries[key] = map[string]interface{}{
-            "batch_id": i,
-            "data":     "test_data",
-            "timestamp": time.Now().Unix(),
-        }
-    }
-    
-    start = time.Now()
-    if err := client.BatchInsert(batchEntries); err != nil {
-        log.Printf("Erreur batch: %v", err)
-    }
-    result.BatchTime = time.Since(start)
-    
-    // Test 4: Recherche par index
-    searchCount := 100
-    fmt.Printf("  [4/4] Recherche par index (%d requêtes)...\n", searchCount)
-    
-    indexer := leveldb.NewIndexer(client.GetDB())
-    
-    // Créer quelques index
-    for i := 0; i < searchCount; i++ {
-        key := fmt.Sprintf("bench:order:%05d", i)
-        region := getRegion(i)
-        indexer.CreateIndex("order", "region", region, key)
-    }
-    
-    start = time.Now()
-    for i := 0; i < searchCount; i++ {
-        region := getRegion(i)
-        indexer.SearchByIndex("order", "region", region)
-    }
-    result.SearchTime = time.Since(start)
-    
-    // Mesures finales - Calculer la différence de mémoire correctement
-    runtime.GC()
-    var m2 runtime.MemStats
-    runtime.ReadMemStats(&m2)
-    finalAlloc := m2.Alloc
-    
-    // Utiliser une différence signée pour éviter l'underflow
-    if finalAlloc > initialAlloc {
-        result.MemoryUsageMB = float64(finalAlloc-initialAlloc) / 1024 / 1024
-    } else {
-        // Si GC a libéré plus que ce qu'on a alloué, utiliser HeapAlloc
-        result.MemoryUsageMB = float64(m2.HeapAlloc) / 1024 / 1024
-    }
-    
-    result.DiskSizeMB = getDiskSize(nodePath)
-    
-    return result
-}
-
-// benchmarkCouchDB exécute tous les tests sur CouchDB
-func benchmarkCouchDB(baseURL string, dataset int) BenchmarkResult {
-    result := BenchmarkResult{
-        Database: "CouchDB",
-    }
-    
-    dbName := "benchmark_test"
-    dbURL := baseURL + "/" + dbName
-    
-    // Nettoyer et créer la base
-    req, _ := http.NewRequest("DELETE", dbURL, nil)
-    req.SetBasicAuth("admin", "ecommerce2024")
-    http.DefaultClient.Do(req)
-    
-    req, _ = http.NewRequest("PUT", dbURL, nil)
-    req.SetBasicAuth("admin", "ecommerce2024")
-    http.DefaultClient.Do(req)
-    
-    // Test 1: Écriture séquentielle
-    fmt.Printf("  [1/4] Écriture séquentielle de %d documents...\n", dataset)
-    start := time.Now()
-    
-    for i := 0; i < dataset; i++ {
-        doc := map[string]interface{}{
-            "_id":     fmt.Sprintf("bench_order_%05d", i),
-            "order_id": fmt.Sprintf("bench:order:%05d", i),
-            "amount":   100.50 + float64(i)*0.1,
-            "region":   getRegion(i),
-            "status":   getStatus(i),
-            "items":    i % 5,
-        }
-        
-        jsonData, _ := json.Marshal(doc)
-        req, _ := http.NewRequest("PUT", dbURL+"/"+doc["_id"].(string), bytes.NewBuffer(jsonData))
-        req.SetBasicAuth("admin", "ecommerce2024")
-        req.Header.Set("Content-Type", "application/json")
-        
-        resp, err := http.DefaultClient.Do(req)
-        if err == nil {
-            resp.Body.Close()
-        }
-        
-        if (i+1)%1000 == 0 {
-            elapsed := time.Since(start)
-            opsPerSec := float64(i+1) / elapsed.Seconds()
-            fmt.Printf("    %d docs | %.0f ops/sec\n", i+1, opsPerSec)
-        }
-    }
-    
-    result.WriteTime = time.Since(start)
-    result.WriteOpsPerSec = float64(dataset) / result.WriteTime.Seconds()
-    
-    // Test 2: Lecture aléatoire
-    readCount := dataset / 5
-    fmt.Printf("  [2/4] Lecture aléatoire de %d documents...\n", readCount)
-    start = time.Now()
-    
-    for i := 0; i < readCount; i++ {
-        docID := fmt.Sprintf("bench_order_%05d", i*5)
-        req, _ := http.NewRequest("GET", dbURL+"/"+docID, nil)
-        req.SetBasicAuth("admin", "ecommerce2024")
-        
-        resp, err := http.DefaultClient.Do(req)
-        if err == nil {
-            io.Copy(io.Discard, resp.Body)
-            resp.Body.Close()
-        }
-    }
-    
-    result.ReadTime = time.Since(start)
-    result.ReadOpsPerSec = float64(readCount) / result.ReadTime.Seconds()
-    
-    // Test 3: Batch insert (_bulk_docs)
-    batchSize := 1000
-    fmt.Printf("  [3/4] Insertion batch de %d documents...\n", batchSize)
-    
-    docs := make([]map[string]interface{}, batchSize)
-    for i := 0; i < batchSize; i++ {
-        docs[i] = map[string]interface{}{
-            "_id":   fmt.Sprintf("bench_batch_%05d", i),
-            "batch_id": i,
-            "data":     "test_data",
-        }
-    }
-    
-    bulkDoc := map[string]interface{}{
-        "docs": docs,
-    }
-    
-    jsonData, _ := json.Marshal(bulkDoc)
-    start = time.Now()
-    
-    req, _ = http.NewRequest("POST", dbURL+"/_bulk_docs", bytes.NewBuffer(jsonData))
-    req.SetBasicAuth("admin", "ecommerce2024")
-    req.Header.Set("Content-Type", "application/json")
-    
-    resp, err := http.DefaultClient.Do(req)
-    if err == nil {
-        resp.Body.Close()
-    }
-    
-    result.BatchTime = time.Since(start)
-    
-    // Test 4: Recherche (_find)
-    searchCount := 100
-    fmt.Printf("  [4/4] Recherche par index (%d requêtes)...\n", searchCount)
-    
-    start = time.Now()
-    for i := 0; i < searchCount; i++ {
-        region := getRegion(i)
-        query := map[string]interface{}{
-            "selector": map[string]interface{}{
-                "region": region,
-            },
-        }
-        
-        jsonData, _ := json.Marshal(query)
-        req, _ := http.NewRequest("POST", dbURL+"/_find", bytes.NewBuffer(jsonData))
-        req.SetBasicAuth("admin", "ecommerce2024")
-        req.Header.Set("Content-Type", "application/json")
-        
-        resp, err := http.DefaultClient.Do(req)
-        if err == nil {
-            io.Copy(io.Discard, resp.Body)
-            resp.Body.Close()
-        }
-    }
-    result.SearchTime = time.Since(start)
-    
-    // Estimations (CouchDB ne permet pas facilement de mesurer)
-    result.DiskSizeMB = float64(dataset) * 0.025 // ~25KB par doc en moyenne
-    result.MemoryUsageMB = 50.0 // Estimation raisonnable
-    
-    return result
-}
-
-// printResults affiche les résultats d'un benchmark
-func printResults(r BenchmarkResult) {
-    fmt.Printf("═══════════════════════════════════════════════\n")
-    fmt.Printf("  %s - Résultats\n", r.Database)
-    fmt.Printf("═══════════════════════════════════════════════\n")
-    fmt.Printf("Écriture séquentielle:     %v  (%.0f ops/sec)\n", 
-        r.WriteTime, r.WriteOpsPerSec)
-    fmt.Printf("Lecture aléatoire:         %v  (%.0f ops/sec)\n", 
-        r.ReadTime, r.ReadOpsPerSec)
-    fmt.Printf("Insertion batch:           %v\n", r.BatchTime)
-    fmt.Printf("Recherche par index:       %v\n", r.SearchTime)
-    fmt.Printf("Taille sur disque:         %.2f MB\n", r.DiskSizeMB)
-    fmt.Printf("Utilisation mémoire:       %.2f MB\n", r.MemoryUsageMB)
-}
-
-// compareResults affiche une comparaison détaillée
-func compareResults(level, couch BenchmarkResult) {
-    fmt.Println("╔════════════════════════════════════════════════════════════════╗")
-    fmt.Println("║         COMPARAISON DÉTAILLÉE - LevelDB vs CouchDB            ║")
-    fmt.Println("╚════════════════════════════════════════════════════════════════╝")
-    fmt.Println()
-    
-    fmt.Println("┌────────────────────────────────────────────────────────────────┐")
-    fmt.Println("│ Métrique              │ LevelDB      │ CouchDB      │ Gain    │")
-    fmt.Println("├────────────────────────────────────────────────────────────────┤")
-    
-    // Écriture
-    writeGain := (couch.WriteTime.Seconds() - level.WriteTime.Seconds()) / couch.WriteTime.Seconds() * 100
-    fmt.Printf("│ Écriture (10K docs)   │ %-12v │ %-12v │ %+6.1f%% │\n",
-        level.WriteTime, couch.WriteTime, writeGain)
-    fmt.Printf("│   Ops/sec             │ %-12.0f │ %-12.0f │         │\n",
-        level.WriteOpsPerSec, couch.WriteOpsPerSec)
-    
-    // Lecture
-    readGain := (couch.ReadTime.Seconds() - level.ReadTime.Seconds()) / couch.ReadTime.Seconds() * 100
-    fmt.Printf("│ Lecture (2K docs)     │ %-12v │ %-12v │ %+6.1f%% │\n",
-        level.ReadTime, couch.ReadTime, readGain)
-    fmt.Printf("│   Ops/sec             │ %-12.0f │ %-12.0f │         │\n",
-        level.ReadOpsPerSec, couch.ReadOpsPerSec)
-    
-    // Batch
-    batchGain := (couch.BatchTime.Seconds() - level.BatchTime.Seconds()) / couch.BatchTime.Seconds() * 100
-    fmt.Printf("│ Batch (1K docs)       │ %-12v │ %-12v │ %+6.1f%% │\n",
-        level.BatchTime, couch.BatchTime, batchGain)
-    
-    // Recherche
-    searchGain := (couch.SearchTime.Seconds() - level.SearchTime.Seconds()) / couch.SearchTime.Seconds() * 100
-    fmt.Printf("│ Recherche (100)       │ %-12v │ %-12v │ %+6.1f%% │\n",
-        level.SearchTime, couch.SearchTime, searchGain)
-    
-    // Disque
-    diskGain := (couch.DiskSizeMB - level.DiskSizeMB) / couch.DiskSizeMB * 100
-    fmt.Printf("│ Taille disque         │ %-10.1f MB │ %-10.1f MB │ %+6.1f%% │\n",
-        level.DiskSizeMB, couch.DiskSizeMB, diskGain)
-    
-    // Mémoire
-    memGain := (couch.MemoryUsageMB - level.MemoryUsageMB) / couch.MemoryUsageMB * 100
-    fmt.Printf("│ Mémoire               │ %-10.1f MB │ %-10.1f MB │ %+6.1f%% │\n",
-        level.MemoryUsageMB, couch.MemoryUsageMB, memGain)
-    
-    fmt.Println("└────────────────────────────────────────────────────────────────┘")
-    
-    // Synthèse
-    fmt.Println()
-    fmt.Println("📊 SYNTHÈSE:")
-    if writeGain > 0 {
-        fmt.Printf("✅ LevelDB est %.0f%% plus rapide en écriture\n", writeGain)
-    }
-    if readGain > 0 {
-        fmt.Printf("✅ LevelDB est %.0f%% plus rapide en lecture\n", readGain)
-    }
-    if diskGain > 0 {
-        fmt.Printf("✅ LevelDB utilise %.0f%% moins d'espace disque\n", diskGain)
-    }
-    if memGain > 0 {
-        fmt.Printf("✅ LevelDB utilise %.0f%% moins de mémoire\n", memGain)
-    }
-    
-    fmt.Println()
-    fmt.Println("💡 CONCLUSION:")
-    fmt.Println("LevelDB excelle en performances locales, mais CouchDB offre:")
-    fmt.Println("  • Réplication automatique multi-nœuds")
-    fmt.Println("  • Résolution de conflits MVCC")
-    fmt.Println("  • API HTTP native")
-    fmt.Println("  • Vues Map-Reduce intégrées")
-    fmt.Println()
-    fmt.Println("➡️  Recommandation: LevelDB pour cache local, CouchDB pour distribution")
-}
-
-// Fonctions utilitaires
-func getRegion(i int) string {
-    regions := []string{"NA", "EU", "AP", "SA"}
-    return regions[i%len(regions)]
-}
-
-func getStatus(i int) string {
-    statuses := []string{"pending", "delivered", "canceled", "shipped"}
-    return statuses[i%len(statuses)]
-}
-
-func getDiskSize(path string) float64 {
-    var size int64
-    err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
-        if err != nil {
-            return err
-        }
-        if !info.IsDir() {
-            size += info.Size()
-        }
-        return nil
-    })
-    
-    if err != nil {
-        return 0
-    }
-    
-    return float64(size) / 1024 / 1024 // MB
+// cmd/compare/main.go
+// Outil de benchmark comparatif LevelDB vs CouchDB
+
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "os"
+    "path/filepath"
+    "runtime"
+    "time"
+    
+    "leveldb-tp/pkg/format"
+    "leveldb-tp/pkg/leveldb"
+)
+
+// Destination de l'affichage habituel: la sortie d'erreur avec un format
+// machine, pour que seuls les résultats soient écrits sur la sortie standard
+var msgOut io.Writer = os.Stdout
+
+type BenchmarkResult struct {
+    Database      string
+    WriteTime     time.Duration
+    ReadTime      time.Duration
+    BatchTime     time.Duration
+    SearchTime    time.Duration
+    DiskSizeMB    float64
+    MemoryUsageMB float64
+    WriteOpsPerSec float64
+    ReadOpsPerSec  float64
+}
+
+func main() {
+    var (
+        dataset  = flag.Int("dataset", 5000, "Nombre de documents pour le test")
+        db       = flag.String("db", "both", "Base à tester: leveldb|couchdb|both")
+        node     = flag.String("node", "node1", "Nœud LevelDB à utiliser")
+        couchURL = flag.String("couch", "http://localhost:5987", "URL CouchDB")
+        compare  = flag.Bool("compare", true, "Afficher tableau comparatif")
+        outFmt   = flag.String("format", "table", "Format des résultats: table, json, ndjson ou csv")
+    )
+    flag.Parse()
+    
+    log.SetFlags(0)
+    
+    outputFormat, err := format.Parse(*outFmt)
+    if err != nil {
+        log.Fatalf("Option -format: %v", err)
+    }
+    
+    // Format machine: seuls les résultats vont sur la sortie standard
+    var results []BenchmarkResult
+    if outputFormat != format.Table {
+        msgOut = os.Stderr
+        defer func() {
+            writeResults(os.Stdout, outputFormat, results)
+        }()
+    }
+    
+    fmt.Fprintln(msgOut, "╔════════════════════════════════════════════════════╗")
+    fmt.Fprintf(msgOut, "║   Benchmark LevelDB vs CouchDB (%d docs)    ║\n", *dataset)
+    fmt.Fprintln(msgOut, "╚════════════════════════════════════════════════════╝")
+    fmt.Fprintln(msgOut)
+    
+    var levelResult, couchResult BenchmarkResult
+    var couchAvailable bool
+    
+    // Test de connexion CouchDB si nécessaire
+    if *db == "couchdb" || *db == "both" {
+        fmt.Fprintln(msgOut, "🔍 Test de connexion CouchDB...")
+        if err := testCouchDBConnection(*couchURL); err != nil {
+            fmt.Fprintf(msgOut, "❌ ERREUR: Impossible de se connecter à CouchDB (%s)\n", *couchURL)
+            fmt.Fprintf(msgOut, "   Détails: %v\n", err)
+            fmt.Fprintln(msgOut)
+            
+            if *db == "couchdb" {
+                fmt.Fprintln(msgOut, "💡 Vérifiez que:")
+                fmt.Fprintln(msgOut, "   • CouchDB est démarré")
+                fmt.Fprintln(msgOut, "   • L'URL est correcte (défaut: http://localhost:5987)")
+                fmt.Fprintln(msgOut, "   • Les credentials sont valides (admin/ecommerce2024)")
+                os.Exit(1)
+            } else {
+                fmt.Fprintln(msgOut, "⚠️  Benchmark CouchDB ignoré, uniquement LevelDB sera testé")
+                fmt.Fprintln(msgOut)
+                couchAvailable = false
+            }
+        } else {
+            fmt.Fprintln(msgOut, "✅ Connexion CouchDB réussie!")
+            fmt.Fprintln(msgOut)
+            couchAvailable = true
+        }
+    }
+    
+    if *db == "leveldb" || *db == "both" {
+        fmt.Fprintln(msgOut, "🔧 Benchmark LevelDB...")
+        fmt.Fprintln(msgOut)
+        levelResult = benchmarkLevelDB(*node, *dataset)
+        results = append(results, levelResult)
+        printResults(levelResult)
+        fmt.Fprintln(msgOut)
+    }
+    
+    if (*db == "couchdb" || *db == "both") && couchAvailable {
+        fmt.Fprintln(msgOut, "🔧 Benchmark CouchDB...")
+        fmt.Fprintln(msgOut)
+        couchResult = benchmarkCouchDB(*couchURL, *dataset)
+        results = append(results, couchResult)
+        printResults(couchResult)
+        fmt.Fprintln(msgOut)
+    }
+    
+    if *compare && *db == "both" && couchAvailable {
+        fmt.Fprintln(msgOut, "📊 Comparaison détaillée...")
+        fmt.Fprintln(msgOut)
+        compareResults(levelResult, couchResult)
+    }
+}
+
+// testCouchDBConnection vérifie la connexion à CouchDB
+func testCouchDBConnection(baseURL string) error {
+    client := &http.Client{
+        Timeout: 5 * time.Second,
+    }
+    
+    // Test 1: Vérifier que le serveur répond
+    req, err := http.NewRequest("GET", baseURL, nil)
+    if err != nil {
+        return fmt.Errorf("création requête impossible: %v", err)
+    }
+    
+    resp, err := client.Do(req)
+    if err != nil {
+        return fmt.Errorf("serveur inaccessible: %v", err)
+    }
+    defer resp.Body.Close()
+    
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("serveur répond avec status %d", resp.StatusCode)
+    }
+    
+    // Test 2: Vérifier l'authentification
+    req, err = http.NewRequest("GET", baseURL+"/_all_dbs", nil)
+    if err != nil {
+        return err
+    }
+    req.SetBasicAuth("admin", "ecommerce2024")
+    
+    resp, err = client.Do(req)
+    if err != nil {
+        return fmt.Errorf("erreur lors de la requête: %v", err)
+    }
+    defer resp.Body.Close()
+    
+    if resp.StatusCode == http.StatusUnauthorized {
+        return fmt.Errorf("authentification échouée (vérifiez les credentials)")
+    }
+    
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("erreur serveur (status %d)", resp.StatusCode)
+    }
+    
+    return nil
+}
+
+// benchmarkLevelDB exécute tous les tests sur LevelDB
+func benchmarkLevelDB(node string, dataset int) BenchmarkResult {
+    nodePath := filepath.Join("leveldb-stores", node)
+    client, err := leveldb.NewClient(nodePath)
+    if err != nil {
+        log.Fatalf("Erreur ouverture LevelDB: %v", err)
+    }
+    defer client.Close()
+    
+    result := BenchmarkResult{
+        Database: "LevelDB",
+    }
+    
+    // Mesure mémoire initiale - Forcer GC pour une mesure propre
+    runtime.GC()
+    var m1 runtime.MemStats
+    runtime.ReadMemStats(&m1)
+    initialAlloc := m1.Alloc
+    
+    // Test 1: Écriture séquentielle
+    fmt.Fprintf(msgOut, "  [1/4] Écriture séquentielle de %d documents...\n", dataset)
+    start := time.Now()
+    
+    for i := 0; i < dataset; i++ {
+        key := fmt.Sprintf("bench:order:%05d", i)
+        data := map[string]interface{}{
+            "order_id": key,
+            "amount":   100.50 + float64(i)*0.1,
+            "region":   getRegion(i),
+            "status":   getStatus(i),
+            "items":    i % 5,
+        }
+        
+        if err := client.Put(key, data); err != nil {
+            log.Printf("Erreur écriture %s: %v", key, err)
+        }
+        
+        if (i+1)%1000 == 0 {
+            elapsed := time.Since(start)
+            opsPerSec := float64(i+1) / elapsed.Seconds()
+            fmt.Fprintf(msgOut, "    %d docs | %.0f ops/sec\n", i+1, opsPerSec)
+        }
+    }
+    
+    result.WriteTime = time.Since(start)
+    result.WriteOpsPerSec = float64(dataset) / result.WriteTime.Seconds()
+    
+    // Test 2: Lecture aléatoire
+    readCount := dataset / 5 // 20% du dataset
+    fmt.Fprintf(msgOut, "  [2/4] Lecture aléatoire de %d documents...\n", readCount)
+    start = time.Now()
+    
+    readErrors := 0
+    for i := 0; i < readCount; i++ {
+        key := fmt.Sprintf("bench:order:%05d", i*5)
+        if _, err := client.Get(key); err != nil {
+            readErrors++
+        }
+    }
+    
+    result.ReadTime = time.Since(start)
+    result.ReadOpsPerSec = float64(readCount) / result.ReadTime.Seconds()
+    
+    if readErrors > 0 {
+        fmt.Fprintf(msgOut, "    Avertissement: %d erreurs de lecture\n", readErrors)
+    }
+    
+    // Test 3: Batch insert
+    batchSize := 1000
+    fmt.Fprintf(msgOut, "  [3/4] Insertion batch de %d documents...\n", batchSize)
+    
+    batchEntries := make(map[string]interface{})
+    for i := 0; i < batchSize; i++ {
+        key := fmt.Sprintf("bench:batch:%05d", i)
+        batchEntries[key] = map[string]interface{}{
+            "batch_id": i,
+            "data":     "test_data",
+            "timestamp": time.Now().Unix(),
+        }
+    }
+    
+    start = time.Now()
+    if err := client.BatchInsert(batchEntries); err != nil {
+        log.Printf("Erreur batch: %v", err)
+    }
+    result.BatchTime = time.Since(start)
+    
+    // Test 4: Recherche par index
+    searchCount := 100
+    fmt.Fprintf(msgOut, "  [4/4] Recherche par index (%d requêtes)...\n", searchCount)
+    
+    indexer := leveldb.NewIndexer(client.GetDB())
+    
+    // Créer quelques index
+    for i := 0; i < searchCount; i++ {
+        key := fmt.Sprintf("bench:order:%05d", i)
+        region := getRegion(i)
+        indexer.CreateIndex("order", "region", region, key)
+    }
+    
+    start = time.Now()
+    for i := 0; i < searchCount; i++ {
+        region := getRegion(i)
+        indexer.SearchByIndex("order", "region", region)
+    }
+    result.SearchTime = time.Since(start)
+    
+    // Mesures finales - Calculer la différence de mémoire correctement
+    runtime.GC()
+    var m2 runtime.MemStats
+    runtime.ReadMemStats(&m2)
+    finalAlloc := m2.Alloc
+    
+    // Utiliser une différence signée pour éviter l'underflow
+    if finalAlloc > initialAlloc {
+        result.MemoryUsageMB = float64(finalAlloc-initialAlloc) / 1024 / 1024
+    } else {
+        // Si GC a libéré plus que ce qu'on a alloué, utiliser HeapAlloc
+        result.MemoryUsageMB = float64(m2.HeapAlloc) / 1024 / 1024
+    }
+    
+    result.DiskSizeMB = getDiskSize(nodePath)
+    
+    return result
+}
+
+// benchmarkCouchDB exécute tous les tests sur CouchDB
+func benchmarkCouchDB(baseURL string, dataset int) BenchmarkResult {
+    result := BenchmarkResult{
+        Database: "CouchDB",
+    }
+    
+    dbName := "benchmark_test"
+    dbURL := baseURL + "/" + dbName
+    
+    // Nettoyer et créer la base
+    req, _ := http.NewRequest("DELETE", dbURL, nil)
+    req.SetBasicAuth("admin", "ecommerce2024")
+    http.DefaultClient.Do(req)
+    
+    req, _ = http.NewRequest("PUT", dbURL, nil)
+    req.SetBasicAuth("admin", "ecommerce2024")
+    http.DefaultClient.Do(req)
+    
+    // Test 1: Écriture séquentielle
+    fmt.Fprintf(msgOut, "  [1/4] Écriture séquentielle de %d documents...\n", dataset)
+    start := time.Now()
+    
+    for i := 0; i < dataset; i++ {
+        doc := map[string]interface{}{
+            "_id":     fmt.Sprintf("bench_order_%05d", i),
+            "order_id": fmt.Sprintf("bench:order:%05d", i),
+            "amount":   100.50 + float64(i)*0.1,
+            "region":   getRegion(i),
+            "status":   getStatus(i),
+            "items":    i % 5,
+        }
+        
+        jsonData, _ := json.Marshal(doc)
+        req, _ := http.NewRequest("PUT", dbURL+"/"+doc["_id"].(string), bytes.NewBuffer(jsonData))
+        req.SetBasicAuth("admin", "ecommerce2024")
+        req.Header.Set("Content-Type", "application/json")
+        
+        resp, err := http.DefaultClient.Do(req)
+        if err == nil {
+            resp.Body.Close()
+        }
+        
+        if (i+1)%1000 == 0 {
+            elapsed := time.Since(start)
+            opsPerSec := float64(i+1) / elapsed.Seconds()
+            fmt.Fprintf(msgOut, "    %d docs | %.0f ops/sec\n", i+1, opsPerSec)
+        }
+    }
+    
+    result.WriteTime = time.Since(start)
+    result.WriteOpsPerSec = float64(dataset) / result.WriteTime.Seconds()
+    
+    // Test 2: Lecture aléatoire
+    readCount := dataset / 5
+    fmt.Fprintf(msgOut, "  [2/4] Lecture aléatoire de %d documents...\n", readCount)
+    start = time.Now()
+    
+    for i := 0; i < readCount; i++ {
+        docID := fmt.Sprintf("bench_order_%05d", i*5)
+        req, _ := http.NewRequest("GET", dbURL+"/"+docID, nil)
+        req.SetBasicAuth("admin", "ecommerce2024")
+        
+        resp, err := http.DefaultClient.Do(req)
+        if err == nil {
+            io.Copy(io.Discard, resp.Body)
+            resp.Body.Close()
+        }
+    }
+    
+    result.ReadTime = time.Since(start)
+    result.ReadOpsPerSec = float64(readCount) / result.ReadTime.Seconds()
+    
+    // Test 3: Batch insert (_bulk_docs)
+    batchSize := 1000
+    fmt.Fprintf(msgOut, "  [3/4] Insertion batch de %d documents...\n", batchSize)
+    
+    docs := make([]map[string]interface{}, batchSize)
+    for i := 0; i < batchSize; i++ {
+        docs[i] = map[string]interface{}{
+            "_id":   fmt.Sprintf("bench_batch_%05d", i),
+            "batch_id": i,
+            "data":     "test_data",
+        }
+    }
+    
+    bulkDoc := map[string]interface{}{
+        "docs": docs,
+    }
+    
+    jsonData, _ := json.Marshal(bulkDoc)
+    start = time.Now()
+    
+    req, _ = http.NewRequest("POST", dbURL+"/_bulk_docs", bytes.NewBuffer(jsonData))
+    req.SetBasicAuth("admin", "ecommerce2024")
+    req.Header.Set("Content-Type", "application/json")
+    
+    resp, err := http.DefaultClient.Do(req)
+    if err == nil {
+        resp.Body.Close()
+    }
+    
+    result.BatchTime = time.Since(start)
+    
+    // Test 4: Recherche (_find)
+    searchCount := 100
+    fmt.Fprintf(msgOut, "  [4/4] Recherche par index (%d requêtes)...\n", searchCount)
+    
+    start = time.Now()
+    for i := 0; i < searchCount; i++ {
+        region := getRegion(i)
+        query := map[string]interface{}{
+            "selector": map[string]interface{}{
+                "region": region,
+            },
+        }
+        
+        jsonData, _ := json.Marshal(query)
+        req, _ := http.NewRequest("POST", dbURL+"/_find", bytes.NewBuffer(jsonData))
+        req.SetBasicAuth("admin", "ecommerce2024")
+        req.Header.Set("Content-Type", "application/json")
+        
+        resp, err := http.DefaultClient.Do(req)
+        if err == nil {
+            io.Copy(io.Discard, resp.Body)
+            resp.Body.Close()
+        }
+    }
+    result.SearchTime = time.Since(start)
+    
+    // Estimations (CouchDB ne permet pas facilement de mesurer)
+    result.DiskSizeMB = float64(dataset) * 0.025 // ~25KB par doc en moyenne
+    result.MemoryUsageMB = 50.0 // Estimation raisonnable
+    
+    return result
+}
+
+// printResults affiche les résultats d'un benchmark
+func printResults(r BenchmarkResult) {
+    fmt.Fprintf(msgOut, "═══════════════════════════════════════════════\n")
+    fmt.Fprintf(msgOut, "  %s - Résultats\n", r.Database)
+    fmt.Fprintf(msgOut, "═══════════════════════════════════════════════\n")
+    fmt.Fprintf(msgOut, "Écriture séquentielle:     %v  (%.0f ops/sec)\n", 
+        r.WriteTime, r.WriteOpsPerSec)
+    fmt.Fprintf(msgOut, "Lecture aléatoire:         %v  (%.0f ops/sec)\n", 
+        r.ReadTime, r.ReadOpsPerSec)
+    fmt.Fprintf(msgOut, "Insertion batch:           %v\n", r.BatchTime)
+    fmt.Fprintf(msgOut, "Recherche par index:       %v\n", r.SearchTime)
+    fmt.Fprintf(msgOut, "Taille sur disque:         %.2f MB\n", r.DiskSizeMB)
+    fmt.Fprintf(msgOut, "Utilisation mémoire:       %.2f MB\n", r.MemoryUsageMB)
+}
+
+// writeResults écrit les résultats dans un format lisible par les scripts
+// (durées en millisecondes)
+func writeResults(out io.Writer, outputFormat string, results []BenchmarkResult) {
+    w := format.NewWriter(out, outputFormat,
+        "database", "write_ms", "read_ms", "batch_ms", "search_ms",
+        "disk_mb", "memory_mb", "write_ops_per_sec", "read_ops_per_sec")
+    for _, r := range results {
+        w.Row(r.Database, ms(r.WriteTime), ms(r.ReadTime), ms(r.BatchTime), ms(r.SearchTime),
+            r.DiskSizeMB, r.MemoryUsageMB, r.WriteOpsPerSec, r.ReadOpsPerSec)
+    }
+    if err := w.Flush(); err != nil {
+        log.Fatalf("Erreur écriture résultats: %v", err)
+    }
+}
+
+// ms convertit une durée en millisecondes
+func ms(d time.Duration) float64 {
+    return float64(d) / float64(time.Millisecond)
+}
+
+// compareResults affiche une comparaison détaillée
+func compareResults(level, couch BenchmarkResult) {
+    fmt.Fprintln(msgOut, "╔════════════════════════════════════════════════════════════════╗")
+    fmt.Fprintln(msgOut, "║         COMPARAISON DÉTAILLÉE - LevelDB vs CouchDB            ║")
+    fmt.Fprintln(msgOut, "╚════════════════════════════════════════════════════════════════╝")
+    fmt.Fprintln(msgOut)
+    
+    fmt.Fprintln(msgOut, "┌────────────────────────────────────────────────────────────────┐")
+    fmt.Fprintln(msgOut, "│ Métrique              │ LevelDB      │ CouchDB      │ Gain    │")
+    fmt.Fprintln(msgOut, "├────────────────────────────────────────────────────────────────┤")
+    
+    // Écriture
+    writeGain := (couch.WriteTime.Seconds() - level.WriteTime.Seconds()) / couch.WriteTime.Seconds() * 100
+    fmt.Fprintf(msgOut, "│ Écriture (10K docs)   │ %-12v │ %-12v │ %+6.1f%% │\n",
+        level.WriteTime, couch.WriteTime, writeGain)
+    fmt.Fprintf(msgOut, "│   Ops/sec             │ %-12.0f │ %-12.0f │         │\n",
+        level.WriteOpsPerSec, couch.WriteOpsPerSec)
+    
+    // Lecture
+    readGain := (couch.ReadTime.Seconds() - level.ReadTime.Seconds()) / couch.ReadTime.Seconds() * 100
+    fmt.Fprintf(msgOut, "│ Lecture (2K docs)     │ %-12v │ %-12v │ %+6.1f%% │\n",
+        level.ReadTime, couch.ReadTime, readGain)
+    fmt.Fprintf(msgOut, "│   Ops/sec             │ %-12.0f │ %-12.0f │         │\n",
+        level.ReadOpsPerSec, couch.ReadOpsPerSec)
+    
+    // Batch
+    batchGain := (couch.BatchTime.Seconds() - level.BatchTime.Seconds()) / couch.BatchTime.Seconds() * 100
+    fmt.Fprintf(msgOut, "│ Batch (1K docs)       │ %-12v │ %-12v │ %+6.1f%% │\n",
+        level.BatchTime, couch.BatchTime, batchGain)
+    
+    // Recherche
+    searchGain := (couch.SearchTime.Seconds() - level.SearchTime.Seconds()) / couch.SearchTime.Seconds() * 100
+    fmt.Fprintf(msgOut, "│ Recherche (100)       │ %-12v │ %-12v │ %+6.1f%% │\n",
+        level.SearchTime, couch.SearchTime, searchGain)
+    
+    // Disque
+    diskGain := (couch.DiskSizeMB - level.DiskSizeMB) / couch.DiskSizeMB * 100
+    fmt.Fprintf(msgOut, "│ Taille disque         │ %-10.1f MB │ %-10.1f MB │ %+6.1f%% │\n",
+        level.DiskSizeMB, couch.DiskSizeMB, diskGain)
+    
+    // Mémoire
+    memGain := (couch.MemoryUsageMB - level.MemoryUsageMB) / couch.MemoryUsageMB * 100
+    fmt.Fprintf(msgOut, "│ Mémoire               │ %-10.1f MB │ %-10.1f MB │ %+6.1f%% │\n",
+        level.MemoryUsageMB, couch.MemoryUsageMB, memGain)
+    
+    fmt.Fprintln(msgOut, "└────────────────────────────────────────────────────────────────┘")
+    
+    // Synthèse
+    fmt.Fprintln(msgOut)
+    fmt.Fprintln(msgOut, "📊 SYNTHÈSE:")
+    if writeGain > 0 {
+        fmt.Fprintf(msgOut, "✅ LevelDB est %.0f%% plus rapide en écriture\n", writeGain)
+    }
+    if readGain > 0 {
+        fmt.Fprintf(msgOut, "✅ LevelDB est %.0f%% plus rapide en lecture\n", readGain)
+    }
+    if diskGain > 0 {
+        fmt.Fprintf(msgOut, "✅ LevelDB utilise %.0f%% moins d'espace disque\n", diskGain)
+    }
+    if memGain > 0 {
+        fmt.Fprintf(msgOut, "✅ LevelDB utilise %.0f%% moins de mémoire\n", memGain)
+    }
+    
+    fmt.Fprintln(msgOut)
+    fmt.Fprintln(msgOut, "💡 CONCLUSION:")
+    fmt.Fprintln(msgOut, "LevelDB excelle en performances locales, mais CouchDB offre:")
+    fmt.Fprintln(msgOut, "  • Réplication automatique multi-nœuds")
+    fmt.Fprintln(msgOut, "  • Résolution de conflits MVCC")
+    fmt.Fprintln(msgOut, "  • API HTTP native")
+    fmt.Fprintln(msgOut, "  • Vues Map-Reduce intégrées")
+    fmt.Fprintln(msgOut)
+    fmt.Fprintln(msgOut, "➡️  Recommandation: LevelDB pour cache local, CouchDB pour distribution")
+}
+
+// Fonctions utilitaires
+func getRegion(i int) string {
+    regions := []string{"NA", "EU", "AP", "SA"}
+    return regions[i%len(regions)]
+}
+
+func getStatus(i int) string {
+    statuses := []string{"pending", "delivered", "canceled", "shipped"}
+    return statuses[i%len(statuses)]
+}
+
+func getDiskSize(path string) float64 {
+    var size int64
+    err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+        if err != nil {
+            return err
+        }
+        if !info.IsDir() {
+            size += info.Size()
+        }
+        return nil
+    })
+    
+    if err != nil {
+        return 0
+    }
+    
+    return float64(size) / 1024 / 1024 // MB
 }
\ No newline at end of file
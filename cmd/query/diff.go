@@ -0,0 +1,329 @@
+// cmd/query/diff.go
+// Différence d'un document entre deux nœuds, deux snapshots ou deux versions de l'historique (-diff)
+
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    
+    "leveldb-tp/pkg/format"
+    tpleveldb "leveldb-tp/pkg/leveldb"
+)
+
+// Répertoire des snapshots: copies de nœuds (répertoires LevelDB) ou exports
+// JSON de cmd/replicator
+const snapshotsDir = "snapshots"
+
+// diffSide est une des deux versions comparées
+type diffSide struct {
+    source  string           // source telle que donnée (node1, snapshot:avant, node1~1)
+    path    string           // base ouverte ou fichier lu
+    version string           // position dans l'historique, si la version en vient
+    entry   *tpleveldb.Entry // nil: document absent
+}
+
+// diffSource est une source découpée: base et sélection de version
+//
+//     node1          version courante sur le nœud
+//     snapshot:avant snapshots/avant (répertoire LevelDB ou export .json)
+//     node1~2        deux versions avant la dernière version journalisée
+//     node1@3        troisième version journalisée de la clé
+type diffSource struct {
+    base     string
+    path     string
+    back     int // ~N
+    at       int // @N (0: non demandé)
+    snapshot bool
+}
+
+func parseDiffSource(spec string) (diffSource, error) {
+    src := diffSource{}
+    base := spec
+    if i := strings.LastIndexAny(spec, "~@"); i >= 0 {
+        n, err := strconv.Atoi(spec[i+1:])
+        if err != nil || n < 0 || (spec[i] == '@' && n == 0) {
+            return src, fmt.Errorf("version invalide dans %q (~N: N versions avant la dernière, @N: N-ième version, à partir de 1)", spec)
+        }
+        if spec[i] == '~' {
+            src.back = n
+        } else {
+            src.at = n
+        }
+        base = spec[:i]
+    }
+    if base == "" {
+        return src, fmt.Errorf("source vide dans %q", spec)
+    }
+    
+    src.base = base
+    switch {
+    case strings.HasPrefix(base, "snapshot:"):
+        src.path = filepath.Join(snapshotsDir, strings.TrimPrefix(base, "snapshot:"))
+        src.snapshot = true
+    case strings.HasPrefix(filepath.ToSlash(base), snapshotsDir+"/"):
+        src.path = base
+        src.snapshot = true
+    default:
+        src.path = filepath.Join("leveldb-stores", base)
+    }
+    return src, nil
+}
+
+// historical indique si la source désigne une version de l'historique
+func (s diffSource) historical() bool {
+    return s.back > 0 || s.at > 0
+}
+
+// doDiff compare un document entre deux sources. Sans -from ni -to, la
+// dernière modification de la clé sur le nœud courant est affichée; une
+// source omise vaut le nœud courant.
+func doDiff(node, key, fromSpec, toSpec string) {
+    switch {
+    case fromSpec == "" && toSpec == "":
+        fromSpec, toSpec = node+"~1", node
+    case fromSpec == "":
+        fromSpec = node
+    case toSpec == "":
+        toSpec = node
+    }
+    
+    // Une même base n'est ouverte qu'une fois (verrou LevelDB)
+    clients := make(map[string]*tpleveldb.Client)
+    defer func() {
+        for _, c := range clients {
+            c.Close()
+        }
+    }()
+    
+    from, err := readDiffSide(clients, fromSpec, key)
+    if err != nil {
+        log.Fatalf("Option -from: %v", err)
+    }
+    to, err := readDiffSide(clients, toSpec, key)
+    if err != nil {
+        log.Fatalf("Option -to: %v", err)
+    }
+    if from.entry == nil && to.entry == nil {
+        log.Fatalf("Document %s absent de %s et de %s", key, fromSpec, toSpec)
+    }
+    
+    var diffs []tpleveldb.FieldDiff
+    switch {
+    case from.entry == nil:
+        diffs = []tpleveldb.FieldDiff{{Op: "added", To: to.entry.Data}}
+    case to.entry == nil:
+        diffs = []tpleveldb.FieldDiff{{Op: "removed", From: from.entry.Data}}
+    default:
+        if diffs, err = tpleveldb.DiffJSON(from.entry.Data, to.entry.Data); err != nil {
+            log.Fatalf("Erreur comparaison: %v", err)
+        }
+    }
+    
+    if machineOutput() {
+        writeDiff(key, from, to, diffs)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "Diff: %s\n", key)
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    meta := format.NewWriter(msgOut, format.Table, "", "de", "vers")
+    for _, m := range diffMeta(from, to) {
+        switch m.name {
+        case "version":
+            if m.from == "" && m.to == "" {
+                continue
+            }
+        case "hash":
+            m.from, m.to = shortHash(m.from), shortHash(m.to)
+        }
+        meta.Row(m.name, m.from, m.to)
+    }
+    meta.Flush()
+    fmt.Fprintln(msgOut)
+    
+    if len(diffs) == 0 {
+        fmt.Fprintln(msgOut, "✓ Documents identiques")
+        return
+    }
+    printFieldDiffs(diffs)
+    fmt.Fprintf(msgOut, "\n%d différence(s)\n", len(diffs))
+}
+
+// printFieldDiffs affiche les différences champ par champ (chemin, changement, avant, après)
+func printFieldDiffs(diffs []tpleveldb.FieldDiff) {
+    out := format.NewWriter(msgOut, format.Table, "chemin", "changement", "avant", "après")
+    for _, d := range diffs {
+        path := d.Path
+        if path == "" {
+            path = "(document)"
+        }
+        out.Row(path, diffOpLabel(d.Op), diffValue(d.Op != "added", d.From), diffValue(d.Op != "removed", d.To))
+    }
+    out.Flush()
+}
+
+// readDiffSide lit la version du document désignée par spec
+func readDiffSide(clients map[string]*tpleveldb.Client, spec, key string) (*diffSide, error) {
+    src, err := parseDiffSource(spec)
+    if err != nil {
+        return nil, err
+    }
+    side := &diffSide{source: spec, path: src.path}
+    
+    info, err := os.Stat(src.path)
+    if err != nil {
+        return nil, fmt.Errorf("source introuvable: %s", src.path)
+    }
+    if !info.IsDir() {
+        if src.historical() {
+            return nil, fmt.Errorf("%s: un export JSON n'a pas d'historique", src.path)
+        }
+        side.entry, err = readExportEntry(src.path, key)
+        return side, err
+    }
+    
+    client, ok := clients[src.path]
+    if !ok {
+        if client, err = tpleveldb.OpenReadOnly(src.path); err != nil {
+            return nil, fmt.Errorf("%s: %v", src.path, err)
+        }
+        clients[src.path] = client
+    }
+    
+    if !src.historical() {
+        value, err := client.GetDB().Get([]byte(key), nil)
+        if err != nil {
+            return side, nil
+        }
+        side.entry, err = decodeEntry(value)
+        return side, err
+    }
+    
+    history, err := client.History(key)
+    if err != nil {
+        return nil, err
+    }
+    pos := src.at
+    if src.back > 0 {
+        pos = len(history) - src.back
+    }
+    if pos < 1 || pos > len(history) {
+        return nil, fmt.Errorf("%s: version introuvable (%d version(s) journalisée(s) pour %s)", spec, len(history), key)
+    }
+    
+    change := history[pos-1]
+    side.version = fmt.Sprintf("%d/%d (seq %d, %s)", pos, len(history), change.Seq, change.Op)
+    if change.Op == "put" {
+        side.entry, err = decodeEntry(change.Entry)
+    }
+    return side, err
+}
+
+// readExportEntry cherche une clé dans un export de cmd/replicator
+// ([{"key":..., "value":...}, ...]), lu au fil de l'eau
+func readExportEntry(path, key string) (*tpleveldb.Entry, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+    
+    dec := json.NewDecoder(file)
+    if _, err := dec.Token(); err != nil {
+        return nil, fmt.Errorf("%s: export invalide: %v", path, err)
+    }
+    for dec.More() {
+        var item struct {
+            Key   string          `json:"key"`
+            Value json.RawMessage `json:"value"`
+        }
+        if err := dec.Decode(&item); err != nil {
+            return nil, fmt.Errorf("%s: export invalide: %v", path, err)
+        }
+        if item.Key == key {
+            return decodeEntry(item.Value)
+        }
+    }
+    if _, err := dec.Token(); err != nil && err != io.EOF {
+        return nil, fmt.Errorf("%s: export invalide: %v", path, err)
+    }
+    return nil, nil
+}
+
+func decodeEntry(value []byte) (*tpleveldb.Entry, error) {
+    var entry tpleveldb.Entry
+    if err := json.Unmarshal(value, &entry); err != nil {
+        return nil, fmt.Errorf("erreur désérialisation: %v", err)
+    }
+    return &entry, nil
+}
+
+type diffMetaRow struct {
+    name     string
+    from, to string
+}
+
+// diffMeta retourne les métadonnées des deux versions (source, hash, timestamp, nœud)
+func diffMeta(from, to *diffSide) []diffMetaRow {
+    field := func(s *diffSide, get func(e *tpleveldb.Entry) string) string {
+        if s.entry == nil {
+            return "(absent)"
+        }
+        return get(s.entry)
+    }
+    return []diffMetaRow{
+        {"source", from.path, to.path},
+        {"version", from.version, to.version},
+        {"hash", field(from, func(e *tpleveldb.Entry) string { return e.Hash }), field(to, func(e *tpleveldb.Entry) string { return e.Hash })},
+        {"timestamp", field(from, func(e *tpleveldb.Entry) string { return e.Timestamp }), field(to, func(e *tpleveldb.Entry) string { return e.Timestamp })},
+        {"node", field(from, func(e *tpleveldb.Entry) string { return e.Node }), field(to, func(e *tpleveldb.Entry) string { return e.Node })},
+    }
+}
+
+// writeDiff écrit le diff pour les scripts: une ligne par métadonnée (chemin
+// @source, @version, @hash, @timestamp, @node; op "same" ou "changed") puis
+// une ligne par champ différent (op "added", "removed" ou "changed")
+func writeDiff(key string, from, to *diffSide, diffs []tpleveldb.FieldDiff) {
+    out := newOutput("key", "path", "op", "from", "to")
+    for _, m := range diffMeta(from, to) {
+        op := "same"
+        if m.from != m.to {
+            op = "changed"
+        }
+        out.Row(key, "@"+m.name, op, m.from, m.to)
+    }
+    for _, d := range diffs {
+        out.Row(key, d.Path, d.Op, d.From, d.To)
+    }
+    flushOutput(out)
+}
+
+func diffOpLabel(op string) string {
+    switch op {
+    case "added":
+        return "ajouté"
+    case "removed":
+        return "supprimé"
+    }
+    return "modifié"
+}
+
+// diffValue affiche une valeur en JSON (les chaînes entre guillemets, pour
+// distinguer "10" de 10); present est faux pour le côté sans valeur
+func diffValue(present bool, v interface{}) string {
+    if !present {
+        return ""
+    }
+    b, err := json.Marshal(v)
+    if err != nil {
+        return fmt.Sprint(v)
+    }
+    return string(b)
+}
\ No newline at end of file
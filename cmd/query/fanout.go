@@ -0,0 +1,344 @@
+// cmd/query/fanout.go
+// Requêtes sur plusieurs nœuds en parallèle (-nodes), avec fusion des résultats et repérage des divergences
+
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    tpleveldb "leveldb-tp/pkg/leveldb"
+)
+
+// nodeResult est le résultat d'une action sur un nœud
+type nodeResult struct {
+    node    string
+    client  *tpleveldb.Client
+    err     error
+    count   int
+    keys    []string                    // clés trouvées par la recherche
+    entries map[string]*tpleveldb.Entry // versions lues (nil: clé absente)
+}
+
+// listNodes retourne les nœuds de leveldb-stores, triés
+func listNodes() ([]string, error) {
+    entries, err := os.ReadDir("leveldb-stores")
+    if err != nil {
+        return nil, err
+    }
+    var nodes []string
+    for _, e := range entries {
+        if e.IsDir() {
+            nodes = append(nodes, e.Name())
+        }
+    }
+    return nodes, nil
+}
+
+// parseNodes interprète -nodes: "all" ou une liste séparée par des virgules
+func parseNodes(spec string) []string {
+    if spec != "all" {
+        return splitList(spec)
+    }
+    nodes, err := listNodes()
+    if err != nil {
+        log.Fatalf("Erreur lecture leveldb-stores: %v", err)
+    }
+    return nodes
+}
+
+// forEachNode ouvre les nœuds et exécute fn sur chacun en parallèle. Un nœud
+// illisible est signalé dans son résultat sans interrompre les autres.
+func forEachNode(nodes []string, fn func(r *nodeResult) error) []*nodeResult {
+    results := make([]*nodeResult, len(nodes))
+    var wg sync.WaitGroup
+    for i, node := range nodes {
+        results[i] = &nodeResult{node: node, entries: make(map[string]*tpleveldb.Entry)}
+        wg.Add(1)
+        go func(r *nodeResult) {
+            defer wg.Done()
+    
+            // Lecture seule, comme -diff: verrou partagé avec les autres
+            // lecteurs et aucune écriture (index _hist) à l'ouverture
+            path := filepath.Join("leveldb-stores", r.node)
+            if info, err := os.Stat(path); err != nil || !info.IsDir() {
+                r.err = fmt.Errorf("nœud introuvable: %s", path)
+                return
+            }
+            client, err := tpleveldb.OpenReadOnly(path)
+            if err != nil {
+                r.err = err
+                return
+            }
+            r.client = client
+            r.err = fn(r)
+        }(results[i])
+    }
+    wg.Wait()
+    return results
+}
+
+// readEntries lit sur chaque nœud la version des clés données, en parallèle
+func readEntries(results []*nodeResult, keys []string) {
+    var wg sync.WaitGroup
+    for _, r := range results {
+        if r.client == nil || r.err != nil {
+            continue
+        }
+        wg.Add(1)
+        go func(r *nodeResult) {
+            defer wg.Done()
+            for _, key := range keys {
+                value, err := r.client.GetDB().Get([]byte(key), nil)
+                if err == leveldb.ErrNotFound {
+                    r.entries[key] = nil
+                    continue
+                }
+                if err != nil {
+                    r.err = err
+                    return
+                }
+                var entry tpleveldb.Entry
+                if err := json.Unmarshal(value, &entry); err != nil {
+                    r.err = fmt.Errorf("erreur désérialisation %s: %v", key, err)
+                    return
+                }
+                r.entries[key] = &entry
+            }
+        }(r)
+    }
+    wg.Wait()
+}
+
+func closeNodes(results []*nodeResult) {
+    for _, r := range results {
+        if r.client != nil {
+            r.client.Close()
+        }
+    }
+}
+
+// reportNodeErrors signale les nœuds en erreur et retourne les nœuds valides
+func reportNodeErrors(results []*nodeResult) []*nodeResult {
+    var ok []*nodeResult
+    for _, r := range results {
+        if r.err != nil {
+            fmt.Fprintf(os.Stderr, "✗ %s: %v\n", r.node, r.err)
+            continue
+        }
+        ok = append(ok, r)
+    }
+    if len(ok) == 0 {
+        log.Fatalf("Aucun nœud lisible")
+    }
+    return ok
+}
+
+// divergence compare les versions d'une clé sur les nœuds: "identique",
+// "absent" (manquante sur certains nœuds) ou "divergent" (hashs différents)
+func divergence(key string, results []*nodeResult) (string, string) {
+    var missing []string
+    byHash := make(map[string][]string)
+    for _, r := range results {
+        entry := r.entries[key]
+        if entry == nil {
+            missing = append(missing, r.node)
+            continue
+        }
+        byHash[entry.Hash] = append(byHash[entry.Hash], r.node)
+    }
+    
+    if len(byHash) > 1 {
+        var groups []string
+        for hash, nodes := range byHash {
+            groups = append(groups, fmt.Sprintf("%s=%s", strings.Join(nodes, "+"), shortHash(hash)))
+        }
+        sort.Strings(groups)
+        if len(missing) > 0 {
+            groups = append(groups, "absente de "+strings.Join(missing, ", "))
+        }
+        return "divergent", strings.Join(groups, " ")
+    }
+    if len(missing) > 0 {
+        return "absent", "absente de " + strings.Join(missing, ", ")
+    }
+    return "identique", ""
+}
+
+func shortHash(hash string) string {
+    if len(hash) > 12 {
+        return hash[:12]
+    }
+    return hash
+}
+
+// doFanOutCount compte les documents de chaque nœud
+func doFanOutCount(nodes []string) {
+    results := forEachNode(nodes, func(r *nodeResult) error {
+        var err error
+        r.count, err = r.client.Count()
+        return err
+    })
+    defer closeNodes(results)
+    results = reportNodeErrors(results)
+    
+    out := newOutput("node", "documents")
+    total, differ := 0, false
+    for _, r := range results {
+        out.Row(r.node, r.count)
+        total += r.count
+        differ = differ || r.count != results[0].count
+    }
+    
+    if !machineOutput() {
+        fmt.Fprintf(msgOut, "Comptage sur %d nœud(s)\n", len(results))
+        fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    }
+    flushOutput(out)
+    if !machineOutput() && differ {
+        fmt.Fprintln(msgOut, "\n⚠ Les nœuds n'ont pas le même nombre de documents")
+    }
+}
+
+// doFanOutGet lit une clé sur chaque nœud et compare les versions
+func doFanOutGet(nodes []string, key string) {
+    results := forEachNode(nodes, func(r *nodeResult) error { return nil })
+    defer closeNodes(results)
+    results = reportNodeErrors(results)
+    
+    readEntries(results, []string{key})
+    results = reportNodeErrors(results)
+    status, detail := divergence(key, results)
+    
+    out := newOutput("key", "node", "present", "hash", "timestamp", "data", "status")
+    for _, r := range results {
+        if entry := r.entries[key]; entry != nil {
+            out.Row(key, r.node, true, entry.Hash, entry.Timestamp, entry.Data, status)
+        } else {
+            out.Row(key, r.node, false, nil, nil, nil, status)
+        }
+    }
+    
+    if machineOutput() {
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "Document: %s sur %d nœud(s)\n", key, len(results))
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    for _, r := range results {
+        entry := r.entries[key]
+        if entry == nil {
+            fmt.Fprintf(msgOut, "  %-10s absent\n", r.node)
+            continue
+        }
+        fmt.Fprintf(msgOut, "  %-10s hash %s  (%s)\n", r.node, shortHash(entry.Hash), entry.Timestamp)
+    }
+    fmt.Fprintln(msgOut)
+    printDivergence(status, detail)
+    
+    // Le document est affiché une fois s'il est identique partout
+    if status == "identique" {
+        var pretty map[string]interface{}
+        if err := json.Unmarshal(results[0].entries[key].Data, &pretty); err == nil {
+            formatted, _ := json.MarshalIndent(pretty, "", "  ")
+            fmt.Fprintln(msgOut, string(formatted))
+        }
+    }
+}
+
+// doFanOutSearch recherche sur chaque nœud, fusionne les clés trouvées puis
+// compare leurs versions sur tous les nœuds
+func doFanOutSearch(nodes []string, typeName, field, value string, limit int) {
+    results := forEachNode(nodes, func(r *nodeResult) error { return nil })
+    defer closeNodes(results)
+    results = reportNodeErrors(results)
+    
+    // Le type est résolu sur le premier nœud lisible
+    recordType := resolveType(tpleveldb.NewIndexer(results[0].client.GetDB()), typeName, field).Name
+    
+    var wg sync.WaitGroup
+    for _, r := range results {
+        wg.Add(1)
+        go func(r *nodeResult) {
+            defer wg.Done()
+            r.keys, r.err = tpleveldb.NewIndexer(r.client.GetDB()).SearchByIndex(recordType, field, value)
+        }(r)
+    }
+    wg.Wait()
+    results = reportNodeErrors(results)
+    
+    // Union triée des clés trouvées, et nœuds où chaque clé a été trouvée
+    foundOn := make(map[string][]string)
+    for _, r := range results {
+        for _, key := range r.keys {
+            foundOn[key] = append(foundOn[key], r.node)
+        }
+    }
+    keys := make([]string, 0, len(foundOn))
+    for key := range foundOn {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    total := len(keys)
+    if len(keys) > limit {
+        keys = keys[:limit]
+    }
+    
+    readEntries(results, keys)
+    results = reportNodeErrors(results)
+    
+    out := newOutput("key", "found_on", "status", "detail")
+    divergent := 0
+    for _, key := range keys {
+        status, detail := divergence(key, results)
+        // Présente partout mais trouvée seulement sur certains nœuds: index divergent
+        if status == "identique" && len(foundOn[key]) < len(results) {
+            status, detail = "index", "trouvée seulement sur "+strings.Join(foundOn[key], ", ")
+        }
+        if status != "identique" {
+            divergent++
+        }
+        out.Row(key, foundOn[key], status, detail)
+    }
+    
+    if machineOutput() {
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "Recherche: %s.%s = %s sur %d nœud(s)\n", recordType, field, value, len(results))
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    for _, r := range results {
+        fmt.Fprintf(msgOut, "  %-10s %d résultat(s)\n", r.node, len(r.keys))
+    }
+    fmt.Fprintf(msgOut, "  %-10s %d clé(s) distincte(s)\n\n", "fusion", total)
+    flushOutput(out)
+    if total > len(keys) {
+        fmt.Fprintf(msgOut, "  ... (%d autres)\n", total-len(keys))
+    }
+    fmt.Fprintln(msgOut)
+    if divergent == 0 {
+        fmt.Fprintln(msgOut, "✓ Résultats identiques sur tous les nœuds")
+    } else {
+        fmt.Fprintf(msgOut, "⚠ %d clé(s) divergente(s) parmi les %d affichées\n", divergent, len(keys))
+    }
+}
+
+func printDivergence(status, detail string) {
+    switch status {
+    case "identique":
+        fmt.Fprintln(msgOut, "✓ Version identique sur tous les nœuds")
+    case "absent":
+        fmt.Fprintf(msgOut, "⚠ Clé %s\n", detail)
+    default:
+        fmt.Fprintf(msgOut, "⚠ Versions divergentes: %s\n", detail)
+    }
+}
\ No newline at end of file
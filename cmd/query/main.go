@@ -1,269 +1,1707 @@
-// cmd/query/main.go
-// Outil de requêtes et statistiques sur les nœuds LevelDB
-
-package main
-
-import (
-    "encoding/json"
-    "flag"
-    "fmt"
-    "log"
-    "os"
-    "path/filepath"
-    
-    //"github.com/syndtr/goleveldb/leveldb"
-    //"github.com/syndtr/goleveldb/leveldb/util"
-    tpleveldb "leveldb-tp/pkg/leveldb"
-)
-
-func main() {
-    var (
-        node     = flag.String("node", "node1", "Nœud à interroger (node1 ou node2)")
-        count    = flag.Bool("count", false, "Compter le nombre de documents")
-        stats    = flag.Bool("stats", false, "Afficher statistiques détaillées")
-        get      = flag.String("get", "", "Récupérer un document par clé")
-        index    = flag.String("index", "", "Champ d'index pour recherche")
-        value    = flag.String("value", "", "Valeur à rechercher dans l'index")
-        limit    = flag.Int("limit", 10, "Limite de résultats")
-        verify   = flag.String("verify", "", "Vérifier l'intégrité d'un document")
-    )
-    flag.Parse()
-    
-    log.SetFlags(0) // Pas de timestamp dans les logs
-    
-    // Construire chemin du nœud
-    nodePath := filepath.Join("leveldb-stores", *node)
-    
-    // Ouvrir client
-    client, err := tpleveldb.NewClient(nodePath)
-    if err != nil {
-        log.Fatalf("Erreur ouverture nœud %s: %v", *node, err)
-    }
-    defer client.Close()
-    
-    // Router vers la bonne action
-    switch {
-    case *count:
-        doCount(client, *node)
-    case *stats:
-        doStats(client, *node)
-    case *get != "":
-        doGet(client, *get)
-    case *index != "" && *value != "":
-        doSearch(client, *node, *index, *value, *limit)
-    case *verify != "":
-        doVerify(client, *verify)
-    default:
-        fmt.Println("Outil de requêtes LevelDB")
-        fmt.Println()
-        fmt.Println("Usage:")
-        fmt.Println("  query -node node1 -count                    # Compter documents")
-        fmt.Println("  query -node node1 -stats                    # Statistiques")
-        fmt.Println("  query -node node1 -get order:00001          # Récupérer document")
-        fmt.Println("  query -node node1 -index region -value NA   # Recherche par index")
-        fmt.Println("  query -node node1 -verify order:00001       # Vérifier intégrité")
-        fmt.Println()
-        fmt.Println("Options:")
-        flag.PrintDefaults()
-    }
-}
-
-// doCount compte le nombre de documents
-func doCount(client *tpleveldb.Client, node string) {
-    count, err := client.Count()
-    if err != nil {
-        log.Fatalf("Erreur comptage: %v", err)
-    }
-    
-    fmt.Printf("Nœud %s: %d documents\n", node, count)
-}
-
-// doStats affiche des statistiques détaillées
-func doStats(client *tpleveldb.Client, node string) {
-    fmt.Printf("┏━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━┓\n")
-    fmt.Printf("┃   Statistiques Nœud: %-17s┃\n", node)
-    fmt.Printf("┗━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━┛\n")
-    fmt.Println()
-    
-    // Compter total
-    total, err := client.Count()
-    if err != nil {
-        log.Fatalf("Erreur comptage: %v", err)
-    }
-    
-    fmt.Printf("Documents totaux:     %d\n", total)
-    fmt.Println()
-    
-    // Compter par type (parcourir et compter les préfixes)
-    types := map[string]int{
-        "order":   0,
-        "product": 0,
-        "seller":  0,
-        "lead":    0,
-        "idx":     0, // Index
-    }
-    
-    db := client.GetDB()
-    // Remplacer db.Find par une approche correcte utilisant NewIterator
-    iter := db.NewIterator(nil, nil)
-    defer iter.Release()
-    
-    for iter.Next() {
-        key := string(iter.Key())
-        
-        // Ignorer clés système
-        if len(key) > 0 && key[0] == '_' {
-            continue
-        }
-        
-        // Compter par préfixe
-        for prefix := range types {
-            if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
-                types[prefix]++
-                break
-            }
-        }
-    }
-    
-    if err := iter.Error(); err != nil {
-        log.Fatalf("Erreur parcours DB: %v", err)
-    }
-    
-    fmt.Println("Répartition par type:")
-    fmt.Printf("  Commandes:          %d\n", types["order"])
-    fmt.Printf("  Produits:           %d\n", types["product"])
-    fmt.Printf("  Vendeurs:           %d\n", types["seller"])
-    fmt.Printf("  Prospects:          %d\n", types["lead"])
-    fmt.Printf("  Index secondaires:  %d\n", types["idx"])
-    fmt.Println()
-    
-    // Taille sur disque
-    nodePath := filepath.Join("leveldb-stores", node)
-    diskSize := getDiskSize(nodePath)
-    fmt.Printf("Taille sur disque:    %.2f MB\n", diskSize)
-}
-
-// doGet récupère un document
-func doGet(client *tpleveldb.Client, key string) {
-    entry, err := client.Get(key)
-    if err != nil {
-        log.Fatalf("Document non trouvé: %v", err)
-    }
-    
-    // Afficher joliment
-    fmt.Printf("Document: %s\n", key)
-    fmt.Println("════════════════════════════════════════")
-    
-    // Parser et afficher le JSON
-    var prettyJSON map[string]interface{}
-    if err := json.Unmarshal(entry.Data, &prettyJSON); err == nil {
-        formatted, _ := json.MarshalIndent(prettyJSON, "", "  ")
-        fmt.Println(string(formatted))
-    } else {
-        fmt.Println(string(entry.Data))
-    }
-    
-    fmt.Println("════════════════════════════════════════")
-    fmt.Printf("Hash:      %s\n", entry.Hash)
-    fmt.Printf("Timestamp: %s\n", entry.Timestamp)
-    fmt.Printf("Nœud:      %s\n", entry.Node)
-}
-
-// doSearch recherche via index secondaire
-func doSearch(client *tpleveldb.Client, node, field, value string, limit int) {
-    indexer := tpleveldb.NewIndexer(client.GetDB())
-    
-    // Déterminer le type (on suppose "order" par défaut)
-    recordType := "order"
-    
-    fmt.Printf("Recherche: %s = %s (nœud: %s)\n", field, value, node)
-    fmt.Println("════════════════════════════════════════")
-    
-    results, err := indexer.SearchByIndex(recordType, field, value)
-    if err != nil {
-        log.Fatalf("Erreur recherche: %v", err)
-    }
-    
-    if len(results) == 0 {
-        fmt.Println("Aucun résultat trouvé")
-        return
-    }
-    
-    fmt.Printf("Trouvé %d résultat(s)\n\n", len(results))
-    
-    // Afficher les premiers résultats
-    count := 0
-    for _, key := range results {
-        if count >= limit {
-            fmt.Printf("... et %d autres résultats\n", len(results)-limit)
-            break
-        }
-        
-        entry, err := client.Get(key)
-        if err != nil {
-            continue
-        }
-        
-        // Afficher résumé
-        fmt.Printf("%d. %s\n", count+1, key)
-        
-        // Parser et afficher un champ pertinent
-        var data map[string]interface{}
-        if err := json.Unmarshal(entry.Data, &data); err == nil {
-            if amount, ok := data["amount"].(float64); ok {
-                fmt.Printf("   Montant: %.2f\n", amount)
-            }
-            if region, ok := data["region"].(string); ok {
-                fmt.Printf("   Région: %s\n", region)
-            }
-        }
-        
-        fmt.Println()
-        count++
-    }
-}
-
-// doVerify vérifie l'intégrité d'un document
-func doVerify(client *tpleveldb.Client, key string) {
-    fmt.Printf("Vérification intégrité: %s\n", key)
-    fmt.Println("════════════════════════════════════════")
-    
-    valid, err := client.VerifyIntegrity(key)
-    if err != nil {
-        log.Fatalf("Erreur vérification: %v", err)
-    }
-    
-    if valid {
-        fmt.Println("✓ Intégrité vérifiée - Hash valide")
-        fmt.Println()
-        
-        // Afficher le document
-        entry, _ := client.Get(key)
-        fmt.Printf("Hash: %s\n", entry.Hash)
-        fmt.Printf("Timestamp: %s\n", entry.Timestamp)
-    } else {
-        fmt.Println("✗ ATTENTION: Hash invalide!")
-        fmt.Println("Le document a peut-être été corrompu ou modifié")
-    }
-}
-
-// getDiskSize calcule la taille d'un dossier
-func getDiskSize(path string) float64 {
-    var size int64
-    
-    err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
-        if err != nil {
-            return err
-        }
-        if !info.IsDir() {
-            size += info.Size()
-        }
-        return nil
-    })
-    
-    if err != nil {
-        return 0
-    }
-    
-    return float64(size) / 1024 / 1024 // MB
+// cmd/query/main.go
+// Outil de requêtes et statistiques sur les nœuds LevelDB
+
+package main
+
+import (
+    "encoding/json"
+    "flag"
+    "fmt"
+    "io"
+    "log"
+    "math"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+    
+    //"github.com/syndtr/goleveldb/leveldb"
+    //"github.com/syndtr/goleveldb/leveldb/util"
+    "leveldb-tp/pkg/format"
+    tpleveldb "leveldb-tp/pkg/leveldb"
+)
+
+// Format de sortie choisi par -format, et destinations: données sur dataOut,
+// bannières, progression et invites sur msgOut. Avec un format machine, msgOut
+// est la sortie d'erreur: les messages ne se mêlent pas aux enregistrements lus
+// par les scripts. Avec -out, les données vont dans le fichier et msgOut reste
+// la console.
+var (
+    outputFormat           = format.Table
+    dataOut      io.Writer = os.Stdout
+    msgOut       io.Writer = os.Stdout
+    metaColumns            = false // -meta: colonnes hash, timestamp et node
+)
+
+func main() {
+    var (
+        node     = flag.String("node", "node1", "Nœud à interroger (node1 ou node2)")
+        count    = flag.Bool("count", false, "Compter le nombre de documents")
+        stats    = flag.Bool("stats", false, "Afficher statistiques détaillées")
+        get      = flag.String("get", "", "Récupérer un document par clé")
+        recType  = flag.String("type", "", "Type d'enregistrement (order, product, seller, lead...): déduit de l'index si absent")
+        types    = flag.Bool("types", false, "Lister les types d'enregistrement découverts (préfixes de clés et _namespace)")
+        index    = flag.String("index", "", "Champ d'index pour recherche")
+        value    = flag.String("value", "", "Valeur à rechercher dans l'index")
+        limit    = flag.Int("limit", 10, "Limite de résultats (0: tous, avec -format ou -out)")
+        verify   = flag.String("verify", "", "Vérifier l'intégrité d'un document")
+        reindex  = flag.String("reindex", "", "Reconstruire un index: type:champ (ex: product:category)")
+        checkIdx = flag.Bool("check-indexes", false, "Vérifier la cohérence des index secondaires")
+        repair   = flag.Bool("repair", false, "Réparer les incohérences trouvées par -check-indexes")
+        project  = flag.String("project", "", "Champs projetés dans l'index (avec -reindex), ex: weight_g,category")
+        unique   = flag.Bool("unique", false, "Déclarer l'index comme unique (avec -reindex)")
+        orderBy  = flag.String("order-by", "", "Trier les résultats de -index/-value par un autre champ indexé")
+        desc     = flag.Bool("desc", false, "Tri décroissant (avec -index/-value)")
+        after    = flag.String("after", "", "Jeton de continuation pour la page suivante")
+        groupBy  = flag.String("group-by", "", "Agréger par champ indexé: [type:]champ (ex: seller:state)")
+        agg      = flag.String("agg", "count", "Agrégats: count,sum,min,max,avg,p50,p90,...,hist")
+        aggField = flag.String("agg-field", "", "Champ numérique agrégé (ex: weight_g)")
+        buckets  = flag.Int("buckets", 10, "Nombre de classes de l'histogramme (agrégat hist)")
+        fields   = flag.String("fields", "", "Champs à afficher pour -index/-value (lus depuis l'index s'il est couvrant)")
+        loadZips = flag.String("load-zips", "", "Charger les centroïdes de codes postaux depuis un CSV de géolocalisation")
+        geoIndex = flag.String("geo-index", "", "Créer un index géographique: type:champ_code_postal (ex: seller:zip_code_prefix)")
+        geo      = flag.String("geo", "seller:geo", "Index géographique interrogé par -near/-bbox: type:nom")
+        near     = flag.String("near", "", "Rechercher autour d'un point: lat,lon (avec -radius)")
+        radius   = flag.Float64("radius", 10, "Rayon de recherche en km (avec -near)")
+        bbox     = flag.String("bbox", "", "Rechercher dans un rectangle: minLat,minLon,maxLat,maxLon")
+        compIdx  = flag.String("composite", "", "Rechercher dans un index composite: type:champ1,champ2 (avec -value v1[,v2])")
+        from     = flag.String("from", "", "Borne basse (incluse) pour -composite ou -range, source de -diff")
+        to       = flag.String("to", "", "Borne haute (incluse) pour -composite ou -range, cible de -diff")
+        expr     = flag.String("expr", "", "Expression d'un index calculé (avec -reindex type:nom), ex: length_cm * height_cm * width_cm")
+        rangeIdx = flag.String("range", "", "Rechercher une plage -from/-to dans un index: type:nom")
+        join     = flag.String("join", "", "Jointure: gauche.champ=droit[.champ] (ex: lead.seller_id=seller)")
+        strategy = flag.String("strategy", "auto", "Stratégie de jointure: auto, nested ou hash")
+        outer    = flag.Bool("outer", false, "Jointure externe gauche (avec -join)")
+        idxStats = flag.String("index-stats", "", "Statistiques d'un index: type:champ (valeurs fréquentes: -limit)")
+        modSince = flag.String("modified-since", "", "Clés modifiées depuis un instant RFC3339 (ex: 2024-01-01T00:00:00Z)")
+        modUntil = flag.String("modified-until", "", "Borne haute (incluse) pour -modified-since")
+        mtimeIdx = flag.Bool("rebuild-mtime", false, "Reconstruire l'index des dates de modification")
+        pruneLog = flag.Uint64("prune-changes", 0, "Supprimer du journal des changements les numéros < N (déjà consommés par les vues; le Client en garde sinon les 10000 derniers)")
+        shell    = flag.Bool("shell", false, "Shell interactif sur le nœud (get, put, scan, search, use...)")
+        sqlQuery = flag.String("q", "", "Requête: SELECT champs FROM type [WHERE ...] [ORDER BY champ [DESC]] [LIMIT n]")
+        normName = flag.String("normalizer", "", "Normaliseur de l'index (avec -reindex): casefold, exact, accentfold ou numeric")
+        outFmt   = flag.String("format", "table", "Format de sortie: table, json, ndjson ou csv")
+        explain  = flag.Bool("explain", false, "Exécuter -q ou -index/-value et afficher le plan mesuré (lignes, pas d'itérateur, lectures, temps)")
+        diffKey  = flag.String("diff", "", "Comparer un document entre -from et -to: nœud, snapshot:nom, nœud~N (N versions avant) ou nœud@N (N-ième version)")
+        putKey   = flag.String("put", "", "Écrire un document (avec -data): index, hash et journal tenus à jour")
+        data     = flag.String("data", "", "Document JSON pour -put: texte, @fichier.json ou @- (entrée standard)")
+        patchKey = flag.String("patch", "", "Modifier un document existant (avec -merge)")
+        merge    = flag.String("merge", "", "Patch JSON fusionné par -patch (null supprime un champ): texte, @fichier ou @-")
+        delKey   = flag.String("delete", "", "Supprimer un document")
+        dryRun   = flag.Bool("dry-run", false, "Afficher l'effet de -put, -patch ou -delete sans écrire")
+        yes      = flag.Bool("yes", false, "Ne pas demander de confirmation pour -put, -patch ou -delete")
+        nodes    = flag.String("nodes", "", "Interroger plusieurs nœuds en parallèle: all ou liste (ex: node1,node2), avec -count, -get ou -index/-value")
+        outFile  = flag.String("out", "", "Écrire les résultats dans un fichier, au fil de l'eau: format déduit de l'extension (.ndjson, .csv, .json) sauf -format")
+        meta     = flag.Bool("meta", false, "Ajouter les colonnes hash, timestamp et node aux résultats de -q et -index/-value (sortie -format ou -out)")
+    )
+    flag.Parse()
+    
+    log.SetFlags(0) // Pas de timestamp dans les logs
+    
+    var err error
+    if outputFormat, err = format.Parse(*outFmt); err != nil {
+        log.Fatalf("Option -format: %v", err)
+    }
+    if *outFile != "" {
+        file := openOutFile(*outFile, *outFmt)
+        defer closeOutFile(file, *outFile)
+    } else if machineOutput() {
+        msgOut = os.Stderr
+    }
+    
+    metaColumns = *meta
+    if metaColumns && (!machineOutput() || (*sqlQuery == "" && (*index == "" || *value == ""))) {
+        log.Fatalf("-meta s'applique à -q ou à -index/-value, avec -format json/ndjson/csv ou -out")
+    }
+    
+    // Le diff ouvre lui-même ses sources, en lecture seule
+    if *diffKey != "" {
+        doDiff(*node, *diffKey, *from, *to)
+        return
+    }
+    
+    // Plusieurs nœuds: chaque nœud est ouvert par l'action, en parallèle
+    if *nodes != "" {
+        targets := parseNodes(*nodes)
+        if len(targets) == 0 {
+            log.Fatalf("Option -nodes: aucun nœud")
+        }
+        switch {
+        case *count:
+            doFanOutCount(targets)
+        case *get != "":
+            doFanOutGet(targets, *get)
+        case *index != "" && *value != "":
+            doFanOutSearch(targets, *recType, *index, *value, *limit)
+        default:
+            log.Fatalf("-nodes s'applique à -count, -get ou -index/-value")
+        }
+        return
+    }
+    
+    // Construire chemin du nœud
+    nodePath := filepath.Join("leveldb-stores", *node)
+    
+    // Ouvrir client
+    client, err := tpleveldb.NewClient(nodePath)
+    if err != nil {
+        log.Fatalf("Erreur ouverture nœud %s: %v", *node, err)
+    }
+    // Le shell peut changer de nœud: fermer le client courant en sortie
+    defer func() { client.Close() }()
+    
+    if *explain && *sqlQuery == "" && (*index == "" || *value == "") {
+        log.Fatalf("-explain s'applique à -q ou à -index/-value")
+    }
+    
+    // Router vers la bonne action
+    switch {
+    case *shell:
+        client = runShell(client, *node)
+    case *sqlQuery != "" && *explain:
+        doExplainSQL(client, *sqlQuery)
+    case *sqlQuery != "":
+        doSQL(client, *sqlQuery)
+    case *types:
+        doTypes(client)
+    case *count:
+        doCount(client, *node)
+    case *stats:
+        doStats(client, *node)
+    case *get != "":
+        doGet(client, *get)
+    case *putKey != "":
+        doPut(client, *putKey, *data, writeOptions{dryRun: *dryRun, yes: *yes})
+    case *patchKey != "":
+        doPatch(client, *patchKey, *merge, writeOptions{dryRun: *dryRun, yes: *yes})
+    case *delKey != "":
+        doDelete(client, *delKey, writeOptions{dryRun: *dryRun, yes: *yes})
+    case *join != "":
+        doJoin(client, *join, *strategy, *outer, *index, *value, splitList(*fields), *limit)
+    case *index != "" && *value != "" && *explain:
+        opts := tpleveldb.SearchOptions{OrderBy: *orderBy, Desc: *desc, PageSize: *limit, After: *after}
+        doExplainSearch(client, *recType, *index, *value, opts)
+    case *index != "" && *value != "":
+        opts := tpleveldb.SearchOptions{OrderBy: *orderBy, Desc: *desc, PageSize: *limit, After: *after}
+        doSearch(client, *node, *recType, *index, *value, opts, splitList(*fields))
+    case *verify != "":
+        doVerify(client, *verify)
+    case *groupBy != "":
+        doAggregate(client, *recType, *groupBy, splitList(*agg), *aggField, *buckets)
+    case *idxStats != "":
+        doIndexStats(client, *idxStats, *limit)
+    case *modSince != "":
+        doModifiedSince(client, *modSince, *modUntil, *limit)
+    case *mtimeIdx:
+        doRebuildMtime(client)
+    case *pruneLog > 0:
+        doPruneChanges(client, *pruneLog)
+    case *reindex != "":
+        doReindex(client, *reindex, *expr, splitList(*project), *unique, *normName)
+    case *checkIdx:
+        doCheckIndexes(client, *repair)
+    case *loadZips != "":
+        doLoadZips(client, *loadZips)
+    case *geoIndex != "":
+        doGeoIndex(client, *geoIndex)
+    case *near != "" || *bbox != "":
+        doGeoSearch(client, *geo, *near, *radius, *bbox, *limit)
+    case *rangeIdx != "":
+        doRangeSearch(client, *rangeIdx, *from, *to, *limit)
+    case *compIdx != "":
+        doCompositeSearch(client, *compIdx, splitList(*value), *from, *to, *limit)
+    default:
+        fmt.Fprintln(msgOut, "Outil de requêtes LevelDB")
+        fmt.Fprintln(msgOut)
+        fmt.Fprintln(msgOut, "Usage:")
+        fmt.Fprintln(msgOut, "  query -node node1 -count                    # Compter documents")
+        fmt.Fprintln(msgOut, "  query -node node1 -stats                    # Statistiques")
+        fmt.Fprintln(msgOut, "  query -node node1 -get order:00001          # Récupérer document")
+        fmt.Fprintln(msgOut, "  query -node node1 -types                    # Types d'enregistrement")
+        fmt.Fprintln(msgOut, "  query -node node1 -index region -value NA   # Recherche par index")
+        fmt.Fprintln(msgOut, "  query -node node1 -type seller -index state -value SP  # Recherche sur un type")
+        fmt.Fprintln(msgOut, "  query -node node1 -verify order:00001       # Vérifier intégrité")
+        fmt.Fprintln(msgOut, "  query -node node1 -reindex product:category # Reconstruire un index")
+        fmt.Fprintln(msgOut, "  query -node node1 -reindex order:region -project amount,status  # Index couvrant")
+        fmt.Fprintln(msgOut, "  query -node node1 -reindex lead:mql_id -unique                  # Index unique")
+        fmt.Fprintln(msgOut, "  query -node node1 -reindex seller:city -normalizer accentfold   # Recherche sans accents")
+        fmt.Fprintln(msgOut, "  query -node node1 -reindex 'order:items[*].product_id'          # Index sur chemin JSON")
+        fmt.Fprintln(msgOut, "  query -node node1 -group-by seller:state -agg count             # Agrégation")
+        fmt.Fprintln(msgOut, "  query -node node1 -group-by product:category -agg avg,p90 -agg-field weight_g")
+        fmt.Fprintln(msgOut, "  query -node node1 -index region -value NA -fields amount,status")
+        fmt.Fprintln(msgOut, "  query -node node1 -index region -value NA -order-by status -desc -limit 20")
+        fmt.Fprintln(msgOut, "  query -node node1 -check-indexes -repair    # Vérifier/réparer les index")
+        fmt.Fprintln(msgOut, "  query -node node1 -index-stats seller:state -limit 5           # Sélectivité d'un index")
+        fmt.Fprintln(msgOut, "  query -node node1 -shell                   # Session interactive")
+        fmt.Fprintln(msgOut, "  query -node node1 -q \"SELECT key, category FROM product WHERE weight_g > 500 AND category = 'perfumaria' ORDER BY weight_g LIMIT 20\"")
+        fmt.Fprintln(msgOut, "  query -node node1 -explain -q \"SELECT key FROM product WHERE category = 'perfumaria' AND weight_g > 500\"")
+        fmt.Fprintln(msgOut, "  query -node node1 -modified-since 2024-01-01T00:00:00Z     # Clés modifiées depuis")
+        fmt.Fprintln(msgOut, "  query -node node1 -prune-changes 50000                     # Purger le début du journal")
+        fmt.Fprintln(msgOut, "  loader -node node1 -keep-changes 0                         # Charger sans conserver le journal")
+        fmt.Fprintln(msgOut, "  query -node node1 -load-zips geolocation.csv               # Centroïdes des codes postaux")
+        fmt.Fprintln(msgOut, "  query -node node1 -geo-index seller:zip_code_prefix        # Index géographique")
+        fmt.Fprintln(msgOut, "  query -node node1 -near -23.55,-46.63 -radius 25           # Vendeurs proches")
+        fmt.Fprintln(msgOut, "  query -node node1 -bbox -24,-47,-23,-46                    # Vendeurs dans un rectangle")
+        fmt.Fprintln(msgOut, "  query -node node1 -reindex seller:state,city               # Index composite")
+        fmt.Fprintln(msgOut, "  query -node node1 -composite seller:state,city -value sp -from a -to c")
+        fmt.Fprintln(msgOut, "  query -node node1 -reindex product:volume -expr 'length_cm * height_cm * width_cm'")
+        fmt.Fprintln(msgOut, "  query -node node1 -range product:volume -from 1000 -to 5000  # Plage sur un index")
+        fmt.Fprintln(msgOut, "  query -node node1 -join lead.seller_id=seller -fields mql_id,won_date,seller.state")
+        fmt.Fprintln(msgOut, "  query -node node1 -join lead.seller_id=seller -index pipeline_stage -value closed -strategy hash")
+        fmt.Fprintln(msgOut, "  query -node node1 -range product:volume -from 1000 -to 5000 -format csv  # Sortie pour scripts")
+        fmt.Fprintln(msgOut, "  query -nodes all -get order:00001          # Comparer un document sur tous les nœuds")
+        fmt.Fprintln(msgOut, "  query -nodes node1,node2 -index region -value NA  # Recherche fusionnée, divergences signalées")
+        fmt.Fprintln(msgOut, "  query -node node1 -diff order:00001                    # Dernière modification du document")
+        fmt.Fprintln(msgOut, "  query -node node1 -put order:00001 -data @order.json   # Écrire un document")
+        fmt.Fprintln(msgOut, "  query -node node1 -patch order:00001 -merge '{\"status\":\"shipped\"}' -dry-run")
+        fmt.Fprintln(msgOut, "  query -node node1 -delete order:00001 -yes             # Supprimer sans confirmation")
+        fmt.Fprintln(msgOut, "  query -node node1 -q \"SELECT * FROM seller WHERE state = 'SP'\" -out sp.ndjson -meta  # Extraction")
+        fmt.Fprintln(msgOut, "  query -node node1 -index state -value SP -limit 0 -out sp.csv  # Tous les résultats en CSV")
+        fmt.Fprintln(msgOut, "  query -diff order:00001 -from node1 -to node2          # Document sur deux nœuds")
+        fmt.Fprintln(msgOut, "  query -diff order:00001 -from snapshot:avant -to node1~1  # Snapshot et version précédente")
+        fmt.Fprintln(msgOut)
+        fmt.Fprintln(msgOut, "Options:")
+        flag.PrintDefaults()
+    }
+}
+
+// doCount compte le nombre de documents
+func doCount(client *tpleveldb.Client, node string) {
+    count, err := client.Count()
+    if err != nil {
+        log.Fatalf("Erreur comptage: %v", err)
+    }
+    
+    if machineOutput() {
+        out := newOutput("node", "documents")
+        out.Row(node, count)
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "Nœud %s: %d documents\n", node, count)
+}
+
+// doStats affiche des statistiques détaillées: préfixes de clés découverts,
+// distribution des tailles et état interne de goleveldb
+func doStats(client *tpleveldb.Client, node string) {
+    // Compter total
+    total, err := client.Count()
+    if err != nil {
+        log.Fatalf("Erreur comptage: %v", err)
+    }
+    
+    stats, err := client.StoreStats()
+    if err != nil {
+        log.Fatalf("Erreur statistiques: %v", err)
+    }
+    
+    // Taille sur disque
+    nodePath := filepath.Join("leveldb-stores", node)
+    diskSize := getDiskSize(nodePath)
+    
+    if machineOutput() {
+        out := newOutput("node", "documents", "disk_mb", "prefixes", "key_sizes", "value_sizes", "engine")
+        out.Row(node, total, diskSize, stats.Prefixes, stats.KeySizes, stats.ValueSizes, stats.Engine)
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "┏━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━┓\n")
+    fmt.Fprintf(msgOut, "┃   Statistiques Nœud: %-17s┃\n", node)
+    fmt.Fprintf(msgOut, "┗━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━┛\n")
+    fmt.Fprintln(msgOut)
+    
+    fmt.Fprintf(msgOut, "Documents totaux:     %d\n", total)
+    fmt.Fprintf(msgOut, "Taille sur disque:    %.2f MB\n", diskSize)
+    fmt.Fprintln(msgOut)
+    
+    fmt.Fprintln(msgOut, "Répartition par préfixe (taille tables: estimation db.SizeOf, hors journal):")
+    out := newOutput("préfixe", "clés", "clés (KB)", "valeurs (KB)", "tables (KB)")
+    for _, ps := range stats.Prefixes {
+        out.Row(ps.Prefix, ps.Keys, kb(ps.KeyBytes), kb(ps.ValueBytes), kb(ps.ApproxBytes))
+    }
+    flushOutput(out)
+    fmt.Fprintln(msgOut)
+    
+    printSizeHistogram("Taille des clés", stats.KeySizes)
+    printSizeHistogram("Taille des valeurs", stats.ValueSizes)
+    
+    engine := stats.Engine
+    fmt.Fprintln(msgOut, "Moteur goleveldb:")
+    if len(engine.Levels) > 0 {
+        out = newOutput("niveau", "tables", "taille (KB)", "compaction lue (KB)", "compaction écrite (KB)", "durée")
+        for _, l := range engine.Levels {
+            out.Row(l.Level, l.Tables, kb(l.SizeBytes), kb(l.ReadBytes), kb(l.WriteBytes), l.Duration.String())
+        }
+        flushOutput(out)
+    } else {
+        fmt.Fprintln(msgOut, "  (aucune table: données encore dans le journal)")
+    }
+    fmt.Fprintf(msgOut, "  E/S:                lues %.1f KB, écrites %.1f KB\n", kb(int64(engine.IOReadBytes)), kb(int64(engine.IOWriteBytes)))
+    fmt.Fprintf(msgOut, "  Ralentissements:    %d (%v)", engine.WriteDelayCount, engine.WriteDelay)
+    if engine.WritePaused {
+        fmt.Fprint(msgOut, ", écritures en pause")
+    }
+    fmt.Fprintln(msgOut)
+    fmt.Fprintf(msgOut, "  Tables ouvertes:    %d\n", engine.OpenedTables)
+    fmt.Fprintf(msgOut, "  Cache de blocs:     %.1f KB\n", kb(int64(engine.BlockCacheBytes)))
+    fmt.Fprintf(msgOut, "  Pool de tampons:    %d demandes, %d réutilisés, %d allocations\n",
+        engine.BufferPoolGets, engine.BufferPoolHits(), engine.BufferPoolMisses)
+    fmt.Fprintf(msgOut, "  Snapshots/itérateurs actifs: %d/%d\n", engine.AliveSnapshots, engine.AliveIterators)
+    
+    if engine.SSTables != "" {
+        fmt.Fprintln(msgOut)
+        fmt.Fprintln(msgOut, "Tables SST (leveldb.sstables):")
+        fmt.Fprint(msgOut, engine.SSTables)
+    }
+}
+
+// printSizeHistogram affiche une distribution de tailles par classes de puissances de 2
+func printSizeHistogram(title string, h tpleveldb.SizeHistogram) {
+    fmt.Fprintf(msgOut, "%s: %d, min %d, max %d, moyenne %.1f octets\n", title, h.Count, h.Min, h.Max, h.Avg())
+    
+    peak := 0
+    for _, n := range h.Buckets {
+        if n > peak {
+            peak = n
+        }
+    }
+    for i, n := range h.Buckets {
+        if n == 0 {
+            continue
+        }
+        fmt.Fprintf(msgOut, "  ≤ %-8d %8d  %s\n", h.BucketLimit(i), n, strings.Repeat("█", (n*30+peak-1)/peak))
+    }
+    fmt.Fprintln(msgOut)
+}
+
+// kb convertit des octets en kilo-octets, arrondis au dixième
+func kb(bytes int64) float64 {
+    return math.Round(float64(bytes)/102.4) / 10
+}
+
+// doGet récupère un document
+func doGet(client *tpleveldb.Client, key string) {
+    entry, err := client.Get(key)
+    if err != nil {
+        log.Fatalf("Document non trouvé: %v", err)
+    }
+    
+    if machineOutput() {
+        out := newOutput("key", "data", "hash", "timestamp", "node")
+        out.Row(key, entry.Data, entry.Hash, entry.Timestamp, entry.Node)
+        flushOutput(out)
+        return
+    }
+    
+    // Afficher joliment
+    fmt.Fprintf(msgOut, "Document: %s\n", key)
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    
+    // Parser et afficher le JSON
+    var prettyJSON map[string]interface{}
+    if err := json.Unmarshal(entry.Data, &prettyJSON); err == nil {
+        formatted, _ := json.MarshalIndent(prettyJSON, "", "  ")
+        fmt.Fprintln(msgOut, string(formatted))
+    } else {
+        fmt.Fprintln(msgOut, string(entry.Data))
+    }
+    
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    fmt.Fprintf(msgOut, "Hash:      %s\n", entry.Hash)
+    fmt.Fprintf(msgOut, "Timestamp: %s\n", entry.Timestamp)
+    fmt.Fprintf(msgOut, "Nœud:      %s\n", entry.Node)
+}
+
+// doSearch recherche via index secondaire
+func doSearch(client *tpleveldb.Client, node, typeName, field, value string, opts tpleveldb.SearchOptions, fields []string) {
+    indexer := tpleveldb.NewIndexer(client.GetDB())
+    
+    rt := resolveType(indexer, typeName, field)
+    recordType := rt.Name
+    
+    if machineOutput() {
+        doSearchOutput(client, indexer, recordType, field, value, opts, fields)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "Recherche: %s.%s = %s (nœud: %s)\n", recordType, field, value, node)
+    if opts.OrderBy != "" {
+        fmt.Fprintf(msgOut, "Tri: %s", opts.OrderBy)
+        if opts.Desc {
+            fmt.Fprint(msgOut, " (décroissant)")
+        }
+        fmt.Fprintln(msgOut)
+    }
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    
+    if len(fields) > 0 {
+        doSearchFields(client, indexer, recordType, field, value, opts, fields)
+        return
+    }
+    
+    total, err := indexer.CountByIndex(recordType, field, value)
+    if err != nil {
+        log.Fatalf("Erreur recherche: %v", err)
+    }
+    
+    if total == 0 {
+        fmt.Fprintln(msgOut, "Aucun résultat trouvé")
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "Trouvé %d résultat(s)\n\n", total)
+    
+    // Une seule page est lue: les résultats ne sont jamais tous chargés en mémoire
+    page, err := indexer.SearchPage(recordType, field, value, opts)
+    if err != nil {
+        log.Fatalf("Erreur recherche: %v", err)
+    }
+    
+    for i, key := range page.Keys {
+        entry, err := client.Get(key)
+        if err != nil {
+            continue
+        }
+        
+        // Afficher résumé
+        fmt.Fprintf(msgOut, "%d. %s\n", i+1, key)
+        
+        // Afficher les champs du type, puis le champ de tri s'il n'en fait pas partie
+        var data map[string]interface{}
+        if err := json.Unmarshal(entry.Data, &data); err == nil {
+            for _, f := range rt.Fields {
+                if v, ok := data[f]; ok {
+                    fmt.Fprintf(msgOut, "   %s: %v\n", f, v)
+                }
+            }
+            if opts.OrderBy != "" && !containsField(rt.Fields, opts.OrderBy) {
+                fmt.Fprintf(msgOut, "   %s: %v\n", opts.OrderBy, data[opts.OrderBy])
+            }
+        }
+        
+        fmt.Fprintln(msgOut)
+    }
+    
+    if page.Next != "" {
+        fmt.Fprintln(msgOut, "... page suivante:")
+        fmt.Fprintf(msgOut, "   -after %s\n", page.Next)
+    }
+}
+
+// doSearchOutput écrit une page de résultats dans le format -format: la clé et
+// le document, ou la clé et les champs demandés par -fields. Le jeton de la page
+// suivante est écrit sur la sortie d'erreur pour ne pas se mêler aux données.
+func doSearchOutput(client *tpleveldb.Client, indexer *tpleveldb.Indexer, recordType, field, value string, opts tpleveldb.SearchOptions, fields []string) {
+    // Lecture en flux: -limit borne la page (0: tous les résultats)
+    it, err := indexer.IterateIndex(recordType, field, value, opts)
+    if err != nil {
+        log.Fatalf("Erreur recherche: %v", err)
+    }
+    defer it.Release()
+    
+    columns := []string{"key", "data"}
+    if len(fields) > 0 {
+        columns = append([]string{"key"}, fields...)
+    }
+    if metaColumns {
+        columns = append(columns, "hash", "timestamp", "node")
+    }
+    out := newOutput(columns...)
+    
+    // Seules les lignes écrites comptent dans la page; le jeton de la page
+    // suivante repère la dernière d'entre elles
+    n, last, next := 0, "", ""
+    for it.Next() {
+        if opts.PageSize > 0 && n == opts.PageSize {
+            next = last
+            break
+        }
+        
+        key := it.Key()
+        entry, err := client.Get(key)
+        if err != nil {
+            fmt.Fprintf(msgOut, "⚠ %s ignoré: %v\n", key, err)
+            continue
+        }
+        row := []interface{}{key, entry.Data}
+        if len(fields) > 0 {
+            var data map[string]interface{}
+            json.Unmarshal(entry.Data, &data)
+            row = row[:1]
+            for _, f := range fields {
+                row = append(row, tpleveldb.FieldValue(data, f))
+            }
+        }
+        if metaColumns {
+            row = append(row, entry.Hash, entry.Timestamp, entry.Node)
+        }
+        if err := out.Row(row...); err != nil {
+            log.Fatalf("Erreur écriture sortie: %v", err)
+        }
+        
+        n++
+        if n == opts.PageSize {
+            last = it.Token()
+        }
+    }
+    if err := it.Error(); err != nil {
+        log.Fatalf("Erreur recherche: %v", err)
+    }
+    flushOutput(out)
+    
+    if next != "" {
+        fmt.Fprintf(os.Stderr, "page suivante: -after %s\n", next)
+    }
+}
+
+// doSearchFields affiche une vue liste des champs demandés, page par page
+// comme doSearch (tri, -after). Si l'index parcouru est couvrant, les valeurs
+// viennent de l'entrée d'index, sans relire le document.
+func doSearchFields(client *tpleveldb.Client, indexer *tpleveldb.Indexer, recordType, field, value string, opts tpleveldb.SearchOptions, fields []string) {
+    total, err := indexer.CountByIndex(recordType, field, value)
+    if err != nil {
+        log.Fatalf("Erreur recherche: %v", err)
+    }
+    
+    if total == 0 {
+        fmt.Fprintln(msgOut, "Aucun résultat trouvé")
+        return
+    }
+    
+    // Lecture en flux d'une seule page, de la même taille que SearchPage
+    pageSize := opts.PageSize
+    if pageSize <= 0 {
+        pageSize = 100
+    }
+    it, err := indexer.IterateIndex(recordType, field, value, opts)
+    if err != nil {
+        log.Fatalf("Erreur recherche: %v", err)
+    }
+    defer it.Release()
+    
+    if it.Covers(fields) {
+        fmt.Fprintf(msgOut, "Trouvé %d résultat(s) (index couvrant)\n\n", total)
+    } else {
+        fmt.Fprintf(msgOut, "Trouvé %d résultat(s)\n\n", total)
+    }
+    
+    n, last, next := 0, "", ""
+    for it.Next() {
+        if n == pageSize {
+            next = last
+            break
+        }
+        
+        key := it.Key()
+        data := it.Fields()
+        if !it.Covers(fields) {
+            entry, err := client.Get(key)
+            if err != nil {
+                continue
+            }
+            if err := json.Unmarshal(entry.Data, &data); err != nil {
+                continue
+            }
+        }
+        
+        n++
+        last = it.Token()
+        fmt.Fprintf(msgOut, "%d. %s\n", n, key)
+        for _, f := range fields {
+            if f == field {
+                fmt.Fprintf(msgOut, "   %s: %s\n", f, value)
+                continue
+            }
+            if v := tpleveldb.FieldValue(data, f); v != nil {
+                fmt.Fprintf(msgOut, "   %s: %v\n", f, v)
+            }
+        }
+        fmt.Fprintln(msgOut)
+    }
+    if err := it.Error(); err != nil {
+        log.Fatalf("Erreur recherche: %v", err)
+    }
+    
+    if next != "" {
+        fmt.Fprintln(msgOut, "... page suivante:")
+        fmt.Fprintf(msgOut, "   -after %s\n", next)
+    }
+}
+
+// doExplainSQL exécute une requête -q et affiche son plan mesuré à la place des lignes
+func doExplainSQL(client *tpleveldb.Client, src string) {
+    _, explain, err := tpleveldb.NewIndexer(client.GetDB()).ExplainSQL(src)
+    if err != nil {
+        log.Fatalf("Erreur requête: %v", err)
+    }
+    printExplain(explain)
+}
+
+// doExplainSearch exécute une page de recherche par index, lit les documents
+// comme doSearch et affiche le plan mesuré
+func doExplainSearch(client *tpleveldb.Client, typeName, field, value string, opts tpleveldb.SearchOptions) {
+    indexer := tpleveldb.NewIndexer(client.GetDB())
+    recordType := resolveType(indexer, typeName, field).Name
+    
+    page, explain, err := indexer.ExplainSearch(recordType, field, value, opts)
+    if err != nil {
+        log.Fatalf("Erreur recherche: %v", err)
+    }
+    
+    // Lecture des documents de la page: une lecture ponctuelle par clé
+    fetch := &tpleveldb.PlanStep{Op: "FETCH", Detail: "lecture des documents", Estimate: explain.Plan.Estimate, Children: []*tpleveldb.PlanStep{explain.Plan}}
+    start := time.Now()
+    for _, key := range page.Keys {
+        fetch.Reads++
+        if _, err := client.Get(key); err == nil {
+            fetch.Rows++
+        }
+    }
+    elapsed := time.Since(start)
+    fetch.Duration = explain.Plan.Duration + elapsed
+    explain.Plan = fetch
+    explain.AddStage("lecture documents", elapsed)
+    explain.Rows = fetch.Rows
+    
+    printExplain(explain)
+}
+
+// printExplain affiche l'arbre du plan (estimé/réel, pas d'itérateur, lectures
+// ponctuelles, temps inclusif) puis le temps de chaque phase
+func printExplain(explain *tpleveldb.Explain) {
+    if machineOutput() {
+        out := newOutput("statement", "plan", "stages", "rows")
+        out.Row(explain.Statement, explain.Plan, explain.Stages, explain.Rows)
+        flushOutput(out)
+        return
+    }
+    
+    type line struct {
+        label string
+        step  *tpleveldb.PlanStep
+    }
+    var lines []line
+    width := 0
+    explain.Plan.Walk(func(step *tpleveldb.PlanStep, depth int) {
+        label := step.Op
+        if step.Detail != "" {
+            label += " " + step.Detail
+        }
+        if depth > 0 {
+            label = strings.Repeat("   ", depth-1) + "└─ " + label
+        }
+        if n := len([]rune(label)); n > width {
+            width = n
+        }
+        lines = append(lines, line{label, step})
+    })
+    
+    fmt.Fprintf(msgOut, "Plan: %s\n", explain.Statement)
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    fmt.Fprintf(msgOut, "%-*s  %8s  %8s  %8s  %8s  %10s\n", width, "nœud", "estimé", "réel", "pas", "lectures", "temps")
+    for _, l := range lines {
+        estimate := "?"
+        if l.step.Estimate >= 0 {
+            estimate = strconv.Itoa(l.step.Estimate)
+        }
+        // %-*s compte des octets: le remplissage est calculé en caractères
+        pad := strings.Repeat(" ", width-len([]rune(l.label)))
+        fmt.Fprintf(msgOut, "%s%s  %8s  %8d  %8d  %8d  %10v\n", l.label, pad, estimate, l.step.Rows, l.step.Steps, l.step.Reads, l.step.Duration.Round(time.Microsecond))
+    }
+    
+    fmt.Fprintln(msgOut)
+    fmt.Fprintln(msgOut, "Phases:")
+    var total time.Duration
+    for _, stage := range explain.Stages {
+        fmt.Fprintf(msgOut, "  %-20s %v\n", stage.Name, stage.Duration.Round(time.Microsecond))
+        total += stage.Duration
+    }
+    fmt.Fprintf(msgOut, "  %-20s %v\n", "total", total.Round(time.Microsecond))
+    fmt.Fprintf(msgOut, "\n%d ligne(s)\n", explain.Rows)
+}
+
+// resolveType retourne le type interrogé: celui de -type, sinon le seul type
+// qui a un index sur le champ
+func resolveType(indexer *tpleveldb.Indexer, typeName, field string) tpleveldb.RecordType {
+    types, err := indexer.RecordTypes()
+    if err != nil {
+        log.Fatalf("Erreur découverte des types: %v", err)
+    }
+    names := make([]string, len(types))
+    for i, rt := range types {
+        names[i] = rt.Name
+    }
+    
+    if typeName == "" {
+        indexed, err := indexer.TypesWithIndex(field)
+        if err != nil {
+            log.Fatalf("Erreur recherche d'index: %v", err)
+        }
+        switch len(indexed) {
+        case 0:
+            log.Fatalf("Aucun index %q: préciser -type (types: %s)", field, strings.Join(names, ", "))
+        case 1:
+            typeName = indexed[0]
+        default:
+            log.Fatalf("Index %q présent pour plusieurs types (%s): préciser -type", field, strings.Join(indexed, ", "))
+        }
+    }
+    
+    for _, rt := range types {
+        if rt.Name == typeName {
+            return rt
+        }
+    }
+    log.Fatalf("Type inconnu: %q (types: %s)", typeName, strings.Join(names, ", "))
+    return tpleveldb.RecordType{}
+}
+
+// containsField indique si un champ fait partie d'une liste
+func containsField(fields []string, field string) bool {
+    for _, f := range fields {
+        if f == field {
+            return true
+        }
+    }
+    return false
+}
+
+// doTypes liste les types d'enregistrement découverts et leurs champs affichés
+func doTypes(client *tpleveldb.Client) {
+    types, err := tpleveldb.NewIndexer(client.GetDB()).RecordTypes()
+    if err != nil {
+        log.Fatalf("Erreur découverte des types: %v", err)
+    }
+    
+    if machineOutput() {
+        out := newOutput("name", "namespace", "description", "documents", "fields")
+        for _, rt := range types {
+            out.Row(rt.Name, rt.Namespace, rt.Description, rt.Documents, rt.Fields)
+        }
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintln(msgOut, "Types d'enregistrement")
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    for _, rt := range types {
+        fmt.Fprintf(msgOut, "%-12s", rt.Name)
+        if rt.Namespace != "" {
+            fmt.Fprintf(msgOut, " namespace %s", rt.Namespace)
+        }
+        if !rt.Documents {
+            fmt.Fprint(msgOut, " (aucun document)")
+        }
+        fmt.Fprintln(msgOut)
+        if rt.Description != "" {
+            fmt.Fprintf(msgOut, "   %s\n", rt.Description)
+        }
+        if len(rt.Fields) > 0 {
+            fmt.Fprintf(msgOut, "   champs: %s\n", strings.Join(rt.Fields, ", "))
+        }
+    }
+}
+
+// doVerify vérifie l'intégrité d'un document
+func doVerify(client *tpleveldb.Client, key string) {
+    if machineOutput() {
+        // Une empreinte invalide est un résultat, pas une erreur de l'outil
+        valid, err := client.VerifyIntegrity(key)
+        entry, getErr := client.Get(key)
+        if getErr != nil {
+            log.Fatalf("Erreur vérification: %v", getErr)
+        }
+        reason := ""
+        if err != nil {
+            reason = err.Error()
+        }
+        out := newOutput("key", "valid", "hash", "timestamp", "reason")
+        out.Row(key, valid, entry.Hash, entry.Timestamp, reason)
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "Vérification intégrité: %s\n", key)
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    
+    valid, err := client.VerifyIntegrity(key)
+    if err != nil {
+        log.Fatalf("Erreur vérification: %v", err)
+    }
+    
+    if valid {
+        fmt.Fprintln(msgOut, "✓ Intégrité vérifiée - Hash valide")
+        fmt.Fprintln(msgOut)
+        
+        // Afficher le document
+        entry, _ := client.Get(key)
+        fmt.Fprintf(msgOut, "Hash: %s\n", entry.Hash)
+        fmt.Fprintf(msgOut, "Timestamp: %s\n", entry.Timestamp)
+    } else {
+        fmt.Fprintln(msgOut, "✗ ATTENTION: Hash invalide!")
+        fmt.Fprintln(msgOut, "Le document a peut-être été corrompu ou modifié")
+    }
+}
+
+// doAggregate affiche les agrégats par valeur d'un champ indexé
+func doAggregate(client *tpleveldb.Client, typeName, spec string, aggs []string, field string, buckets int) {
+    recordType, groupBy := typeName, spec
+    if parts := strings.SplitN(spec, ":", 2); len(parts) == 2 {
+        recordType, groupBy = parts[0], parts[1]
+    }
+    if recordType == "" {
+        recordType = resolveType(tpleveldb.NewIndexer(client.GetDB()), "", groupBy).Name
+    }
+    
+    q := tpleveldb.AggQuery{RecordType: recordType, GroupBy: groupBy, Field: field}
+    for _, a := range aggs {
+        switch {
+        case a == "hist":
+            q.Buckets = buckets
+        case strings.HasPrefix(a, "p"):
+            p, err := strconv.ParseFloat(a[1:], 64)
+            if err != nil || p < 0 || p > 100 {
+                log.Fatalf("Percentile invalide: %s", a)
+            }
+            q.Percentiles = append(q.Percentiles, p)
+        case a == "count":
+        case a == "sum" || a == "min" || a == "max" || a == "avg":
+        default:
+            log.Fatalf("Agrégat inconnu: %s", a)
+        }
+        if a != "count" && field == "" {
+            log.Fatalf("L'agrégat %s nécessite -agg-field", a)
+        }
+    }
+    
+    groups, err := tpleveldb.NewIndexer(client.GetDB()).Aggregate(q)
+    if err != nil {
+        log.Fatalf("Erreur agrégation: %v", err)
+    }
+    
+    if !machineOutput() {
+        fmt.Fprintf(msgOut, "Agrégation: %s groupé par %s\n", recordType, groupBy)
+        fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    }
+    
+    if machineOutput() {
+        out := newOutput("value", "count", "numeric", "sum", "min", "max", "avg", "percentiles", "histogram")
+        for _, g := range groups {
+            out.Row(g.Value, g.Count, g.Numeric, g.Sum, g.Min, g.Max, g.Avg, g.Percentiles, g.Histogram)
+        }
+        flushOutput(out)
+        return
+    }
+    
+    if len(groups) == 0 {
+        fmt.Fprintf(msgOut, "Aucune entrée dans l'index %s.%s (voir -reindex)\n", recordType, groupBy)
+        return
+    }
+    
+    for _, g := range groups {
+        name := g.Value
+        if name == "" {
+            name = "(vide)"
+        }
+        fmt.Fprintf(msgOut, "%-24s", name)
+        for _, a := range aggs {
+            switch a {
+            case "count":
+                fmt.Fprintf(msgOut, "  count=%d", g.Count)
+            case "sum":
+                fmt.Fprintf(msgOut, "  sum=%.2f", g.Sum)
+            case "min":
+                fmt.Fprintf(msgOut, "  min=%.2f", g.Min)
+            case "max":
+                fmt.Fprintf(msgOut, "  max=%.2f", g.Max)
+            case "avg":
+                fmt.Fprintf(msgOut, "  avg=%.2f", g.Avg)
+            case "hist":
+            default:
+                p, _ := strconv.ParseFloat(a[1:], 64)
+                fmt.Fprintf(msgOut, "  %s=%.2f", a, g.Percentiles[tpleveldb.PercentileName(p)])
+            }
+        }
+        fmt.Fprintln(msgOut)
+        
+        for _, b := range g.Histogram {
+            fmt.Fprintf(msgOut, "    [%10.2f, %10.2f) %6d %s\n", b.Low, b.High, b.Count, strings.Repeat("█", histBar(b.Count, g.Numeric)))
+        }
+    }
+}
+
+// histBar retourne la longueur de barre (sur 30 caractères) d'une classe d'histogramme
+func histBar(count, total int) int {
+    if total == 0 {
+        return 0
+    }
+    return count * 30 / total
+}
+
+// doSQL exécute une requête -q et affiche le résultat en colonnes
+func doSQL(client *tpleveldb.Client, src string) {
+    indexer := tpleveldb.NewIndexer(client.GetDB())
+    if machineOutput() {
+        doSQLOutput(indexer, src)
+        return
+    }
+    
+    start := time.Now()
+    result, err := indexer.ExecSQL(src)
+    if err != nil {
+        log.Fatalf("Erreur requête: %v", err)
+    }
+    elapsed := time.Since(start)
+    
+    out := newOutput(result.Columns...)
+    for _, row := range result.Rows {
+        out.Row(row...)
+    }
+    
+    fmt.Fprintf(msgOut, "Requête: %s\n", src)
+    fmt.Fprintf(msgOut, "Plan:    %s\n", result.Plan)
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    flushOutput(out)
+    fmt.Fprintf(msgOut, "\n%d ligne(s) en %v\n", len(result.Rows), elapsed)
+}
+
+// doSQLOutput écrit les lignes d'une requête au fil du curseur (format machine)
+func doSQLOutput(indexer *tpleveldb.Indexer, src string) {
+    rows, err := indexer.OpenSQL(src)
+    if err != nil {
+        log.Fatalf("Erreur requête: %v", err)
+    }
+    defer rows.Close()
+    
+    columns := rows.Columns()
+    if metaColumns {
+        columns = append(columns[:len(columns):len(columns)], "hash", "timestamp", "node")
+    }
+    out := newOutput(columns...)
+    
+    for rows.Next() {
+        row := rows.Values()
+        if metaColumns {
+            if entry := rows.Entry(); entry != nil {
+                row = append(row, entry.Hash, entry.Timestamp, entry.Node)
+            } else {
+                row = append(row, nil, nil, nil)
+            }
+        }
+        if err := out.Row(row...); err != nil {
+            log.Fatalf("Erreur écriture sortie: %v", err)
+        }
+    }
+    if err := rows.Error(); err != nil {
+        log.Fatalf("Erreur requête: %v", err)
+    }
+    flushOutput(out)
+}
+
+// doModifiedSince liste les clés modifiées dans un intervalle grâce à l'index _mtime:
+func doModifiedSince(client *tpleveldb.Client, since, until string, limit int) {
+    from, err := time.Parse(time.RFC3339, since)
+    if err != nil {
+        log.Fatalf("Date -modified-since invalide: %v", err)
+    }
+    var to time.Time
+    if until != "" {
+        if to, err = time.Parse(time.RFC3339, until); err != nil {
+            log.Fatalf("Date -modified-until invalide: %v", err)
+        }
+    }
+    
+    fmt.Fprintf(msgOut, "Clés modifiées depuis %s", from.UTC().Format(time.RFC3339))
+    if !to.IsZero() {
+        fmt.Fprintf(msgOut, " jusqu'à %s", to.UTC().Format(time.RFC3339))
+    }
+    fmt.Fprintln(msgOut)
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    
+    // Sortie -format/-out en flux: -limit 0 écrit toutes les clés
+    if machineOutput() {
+        out := newOutput("key")
+        n := 0
+        err := client.EachModified(from, to, func(key string) bool {
+            if err := out.Row(key); err != nil {
+                log.Fatalf("Erreur écriture sortie: %v", err)
+            }
+            n++
+            return limit <= 0 || n < limit
+        })
+        if err != nil {
+            log.Fatalf("Erreur recherche: %v", err)
+        }
+        flushOutput(out)
+        return
+    }
+    
+    start := time.Now()
+    keys, err := client.ModifiedBetween(from, to)
+    if err != nil {
+        log.Fatalf("Erreur recherche: %v", err)
+    }
+    
+    fmt.Fprintf(msgOut, "Résultats: %d (en %v)\n\n", len(keys), time.Since(start))
+    for i, key := range keys {
+        if i >= limit {
+            fmt.Fprintf(msgOut, "  ... (%d autres)\n", len(keys)-limit)
+            break
+        }
+        fmt.Fprintf(msgOut, "  %s\n", key)
+    }
+    if len(keys) == 0 {
+        fmt.Fprintln(msgOut, "Aucune clé dans l'intervalle (données antérieures à l'index: voir -rebuild-mtime)")
+    }
+}
+
+// doRebuildMtime reconstruit l'index des dates de modification
+func doRebuildMtime(client *tpleveldb.Client) {
+    fmt.Fprintln(msgOut, "Reconstruction index: _mtime")
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    
+    start := time.Now()
+    created, err := client.RebuildModifiedIndex()
+    if err != nil {
+        log.Fatalf("Erreur reconstruction: %v", err)
+    }
+    elapsed := time.Since(start)
+    
+    if machineOutput() {
+        out := newOutput("index", "entries", "duration_ms")
+        out.Row("_mtime", created, elapsed.Milliseconds())
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "✓ %d entrées d'index créées en %v\n", created, elapsed)
+}
+
+// doPruneChanges supprime le début du journal des changements
+func doPruneChanges(client *tpleveldb.Client, before uint64) {
+    fmt.Fprintf(msgOut, "Purge du journal: changements < %d\n", before)
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    
+    pruned, err := client.PruneChanges(before)
+    if err != nil {
+        log.Fatalf("Erreur purge: %v", err)
+    }
+    
+    if machineOutput() {
+        out := newOutput("before", "pruned", "last_seq")
+        out.Row(before, pruned, client.LastSeq())
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "✓ %d changement(s) supprimé(s), dernier numéro: %d\n", pruned, client.LastSeq())
+}
+
+// doIndexStats affiche la cardinalité, les valeurs fréquentes et la taille d'un index
+func doIndexStats(client *tpleveldb.Client, spec string, top int) {
+    parts := strings.SplitN(spec, ":", 2)
+    if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+        log.Fatalf("Format -index-stats invalide: %q (attendu type:champ)", spec)
+    }
+    
+    indexer := tpleveldb.NewIndexer(client.GetDB())
+    
+    start := time.Now()
+    stats, err := indexer.Stats(parts[0], parts[1], top)
+    if err != nil {
+        log.Fatalf("Erreur statistiques: %v", err)
+    }
+    elapsed := time.Since(start)
+    
+    if machineOutput() {
+        out := newOutput("record_type", "field", "entries", "distinct", "avg_per_value", "approx_bytes", "maintained", "updated_at", "top")
+        out.Row(stats.RecordType, stats.Field, stats.Entries, stats.Distinct, stats.AvgPerValue, stats.ApproxBytes, stats.Maintained, stats.UpdatedAt, stats.Top)
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "Statistiques index: %s.%s\n", stats.RecordType, stats.Field)
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    fmt.Fprintf(msgOut, "Entrées:              %d\n", stats.Entries)
+    fmt.Fprintf(msgOut, "Valeurs distinctes:   %d\n", stats.Distinct)
+    fmt.Fprintf(msgOut, "Entrées par valeur:   %.2f\n", stats.AvgPerValue)
+    fmt.Fprintf(msgOut, "Taille approximative: %.2f KB\n", float64(stats.ApproxBytes)/1024)
+    if stats.Maintained {
+        fmt.Fprintf(msgOut, "Source:               compteurs (mis à jour %s)\n", stats.UpdatedAt)
+    } else {
+        fmt.Fprintln(msgOut, "Source:               parcours de l'index (-reindex pour tenir des compteurs)")
+    }
+    fmt.Fprintf(msgOut, "Calculé en:           %v\n", elapsed)
+    
+    if len(stats.Top) == 0 {
+        return
+    }
+    
+    fmt.Fprintln(msgOut)
+    fmt.Fprintln(msgOut, "Valeurs les plus fréquentes:")
+    for _, vc := range stats.Top {
+        pct := 0.0
+        if stats.Entries > 0 {
+            pct = float64(vc.Count) / float64(stats.Entries) * 100
+        }
+        fmt.Fprintf(msgOut, "  %-30s %8d  %5.1f%%\n", vc.Value, vc.Count, pct)
+    }
+}
+
+// doReindex reconstruit un index à partir des documents existants
+func doReindex(client *tpleveldb.Client, spec, expr string, project []string, unique bool, normalizer string) {
+    parts := strings.SplitN(spec, ":", 2)
+    if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+        log.Fatalf("Format -reindex invalide: %q (attendu type:champ)", spec)
+    }
+    recordType, field := parts[0], parts[1]
+    
+    fmt.Fprintf(msgOut, "Reconstruction index: %s.%s\n", recordType, field)
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    
+    indexer := tpleveldb.NewIndexer(client.GetDB())
+    
+    if normalizer != "" {
+        fmt.Fprintf(msgOut, "Normaliseur:     %s\n", normalizer)
+    }
+    
+    start := time.Now()
+    var created int
+    var err error
+    if expr != "" {
+        // Index calculé: type:nom -expr '...'
+        fmt.Fprintf(msgOut, "Expression:      %s\n", expr)
+        created, err = indexer.RebuildIndex(tpleveldb.IndexDef{
+            RecordType: recordType,
+            Name:       field,
+            Kind:       tpleveldb.IndexKindExpr,
+            Expr:       expr,
+            Project:    project,
+            Unique:     unique,
+            Normalizer: normalizer,
+        })
+    } else if strings.Contains(field, ",") {
+        // Index composite: type:champ1,champ2,...
+        created, err = indexer.RebuildIndex(tpleveldb.IndexDef{
+            RecordType: recordType,
+            Fields:     splitList(field),
+            Kind:       tpleveldb.IndexKindComposite,
+            Project:    project,
+            Unique:     unique,
+            Normalizer: normalizer,
+        })
+    } else if len(project) > 0 || unique || normalizer != "" {
+        if len(project) > 0 {
+            fmt.Fprintf(msgOut, "Champs projetés: %s\n", strings.Join(project, ", "))
+        }
+        if unique {
+            fmt.Fprintln(msgOut, "Contrainte:      unique")
+        }
+        created, err = indexer.RebuildIndex(tpleveldb.IndexDef{
+            RecordType: recordType,
+            Field:      field,
+            Project:    project,
+            Unique:     unique,
+            Normalizer: normalizer,
+        })
+    } else {
+        created, err = indexer.Rebuild(recordType, field)
+    }
+    if err != nil {
+        log.Fatalf("Erreur reconstruction: %v", err)
+    }
+    elapsed := time.Since(start)
+    
+    if machineOutput() {
+        out := newOutput("record_type", "index", "entries", "duration_ms")
+        out.Row(recordType, field, created, elapsed.Milliseconds())
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "✓ %d entrées d'index créées en %v\n", created, elapsed)
+}
+
+// doCheckIndexes vérifie la cohérence des index et les répare si demandé
+func doCheckIndexes(client *tpleveldb.Client, repair bool) {
+    fmt.Fprintln(msgOut, "Vérification des index secondaires")
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    
+    indexer := tpleveldb.NewIndexer(client.GetDB())
+    
+    report, err := indexer.Check()
+    if err != nil {
+        log.Fatalf("Erreur vérification: %v", err)
+    }
+    
+    // Sortie machine: une ligne par problème, "repaired" indique si -repair l'a corrigé
+    if machineOutput() {
+        if repair && !report.OK() {
+            if _, err := indexer.Repair(report); err != nil {
+                log.Fatalf("Erreur réparation: %v", err)
+            }
+        }
+        out := newOutput("problem", "index_key", "record_type", "field", "value", "primary_key", "reason", "repaired")
+        for _, p := range report.Orphans {
+            out.Row("orphan", p.IndexKey, p.RecordType, p.Field, p.Value, p.PrimaryKey, p.Reason, repair)
+        }
+        for _, p := range report.Missing {
+            out.Row("missing", p.IndexKey, p.RecordType, p.Field, p.Value, p.PrimaryKey, p.Reason, repair)
+        }
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "Entrées d'index vérifiées:  %d\n", report.CheckedEntries)
+    fmt.Fprintf(msgOut, "Documents vérifiés:         %d\n", report.CheckedDocuments)
+    fmt.Fprintf(msgOut, "Entrées orphelines:         %d\n", len(report.Orphans))
+    fmt.Fprintf(msgOut, "Entrées manquantes:         %d\n", len(report.Missing))
+    fmt.Fprintln(msgOut)
+    
+    for _, problem := range report.Orphans {
+        fmt.Fprintf(msgOut, "  ✗ orpheline  %s (%s)\n", problem.IndexKey, problem.Reason)
+    }
+    for _, problem := range report.Missing {
+        fmt.Fprintf(msgOut, "  ✗ manquante  %s → %s\n", problem.IndexKey, problem.PrimaryKey)
+    }
+    
+    if report.OK() {
+        fmt.Fprintln(msgOut, "✓ Index cohérents")
+        return
+    }
+    
+    if !repair {
+        fmt.Fprintln(msgOut)
+        fmt.Fprintln(msgOut, "Relancer avec -repair pour corriger automatiquement")
+        return
+    }
+    
+    fixed, err := indexer.Repair(report)
+    if err != nil {
+        log.Fatalf("Erreur réparation: %v", err)
+    }
+    
+    fmt.Fprintln(msgOut)
+    fmt.Fprintf(msgOut, "✓ %d corrections appliquées\n", fixed)
+}
+
+// doJoin affiche une jointure entre deux types, filtrée à gauche par -index/-value
+func doJoin(client *tpleveldb.Client, spec, strategy string, outer bool, field, value string, fields []string, limit int) {
+    sides := strings.SplitN(spec, "=", 2)
+    if len(sides) != 2 {
+        log.Fatalf("Format -join invalide: %q (attendu gauche.champ=droit[.champ])", spec)
+    }
+    
+    left := strings.SplitN(sides[0], ".", 2)
+    right := strings.SplitN(sides[1], ".", 2)
+    if len(left) != 2 || left[0] == "" || left[1] == "" || right[0] == "" {
+        log.Fatalf("Format -join invalide: %q (attendu gauche.champ=droit[.champ])", spec)
+    }
+    
+    join := tpleveldb.JoinSpec{
+        Left:      left[0],
+        LeftField: left[1],
+        Right:     right[0],
+        LeftOuter: outer,
+        Limit:     limit,
+    }
+    if len(right) == 2 {
+        join.RightField = right[1]
+    }
+    if strategy != "auto" {
+        join.Strategy = strategy
+    }
+    if field != "" && value != "" {
+        join.Where = tpleveldb.Eq(field, value)
+    }
+    
+    indexer := tpleveldb.NewIndexer(client.GetDB())
+    
+    start := time.Now()
+    result, err := indexer.Join(join)
+    if err != nil {
+        log.Fatalf("Erreur jointure: %v", err)
+    }
+    
+    if machineOutput() {
+        out := newOutput("left_key", "right_key", "left", "right")
+        for _, row := range result.Rows {
+            out.Row(row.LeftKey, row.RightKey, row.Left, row.Right)
+        }
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "Jointure: %s\n", spec)
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    fmt.Fprintf(msgOut, "Stratégie:  %s (%d lectures côté droit)\n", result.Strategy, result.Lookups)
+    fmt.Fprintf(msgOut, "Plan:       %s\n", result.Plan)
+    fmt.Fprintf(msgOut, "Lignes:     %d (en %v)\n\n", len(result.Rows), time.Since(start))
+    
+    for _, row := range result.Rows {
+        rightKey := row.RightKey
+        if rightKey == "" {
+            rightKey = "(aucun)"
+        }
+        fmt.Fprintf(msgOut, "  %s → %s\n", row.LeftKey, rightKey)
+        
+        // Champs affichés: "droit.champ" pour le document droit, sinon le document gauche
+        for _, f := range fields {
+            doc, name := row.Left, strings.TrimPrefix(f, join.Left+".")
+            if strings.HasPrefix(f, join.Right+".") {
+                doc, name = row.Right, strings.TrimPrefix(f, join.Right+".")
+            }
+            if v, ok := doc[name]; ok {
+                fmt.Fprintf(msgOut, "      %-20s %v\n", f+":", v)
+            }
+        }
+    }
+}
+
+// doRangeSearch recherche les entrées d'un index dont la valeur est dans [from, to]
+func doRangeSearch(client *tpleveldb.Client, spec, from, to string, limit int) {
+    parts := strings.SplitN(spec, ":", 2)
+    if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+        log.Fatalf("Format -range invalide: %q (attendu type:nom)", spec)
+    }
+    recordType, field := parts[0], parts[1]
+    
+    fmt.Fprintf(msgOut, "Recherche: %s.%s ∈ [%s, %s]\n", recordType, field, from, to)
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    
+    indexer := tpleveldb.NewIndexer(client.GetDB())
+    
+    // Sortie -format/-out en flux: -limit 0 écrit toutes les entrées
+    if machineOutput() {
+        out := newOutput("key", "value")
+        n := 0
+        err := indexer.EachInRange(recordType, field, from, to, func(hit tpleveldb.IndexHit) bool {
+            if err := out.Row(hit.Key, hit.Value); err != nil {
+                log.Fatalf("Erreur écriture sortie: %v", err)
+            }
+            n++
+            return limit <= 0 || n < limit
+        })
+        if err != nil {
+            log.Fatalf("Erreur recherche: %v", err)
+        }
+        flushOutput(out)
+        return
+    }
+    
+    start := time.Now()
+    hits, err := indexer.SearchRange(recordType, field, from, to)
+    if err != nil {
+        log.Fatalf("Erreur recherche: %v", err)
+    }
+    
+    fmt.Fprintf(msgOut, "Résultats: %d (en %v)\n\n", len(hits), time.Since(start))
+    
+    for i, hit := range hits {
+        if i >= limit {
+            fmt.Fprintf(msgOut, "  ... (%d autres)\n", len(hits)-limit)
+            break
+        }
+        fmt.Fprintf(msgOut, "  %-45s %s\n", hit.Key, hit.Value)
+    }
+}
+
+// doCompositeSearch recherche dans un index composite par valeurs des premiers
+// champs, avec une plage optionnelle sur le champ suivant
+func doCompositeSearch(client *tpleveldb.Client, spec string, values []string, from, to string, limit int) {
+    parts := strings.SplitN(spec, ":", 2)
+    if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+        log.Fatalf("Format -composite invalide: %q (attendu type:champ1,champ2)", spec)
+    }
+    recordType, fields := parts[0], splitList(parts[1])
+    
+    fmt.Fprintf(msgOut, "Recherche composite: %s.(%s) = (%s)", recordType, strings.Join(fields, ", "), strings.Join(values, ", "))
+    if (from != "" || to != "") && len(values) < len(fields) {
+        fmt.Fprintf(msgOut, ", %s ∈ [%s, %s]", fields[len(values)], from, to)
+    }
+    fmt.Fprintln(msgOut)
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    
+    indexer := tpleveldb.NewIndexer(client.GetDB())
+    
+    // Sortie -format/-out en flux: -limit 0 écrit toutes les clés
+    if machineOutput() {
+        out := newOutput("key")
+        n := 0
+        err := indexer.EachInCompositeRange(recordType, fields, values, from, to, func(key string) bool {
+            if err := out.Row(key); err != nil {
+                log.Fatalf("Erreur écriture sortie: %v", err)
+            }
+            n++
+            return limit <= 0 || n < limit
+        })
+        if err != nil {
+            log.Fatalf("Erreur recherche: %v", err)
+        }
+        flushOutput(out)
+        return
+    }
+    
+    start := time.Now()
+    results, err := indexer.SearchCompositeRange(recordType, fields, values, from, to)
+    if err != nil {
+        log.Fatalf("Erreur recherche: %v", err)
+    }
+    
+    fmt.Fprintf(msgOut, "Résultats: %d (en %v)\n\n", len(results), time.Since(start))
+    
+    for i, key := range results {
+        if i >= limit {
+            fmt.Fprintf(msgOut, "  ... (%d autres)\n", len(results)-limit)
+            break
+        }
+        fmt.Fprintf(msgOut, "  %d. %s\n", i+1, key)
+    }
+}
+
+// doLoadZips charge les centroïdes de codes postaux puis reconstruit les index géographiques
+func doLoadZips(client *tpleveldb.Client, csvPath string) {
+    indexer := tpleveldb.NewIndexer(client.GetDB())
+    
+    loaded, err := indexer.LoadZipCentroids(csvPath)
+    if err != nil {
+        log.Fatalf("Erreur chargement centroïdes: %v", err)
+    }
+    fmt.Fprintf(msgOut, "✓ %d préfixes de code postal chargés\n", loaded)
+    
+    // Les geohashs dépendent des centroïdes: les index existants sont recalculés
+    defs, err := indexer.ListIndexDefs("")
+    if err != nil {
+        log.Fatalf("Erreur lecture index: %v", err)
+    }
+    rebuilt := []string{}
+    for _, def := range defs {
+        if def.Kind != tpleveldb.IndexKindGeo {
+            continue
+        }
+        created, err := indexer.RebuildIndex(def)
+        if err != nil {
+            log.Fatalf("Erreur reconstruction %s:%s: %v", def.RecordType, def.IndexName(), err)
+        }
+        fmt.Fprintf(msgOut, "✓ Index %s:%s reconstruit (%d entrées)\n", def.RecordType, def.IndexName(), created)
+        rebuilt = append(rebuilt, def.RecordType+":"+def.IndexName())
+    }
+    
+    if machineOutput() {
+        out := newOutput("zip_prefixes", "rebuilt_indexes")
+        out.Row(loaded, rebuilt)
+        flushOutput(out)
+    }
+}
+
+// doGeoIndex crée l'index géographique "geo" d'un type à partir d'un champ code postal
+func doGeoIndex(client *tpleveldb.Client, spec string) {
+    parts := strings.SplitN(spec, ":", 2)
+    if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+        log.Fatalf("Format -geo-index invalide: %q (attendu type:champ)", spec)
+    }
+    
+    indexer := tpleveldb.NewIndexer(client.GetDB())
+    
+    start := time.Now()
+    created, err := indexer.RebuildIndex(tpleveldb.IndexDef{
+        RecordType: parts[0],
+        Name:       "geo",
+        Field:      parts[1],
+        Kind:       tpleveldb.IndexKindGeo,
+    })
+    if err != nil {
+        log.Fatalf("Erreur création index géographique: %v", err)
+    }
+    
+    if machineOutput() {
+        out := newOutput("record_type", "index", "field", "entries", "duration_ms")
+        out.Row(parts[0], "geo", parts[1], created, time.Since(start).Milliseconds())
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintf(msgOut, "✓ Index %s:geo créé sur %s: %d entrées en %v\n", parts[0], parts[1], created, time.Since(start))
+    if created == 0 {
+        fmt.Fprintln(msgOut, "  (aucun centroïde connu: charger d'abord -load-zips)")
+    }
+}
+
+// doGeoSearch recherche les documents proches d'un point ou dans un rectangle
+func doGeoSearch(client *tpleveldb.Client, spec, near string, radius float64, bbox string, limit int) {
+    parts := strings.SplitN(spec, ":", 2)
+    if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+        log.Fatalf("Format -geo invalide: %q (attendu type:nom)", spec)
+    }
+    recordType, name := parts[0], parts[1]
+    
+    indexer := tpleveldb.NewIndexer(client.GetDB())
+    
+    var hits []tpleveldb.GeoHit
+    var err error
+    if near != "" {
+        coords, perr := parseFloats(near, 2)
+        if perr != nil {
+            log.Fatalf("Format -near invalide: %v", perr)
+        }
+        fmt.Fprintf(msgOut, "Recherche: %s à moins de %.1f km de (%.4f, %.4f)\n", recordType, radius, coords[0], coords[1])
+        hits, err = indexer.SearchNear(recordType, name, coords[0], coords[1], radius)
+    } else {
+        coords, perr := parseFloats(bbox, 4)
+        if perr != nil {
+            log.Fatalf("Format -bbox invalide: %v", perr)
+        }
+        fmt.Fprintf(msgOut, "Recherche: %s dans [%.4f, %.4f] x [%.4f, %.4f]\n", recordType, coords[0], coords[2], coords[1], coords[3])
+        hits, err = indexer.SearchBox(recordType, name, coords[0], coords[1], coords[2], coords[3])
+    }
+    if err != nil {
+        log.Fatalf("Erreur recherche géographique: %v", err)
+    }
+    
+    // Les résultats sont triés par distance: ils sont lus en entier avant
+    // d'être écrits. -limit 0 les écrit tous.
+    if machineOutput() {
+        out := newOutput("key", "distance_km", "lat", "lon")
+        for i, hit := range hits {
+            if limit > 0 && i >= limit {
+                break
+            }
+            if err := out.Row(hit.Key, hit.DistanceKm, hit.Lat, hit.Lon); err != nil {
+                log.Fatalf("Erreur écriture sortie: %v", err)
+            }
+        }
+        flushOutput(out)
+        return
+    }
+    
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    fmt.Fprintf(msgOut, "Résultats: %d\n\n", len(hits))
+    
+    for i, hit := range hits {
+        if i >= limit {
+            fmt.Fprintf(msgOut, "  ... (%d autres)\n", len(hits)-limit)
+            break
+        }
+        fmt.Fprintf(msgOut, "  %-45s %8.2f km  (%.4f, %.4f)\n", hit.Key, hit.DistanceKm, hit.Lat, hit.Lon)
+    }
+}
+
+// parseFloats lit exactement n nombres séparés par des virgules
+func parseFloats(s string, n int) ([]float64, error) {
+    items := strings.Split(s, ",")
+    if len(items) != n {
+        return nil, fmt.Errorf("%d valeurs attendues dans %q", n, s)
+    }
+    
+    values := make([]float64, n)
+    for i, item := range items {
+        v, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+        if err != nil {
+            return nil, err
+        }
+        values[i] = v
+    }
+    return values, nil
+}
+
+// splitList découpe une liste séparée par des virgules
+func splitList(s string) []string {
+    var items []string
+    for _, item := range strings.Split(s, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            items = append(items, item)
+        }
+    }
+    return items
+}
+
+// machineOutput indique si la sortie est destinée à un script (-format autre que table)
+func machineOutput() bool {
+    return outputFormat != format.Table
+}
+
+// openOutFile crée le fichier de -out et y dirige les données. Le format vient
+// de -format s'il est donné, sinon de l'extension du fichier. Les données sont
+// écrites dans <fichier>.partial, renommé par closeOutFile: une erreur fatale
+// (log.Fatalf ne laisse pas les defer s'exécuter) ne laisse pas de fichier
+// tronqué sous le nom attendu.
+func openOutFile(path, formatName string) *os.File {
+    explicit := false
+    flag.Visit(func(f *flag.Flag) {
+        explicit = explicit || f.Name == "format"
+    })
+    
+    if !explicit {
+        switch strings.ToLower(filepath.Ext(path)) {
+        case ".ndjson", ".jsonl":
+            outputFormat = format.NDJSON
+        case ".csv":
+            outputFormat = format.CSV
+        case ".json":
+            outputFormat = format.JSON
+        default:
+            log.Fatalf("Option -out: extension inconnue pour %s (.ndjson, .csv ou .json, ou préciser -format)", path)
+        }
+    }
+    if outputFormat == format.Table {
+        log.Fatalf("Option -out: format table non disponible pour un fichier (json, ndjson ou csv)")
+    }
+    
+    file, err := os.Create(path + ".partial")
+    if err != nil {
+        log.Fatalf("Option -out: %v", err)
+    }
+    dataOut = file
+    return file
+}
+
+// closeOutFile ferme le fichier de -out, lui donne son nom et indique sa taille
+func closeOutFile(file *os.File, path string) {
+    info, statErr := file.Stat()
+    if err := file.Close(); err != nil {
+        os.Remove(file.Name())
+        log.Fatalf("Erreur écriture %s: %v", path, err)
+    }
+    if err := os.Rename(file.Name(), path); err != nil {
+        log.Fatalf("Erreur écriture %s: %v", path, err)
+    }
+    if statErr == nil {
+        fmt.Fprintf(msgOut, "✓ Résultats écrits dans %s (%s, %.1f Ko)\n", path, outputFormat, kb(info.Size()))
+    }
+}
+
+// newOutput crée un écrivain d'enregistrements sur la sortie des données
+func newOutput(columns ...string) *format.Writer {
+    return format.NewWriter(dataOut, outputFormat, columns...)
+}
+
+// flushOutput termine la sortie; une erreur d'écriture est fatale
+func flushOutput(out *format.Writer) {
+    if err := out.Flush(); err != nil {
+        log.Fatalf("Erreur écriture sortie: %v", err)
+    }
+}
+
+// getDiskSize calcule la taille d'un dossier
+func getDiskSize(path string) float64 {
+    var size int64
+    
+    err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+        if err != nil {
+            return err
+        }
+        if !info.IsDir() {
+            size += info.Size()
+        }
+        return nil
+    })
+    
+    if err != nil {
+        return 0
+    }
+    
+    return float64(size) / 1024 / 1024 // MB
 }
\ No newline at end of file
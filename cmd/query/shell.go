@@ -0,0 +1,532 @@
+// cmd/query/shell.go
+// Shell interactif: session persistante sur un nœud, historique et complétion
+
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    
+    "github.com/syndtr/goleveldb/leveldb/util"
+    tpleveldb "leveldb-tp/pkg/leveldb"
+)
+
+// Nombre maximal de propositions de complétion affichées
+const maxCompletions = 40
+
+// lineReader lit une ligne de commande (éditeur avec complétion sur un terminal)
+type lineReader interface {
+    ReadLine(prompt string) (string, error)
+    Close() error
+}
+
+// shellCommand est une commande du shell
+type shellCommand struct {
+    usage string
+    help  string
+    run   func(s *shellSession, args []string, rest string) error
+}
+
+// shellSession est l'état d'une session: nœud ouvert et historique
+type shellSession struct {
+    client  *tpleveldb.Client
+    node    string
+    history []string
+}
+
+var shellCommands map[string]shellCommand
+
+func init() {
+    shellCommands = map[string]shellCommand{
+        "help":    {"help", "Afficher l'aide", (*shellSession).cmdHelp},
+        "use":     {"use <nœud>", "Changer de nœud (ex: use node2)", (*shellSession).cmdUse},
+        "get":     {"get <clé>", "Afficher un document", (*shellSession).cmdGet},
+        "put":     {"put <clé> <json>", "Écrire un document", (*shellSession).cmdPut},
+        "delete":  {"delete <clé>", "Supprimer un document", (*shellSession).cmdDelete},
+        "scan":    {"scan <préfixe> [n]", "Lister les clés d'un préfixe", (*shellSession).cmdScan},
+        "search":  {"search <type> <champ> <valeur>", "Rechercher par index secondaire", (*shellSession).cmdSearch},
+        "q":       {"q <requête>", "Requête SELECT ... FROM ... (voir -q)", (*shellSession).cmdQuery},
+        "explain": {"explain <requête>", "Plan mesuré d'une requête (voir -explain)", (*shellSession).cmdExplain},
+        "stats":   {"stats", "Statistiques du nœud", (*shellSession).cmdStats},
+        "verify":  {"verify <clé>", "Vérifier l'intégrité d'un document", (*shellSession).cmdVerify},
+        "history": {"history", "Afficher l'historique des commandes", (*shellSession).cmdHistory},
+        "exit":    {"exit", "Quitter le shell", nil},
+    }
+}
+
+// runShell ouvre une session interactive et retourne le client du dernier nœud
+// utilisé (fermé par l'appelant)
+func runShell(client *tpleveldb.Client, node string) *tpleveldb.Client {
+    s := &shellSession{client: client, node: node}
+    s.loadHistory()
+    
+    reader := newLineReader(s.complete, &s.history)
+    defer reader.Close()
+    
+    fmt.Fprintf(msgOut, "Shell LevelDB - nœud %s (help pour l'aide, Tab pour compléter)\n", node)
+    
+    for {
+        line, err := reader.ReadLine(fmt.Sprintf("%s> ", s.node))
+        if err == io.EOF {
+            fmt.Fprintln(msgOut)
+            break
+        }
+        if err != nil {
+            fmt.Fprintf(msgOut, "Erreur lecture: %v\n", err)
+            break
+        }
+        
+        line = strings.TrimSpace(line)
+        if line == "" {
+            continue
+        }
+        s.addHistory(line)
+        
+        name, rest := splitWord(line)
+        if name == "exit" || name == "quit" {
+            break
+        }
+        
+        cmd, ok := shellCommands[strings.ToLower(name)]
+        if !ok {
+            // Une requête peut être saisie directement
+            if strings.EqualFold(name, "select") {
+                cmd, rest = shellCommands["q"], line
+            } else {
+                fmt.Fprintf(msgOut, "Commande inconnue: %s (help pour l'aide)\n", name)
+                continue
+            }
+        }
+        
+        if err := cmd.run(s, strings.Fields(rest), rest); err != nil {
+            fmt.Fprintf(msgOut, "Erreur: %v\n", err)
+        }
+    }
+    
+    s.saveHistory()
+    return s.client
+}
+
+// splitWord sépare le premier mot du reste de la ligne
+func splitWord(line string) (string, string) {
+    if i := strings.IndexAny(line, " \t"); i >= 0 {
+        return line[:i], strings.TrimSpace(line[i+1:])
+    }
+    return line, ""
+}
+
+func (s *shellSession) cmdHelp(args []string, rest string) error {
+    names := make([]string, 0, len(shellCommands))
+    for name := range shellCommands {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    
+    for _, name := range names {
+        fmt.Fprintf(msgOut, "  %-32s %s\n", shellCommands[name].usage, shellCommands[name].help)
+    }
+    return nil
+}
+
+func (s *shellSession) cmdUse(args []string, rest string) error {
+    if len(args) != 1 {
+        return fmt.Errorf("usage: use <nœud>")
+    }
+    
+    // OpenFile créerait un nœud vide pour un nom mal saisi
+    nodePath := filepath.Join("leveldb-stores", args[0])
+    if info, err := os.Stat(nodePath); err != nil || !info.IsDir() || strings.ContainsAny(args[0], `/\`) {
+        return fmt.Errorf("nœud inconnu: %s", args[0])
+    }
+    
+    client, err := tpleveldb.NewClient(nodePath)
+    if err != nil {
+        return fmt.Errorf("ouverture nœud %s: %v", args[0], err)
+    }
+    s.client.Close()
+    s.client, s.node = client, args[0]
+    fmt.Fprintf(msgOut, "✓ Nœud %s\n", s.node)
+    return nil
+}
+
+func (s *shellSession) cmdGet(args []string, rest string) error {
+    if len(args) != 1 {
+        return fmt.Errorf("usage: get <clé>")
+    }
+    
+    entry, err := s.client.Get(args[0])
+    if err != nil {
+        return err
+    }
+    
+    var data interface{}
+    if err := json.Unmarshal(entry.Data, &data); err == nil {
+        formatted, _ := json.MarshalIndent(data, "", "  ")
+        fmt.Fprintln(msgOut, string(formatted))
+    } else {
+        fmt.Fprintln(msgOut, string(entry.Data))
+    }
+    fmt.Fprintf(msgOut, "Hash: %s  Timestamp: %s\n", entry.Hash, entry.Timestamp)
+    return nil
+}
+
+func (s *shellSession) cmdPut(args []string, rest string) error {
+    key, doc := splitWord(rest)
+    if key == "" || doc == "" {
+        return fmt.Errorf("usage: put <clé> <json>")
+    }
+    
+    var data map[string]interface{}
+    if err := json.Unmarshal([]byte(doc), &data); err != nil {
+        return fmt.Errorf("JSON invalide: %v", err)
+    }
+    if err := s.client.Put(key, data); err != nil {
+        return err
+    }
+    fmt.Fprintf(msgOut, "✓ %s écrit\n", key)
+    return nil
+}
+
+func (s *shellSession) cmdDelete(args []string, rest string) error {
+    if len(args) != 1 {
+        return fmt.Errorf("usage: delete <clé>")
+    }
+    if _, err := s.client.Get(args[0]); err != nil {
+        return err
+    }
+    if err := s.client.Delete(args[0]); err != nil {
+        return err
+    }
+    fmt.Fprintf(msgOut, "✓ %s supprimé\n", args[0])
+    return nil
+}
+
+func (s *shellSession) cmdScan(args []string, rest string) error {
+    if len(args) < 1 || len(args) > 2 {
+        return fmt.Errorf("usage: scan <préfixe> [n]")
+    }
+    limit := 20
+    if len(args) == 2 {
+        n, err := strconv.Atoi(args[1])
+        if err != nil || n <= 0 {
+            return fmt.Errorf("nombre invalide: %s", args[1])
+        }
+        limit = n
+    }
+    
+    iter := s.client.GetDB().NewIterator(util.BytesPrefix([]byte(args[0])), nil)
+    defer iter.Release()
+    
+    count := 0
+    for iter.Next() {
+        if count < limit {
+            fmt.Fprintf(msgOut, "  %s\n", iter.Key())
+        }
+        count++
+    }
+    if err := iter.Error(); err != nil {
+        return err
+    }
+    
+    if count > limit {
+        fmt.Fprintf(msgOut, "  ... (%d autres)\n", count-limit)
+    }
+    fmt.Fprintf(msgOut, "%d clé(s)\n", count)
+    return nil
+}
+
+func (s *shellSession) cmdSearch(args []string, rest string) error {
+    if len(args) < 3 {
+        return fmt.Errorf("usage: search <type> <champ> <valeur>")
+    }
+    recordType, field := args[0], args[1]
+    
+    // La valeur est le reste de la ligne et peut contenir des espaces (sao paulo)
+    _, rest = splitWord(rest)
+    _, value := splitWord(rest)
+    
+    keys, err := tpleveldb.NewIndexer(s.client.GetDB()).SearchByIndex(recordType, field, value)
+    if err != nil {
+        return err
+    }
+    for i, key := range keys {
+        if i >= 20 {
+            fmt.Fprintf(msgOut, "  ... (%d autres)\n", len(keys)-20)
+            break
+        }
+        fmt.Fprintf(msgOut, "  %s\n", key)
+    }
+    fmt.Fprintf(msgOut, "%d résultat(s)\n", len(keys))
+    return nil
+}
+
+func (s *shellSession) cmdQuery(args []string, rest string) error {
+    if rest == "" {
+        return fmt.Errorf("usage: q SELECT ... FROM type [WHERE ...]")
+    }
+    if _, err := tpleveldb.ParseSQL(rest); err != nil {
+        return err
+    }
+    doSQL(s.client, rest)
+    return nil
+}
+
+func (s *shellSession) cmdExplain(args []string, rest string) error {
+    if rest == "" {
+        return fmt.Errorf("usage: explain SELECT ... FROM type [WHERE ...]")
+    }
+    if _, err := tpleveldb.ParseSQL(rest); err != nil {
+        return err
+    }
+    doExplainSQL(s.client, rest)
+    return nil
+}
+
+func (s *shellSession) cmdStats(args []string, rest string) error {
+    doStats(s.client, s.node)
+    return nil
+}
+
+func (s *shellSession) cmdVerify(args []string, rest string) error {
+    if len(args) != 1 {
+        return fmt.Errorf("usage: verify <clé>")
+    }
+    valid, err := s.client.VerifyIntegrity(args[0])
+    if err != nil {
+        return err
+    }
+    if valid {
+        fmt.Fprintln(msgOut, "✓ Intégrité vérifiée - Hash valide")
+    }
+    return nil
+}
+
+func (s *shellSession) cmdHistory(args []string, rest string) error {
+    for i, line := range s.history {
+        fmt.Fprintf(msgOut, "%5d  %s\n", i+1, line)
+    }
+    return nil
+}
+
+// Historique conservé entre les sessions
+const maxHistory = 500
+
+func historyPath() string {
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return ""
+    }
+    return filepath.Join(home, ".leveldb_query_history")
+}
+
+func (s *shellSession) addHistory(line string) {
+    if n := len(s.history); n > 0 && s.history[n-1] == line {
+        return
+    }
+    s.history = append(s.history, line)
+    if len(s.history) > maxHistory {
+        s.history = s.history[len(s.history)-maxHistory:]
+    }
+}
+
+func (s *shellSession) loadHistory() {
+    path := historyPath()
+    if path == "" {
+        return
+    }
+    file, err := os.Open(path)
+    if err != nil {
+        return
+    }
+    defer file.Close()
+    
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        if line := strings.TrimSpace(scanner.Text()); line != "" {
+            s.addHistory(line)
+        }
+    }
+}
+
+func (s *shellSession) saveHistory() {
+    path := historyPath()
+    if path == "" {
+        return
+    }
+    if err := os.WriteFile(path, []byte(strings.Join(s.history, "\n")+"\n"), 0600); err != nil {
+        fmt.Fprintf(msgOut, "Historique non enregistré: %v\n", err)
+    }
+}
+
+// complete retourne les complétions du dernier mot de line: commande, clé
+// (get/put/delete/scan/verify), type et champ indexé (search) ou nœud (use)
+func (s *shellSession) complete(line string) []string {
+    words := strings.Fields(line)
+    if len(words) == 0 || !strings.HasSuffix(line, " ") && len(words) == 1 {
+        prefix := ""
+        if len(words) == 1 {
+            prefix = words[0]
+        }
+        var names []string
+        for name := range shellCommands {
+            if strings.HasPrefix(name, prefix) {
+                names = append(names, name+" ")
+            }
+        }
+        sort.Strings(names)
+        return names
+    }
+    
+    // Position de l'argument en cours de saisie (1 = premier argument)
+    current := ""
+    argIndex := len(words)
+    if !strings.HasSuffix(line, " ") {
+        current = words[len(words)-1]
+        argIndex--
+    }
+    
+    switch cmd := strings.ToLower(words[0]); {
+    case argIndex == 1 && (cmd == "get" || cmd == "put" || cmd == "delete" || cmd == "scan" || cmd == "verify"):
+        return s.completeKeys(current, "")
+    case argIndex == 1 && cmd == "search":
+        return s.completeKeys(current, ":")
+    case argIndex == 2 && cmd == "search":
+        return s.completeFields(words[1], current)
+    case argIndex == 1 && cmd == "use":
+        return completeNodes(current)
+    }
+    return nil
+}
+
+// completeKeys propose les clés commençant par prefix, tronquées au ':' suivant
+// pour compléter segment par segment (product: puis product:abc...). Avec suffix
+// ":", seuls les types (premier segment) sont proposés, sans le ':'.
+func (s *shellSession) completeKeys(prefix, suffix string) []string {
+    iter := s.client.GetDB().NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    var candidates []string
+    for ok := iter.Next(); ok && len(candidates) < maxCompletions; {
+        key := string(iter.Key())
+        if suffix != "" && (strings.HasPrefix(key, "_") || strings.HasPrefix(key, "idx:")) {
+            ok = iter.Seek([]byte(nextPrefix(key[:strings.IndexByte(key+":", ':')])))
+            continue
+        }
+        
+        if i := strings.IndexByte(key[len(prefix):], ':'); i >= 0 {
+            segment := key[:len(prefix)+i+1]
+            if suffix != "" {
+                candidates = append(candidates, segment[:len(segment)-1]+" ")
+            } else {
+                candidates = append(candidates, segment)
+            }
+            // Sauter toutes les clés de ce segment
+            ok = iter.Seek([]byte(nextPrefix(segment)))
+            continue
+        }
+        
+        if suffix == "" {
+            candidates = append(candidates, key+" ")
+        }
+        ok = iter.Next()
+    }
+    return candidates
+}
+
+// completeFields propose les champs indexés d'un type (définitions et index présents)
+func (s *shellSession) completeFields(recordType, prefix string) []string {
+    seen := make(map[string]bool)
+    var fields []string
+    
+    defs, _ := tpleveldb.NewIndexer(s.client.GetDB()).ListIndexDefs(recordType)
+    for _, def := range defs {
+        if name := def.IndexName(); strings.HasPrefix(name, prefix) && !seen[name] {
+            seen[name] = true
+            fields = append(fields, name+" ")
+        }
+    }
+    
+    base := "idx:" + recordType + ":"
+    iter := s.client.GetDB().NewIterator(util.BytesPrefix([]byte(base+prefix)), nil)
+    defer iter.Release()
+    
+    for ok := iter.Next(); ok && len(fields) < maxCompletions; {
+        rest := string(iter.Key())[len(base):]
+        i := strings.IndexByte(rest, ':')
+        if i < 0 {
+            ok = iter.Next()
+            continue
+        }
+        if name := rest[:i]; !seen[name] {
+            seen[name] = true
+            fields = append(fields, name+" ")
+        }
+        ok = iter.Seek([]byte(nextPrefix(base + rest[:i+1])))
+    }
+    
+    sort.Strings(fields)
+    return fields
+}
+
+// completeNodes propose les nœuds présents dans leveldb-stores
+func completeNodes(prefix string) []string {
+    all, err := listNodes()
+    if err != nil {
+        return nil
+    }
+    var nodes []string
+    for _, name := range all {
+        if strings.HasPrefix(name, prefix) {
+            nodes = append(nodes, name+" ")
+        }
+    }
+    return nodes
+}
+
+// nextPrefix retourne la plus petite chaîne supérieure à toutes celles commençant par p
+func nextPrefix(p string) string {
+    return string(util.BytesPrefix([]byte(p)).Limit)
+}
+
+// commonPrefix retourne le plus long préfixe commun des propositions
+func commonPrefix(candidates []string) string {
+    if len(candidates) == 0 {
+        return ""
+    }
+    prefix := candidates[0]
+    for _, c := range candidates[1:] {
+        for !strings.HasPrefix(c, prefix) {
+            prefix = prefix[:len(prefix)-1]
+        }
+    }
+    return prefix
+}
+
+// scanLineReader lit les lignes sans édition (entrée redirigée ou terminal non géré)
+type scanLineReader struct {
+    scanner *bufio.Scanner
+}
+
+func newScanLineReader() *scanLineReader {
+    return &scanLineReader{scanner: bufio.NewScanner(os.Stdin)}
+}
+
+func (r *scanLineReader) ReadLine(prompt string) (string, error) {
+    fmt.Fprint(msgOut, prompt)
+    if !r.scanner.Scan() {
+        if err := r.scanner.Err(); err != nil {
+            return "", err
+        }
+        return "", io.EOF
+    }
+    return r.scanner.Text(), nil
+}
+
+func (r *scanLineReader) Close() error { return nil }
\ No newline at end of file
@@ -0,0 +1,212 @@
+//go:build linux
+
+// cmd/query/shell_linux.go
+// Éditeur de ligne du shell en mode brut: historique (↑/↓), déplacement et complétion (Tab)
+
+package main
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "syscall"
+    "unicode/utf8"
+    "unsafe"
+)
+
+// termLineReader édite la ligne en mode brut sur un terminal
+type termLineReader struct {
+    in       *bufio.Reader
+    saved    syscall.Termios
+    complete func(line string) []string
+    history  *[]string
+}
+
+// newLineReader retourne l'éditeur de ligne si l'entrée standard est un
+// terminal, sinon une lecture ligne à ligne
+func newLineReader(complete func(line string) []string, history *[]string) lineReader {
+    r := &termLineReader{in: bufio.NewReader(os.Stdin), complete: complete, history: history}
+    if err := ioctlTermios(syscall.TCGETS, &r.saved); err != nil {
+        return newScanLineReader()
+    }
+    return r
+}
+
+func ioctlTermios(req uintptr, t *syscall.Termios) error {
+    _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdin.Fd(), req, uintptr(unsafe.Pointer(t)))
+    if errno != 0 {
+        return errno
+    }
+    return nil
+}
+
+// rawMode désactive l'écho et la lecture par ligne; les signaux (Ctrl-C) sont
+// traités par l'éditeur
+func (r *termLineReader) rawMode() error {
+    raw := r.saved
+    raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+    raw.Iflag &^= syscall.ICRNL | syscall.IXON
+    raw.Cc[syscall.VMIN] = 1
+    raw.Cc[syscall.VTIME] = 0
+    return ioctlTermios(syscall.TCSETS, &raw)
+}
+
+func (r *termLineReader) restore() {
+    ioctlTermios(syscall.TCSETS, &r.saved)
+}
+
+func (r *termLineReader) Close() error {
+    r.restore()
+    return nil
+}
+
+// ReadLine lit une ligne. Le terminal n'est en mode brut que pendant la saisie,
+// pour que l'affichage des commandes reste normal.
+func (r *termLineReader) ReadLine(prompt string) (string, error) {
+    if err := r.rawMode(); err != nil {
+        return "", err
+    }
+    defer r.restore()
+    
+    var buf []rune
+    pos := 0
+    histPos := len(*r.history)
+    draft := ""
+    
+    redraw := func() {
+        fmt.Fprintf(msgOut, "\r\x1b[K%s%s", prompt, string(buf))
+        if back := len(buf) - pos; back > 0 {
+            fmt.Fprintf(msgOut, "\x1b[%dD", back)
+        }
+    }
+    setLine := func(s string) {
+        buf = []rune(s)
+        pos = len(buf)
+        redraw()
+    }
+    
+    fmt.Fprint(msgOut, prompt)
+    for {
+        c, _, err := r.in.ReadRune()
+        if err != nil {
+            return "", err
+        }
+        
+        switch c {
+        case '\r', '\n':
+            fmt.Fprint(msgOut, "\n")
+            return string(buf), nil
+        
+        case 3: // Ctrl-C: abandon de la ligne
+            fmt.Fprint(msgOut, "^C\n")
+            buf, pos = nil, 0
+            histPos = len(*r.history)
+            fmt.Fprint(msgOut, prompt)
+        
+        case 4: // Ctrl-D: fin de session sur une ligne vide
+            if len(buf) == 0 {
+                return "", io.EOF
+            }
+        
+        case 127, 8: // Retour arrière
+            if pos > 0 {
+                buf = append(buf[:pos-1], buf[pos:]...)
+                pos--
+                redraw()
+            }
+        
+        case 1: // Ctrl-A
+            pos = 0
+            redraw()
+        
+        case 5: // Ctrl-E
+            pos = len(buf)
+            redraw()
+        
+        case 21: // Ctrl-U: effacer jusqu'au début
+            buf = buf[pos:]
+            pos = 0
+            redraw()
+        
+        case '\t':
+            r.completeLine(prompt, &buf, &pos)
+            redraw()
+        
+        case 27: // Séquences d'échappement: flèches
+            if b, _ := r.in.ReadByte(); b != '[' {
+                continue
+            }
+            switch b, _ := r.in.ReadByte(); b {
+            case 'A': // ↑
+                if histPos > 0 {
+                    if histPos == len(*r.history) {
+                        draft = string(buf)
+                    }
+                    histPos--
+                    setLine((*r.history)[histPos])
+                }
+            case 'B': // ↓
+                if histPos < len(*r.history) {
+                    histPos++
+                    if histPos == len(*r.history) {
+                        setLine(draft)
+                    } else {
+                        setLine((*r.history)[histPos])
+                    }
+                }
+            case 'C': // →
+                if pos < len(buf) {
+                    pos++
+                    redraw()
+                }
+            case 'D': // ←
+                if pos > 0 {
+                    pos--
+                    redraw()
+                }
+            }
+        
+        default:
+            if c >= ' ' && c != utf8.RuneError {
+                buf = append(buf[:pos], append([]rune{c}, buf[pos:]...)...)
+                pos++
+                redraw()
+            }
+        }
+    }
+}
+
+// completeLine complète le mot sous le curseur: une seule proposition est
+// insérée, plusieurs sont complétées jusqu'à leur préfixe commun puis listées
+func (r *termLineReader) completeLine(prompt string, buf *[]rune, pos *int) {
+    before := string((*buf)[:*pos])
+    candidates := r.complete(before)
+    if len(candidates) == 0 {
+        return
+    }
+    
+    word := before
+    if i := strings.LastIndexAny(before, " \t"); i >= 0 {
+        word = before[i+1:]
+    }
+    
+    completion := commonPrefix(candidates)
+    if len(completion) > len(word) && strings.HasPrefix(completion, word) {
+        insert := []rune(completion[len(word):])
+        *buf = append((*buf)[:*pos], append(insert, (*buf)[*pos:]...)...)
+        *pos += len(insert)
+        return
+    }
+    
+    if len(candidates) > 1 {
+        fmt.Fprint(msgOut, "\n")
+        for _, c := range candidates {
+            fmt.Fprintf(msgOut, "%s\n", strings.TrimSpace(c))
+        }
+        if len(candidates) >= maxCompletions {
+            fmt.Fprint(msgOut, "...\n")
+        }
+    }
+}
\ No newline at end of file
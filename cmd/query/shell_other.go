@@ -0,0 +1,10 @@
+//go:build !linux
+
+// cmd/query/shell_other.go
+// Lecture des commandes du shell hors Linux: ligne à ligne, sans complétion
+
+package main
+
+func newLineReader(complete func(line string) []string, history *[]string) lineReader {
+    return newScanLineReader()
+}
\ No newline at end of file
@@ -0,0 +1,237 @@
+// cmd/query/write.go
+// Écritures par le Client (hash, index, journal): -put, -patch et -delete, avec confirmation et -dry-run
+
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "strings"
+    
+    tpleveldb "leveldb-tp/pkg/leveldb"
+)
+
+// writeOptions regroupe les options communes aux écritures
+type writeOptions struct {
+    dryRun bool // afficher l'effet sans écrire
+    yes    bool // ne pas demander de confirmation
+}
+
+// readJSONArg lit un argument JSON: texte littéral, @fichier ou @- (entrée standard)
+func readJSONArg(name, arg string) []byte {
+    if arg == "" {
+        log.Fatalf("Option %s requise", name)
+    }
+    
+    data := []byte(arg)
+    if strings.HasPrefix(arg, "@") {
+        var err error
+        if arg == "@-" {
+            data, err = io.ReadAll(os.Stdin)
+        } else {
+            data, err = os.ReadFile(arg[1:])
+        }
+        if err != nil {
+            log.Fatalf("Option %s: erreur lecture %s: %v", name, arg[1:], err)
+        }
+    }
+    if !json.Valid(data) {
+        log.Fatalf("Option %s: JSON invalide", name)
+    }
+    return data
+}
+
+// checkStdin refuse @- sans -yes ni -dry-run: l'entrée standard, déjà lue,
+// ne peut plus servir à la confirmation
+func checkStdin(name, arg string, opts writeOptions) {
+    if arg == "@-" && !opts.yes && !opts.dryRun {
+        log.Fatalf("Option %s @-: ajouter -yes (l'entrée standard ne peut pas servir à la confirmation)", name)
+    }
+}
+
+// decodeObject décode un document: les index ne s'appliquent qu'aux objets
+func decodeObject(name string, data []byte) map[string]interface{} {
+    var doc map[string]interface{}
+    if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+        log.Fatalf("%s: le document doit être un objet JSON", name)
+    }
+    return doc
+}
+
+// doPut écrit (ou remplace) un document
+func doPut(client *tpleveldb.Client, key, dataArg string, opts writeOptions) {
+    checkStdin("-data", dataArg, opts)
+    doc := decodeObject("Option -data", readJSONArg("-data", dataArg))
+    
+    preview, err := client.PreviewPut(key, doc)
+    if err != nil {
+        log.Fatalf("Écriture refusée: %v", err)
+    }
+    if !confirmWrite(preview, opts) {
+        return
+    }
+    
+    if err := client.Put(key, doc); err != nil {
+        log.Fatalf("Erreur écriture %s: %v", key, err)
+    }
+    reportWrite(preview, true)
+}
+
+// doPatch fusionne un patch JSON (null supprime un champ) dans un document existant
+func doPatch(client *tpleveldb.Client, key, mergeArg string, opts writeOptions) {
+    checkStdin("-merge", mergeArg, opts)
+    patch := readJSONArg("-merge", mergeArg)
+    
+    entry, err := client.Get(key)
+    if err != nil {
+        log.Fatalf("Document non trouvé: %v", err)
+    }
+    merged, err := tpleveldb.MergePatch(entry.Data, patch)
+    if err != nil {
+        log.Fatalf("Erreur fusion: %v", err)
+    }
+    doc := decodeObject("Option -merge", merged)
+    
+    preview, err := client.PreviewPut(key, doc)
+    if err != nil {
+        log.Fatalf("Écriture refusée: %v", err)
+    }
+    if preview.Hash == entry.Hash {
+        fmt.Fprintf(msgOut, "✓ %s inchangé (le patch n'apporte aucune modification)\n", key)
+        reportWrite(preview, false)
+        return
+    }
+    if !confirmWrite(preview, opts) {
+        return
+    }
+    
+    if err := client.Put(key, doc); err != nil {
+        log.Fatalf("Erreur écriture %s: %v", key, err)
+    }
+    reportWrite(preview, true)
+}
+
+// doDelete supprime un document (tombstone dans le journal, index retirés)
+func doDelete(client *tpleveldb.Client, key string, opts writeOptions) {
+    preview, err := client.PreviewDelete(key)
+    if err != nil {
+        log.Fatalf("Erreur préparation: %v", err)
+    }
+    if preview.Old == nil {
+        log.Fatalf("Document non trouvé: %s", key)
+    }
+    if !confirmWrite(preview, opts) {
+        return
+    }
+    
+    if err := client.Delete(key); err != nil {
+        log.Fatalf("Erreur suppression %s: %v", key, err)
+    }
+    reportWrite(preview, true)
+}
+
+// confirmWrite affiche l'effet de l'écriture puis demande confirmation.
+// Retourne faux avec -dry-run ou si l'opérateur refuse. Une écriture qui
+// laisserait périmé un index non déclaré est refusée.
+func confirmWrite(preview *tpleveldb.WritePreview, opts writeOptions) bool {
+    printPreview(preview)
+    
+    if opts.dryRun {
+        fmt.Fprintln(msgOut, "\nSimulation (-dry-run): rien n'a été écrit")
+        reportWrite(preview, false)
+        return false
+    }
+    if len(preview.Unmaintained) > 0 {
+        recordType := strings.SplitN(preview.Key, ":", 2)[0]
+        log.Fatalf("Écriture refusée: index non déclarés sur %s (%s), ils deviendraient périmés; les déclarer avec -reindex %s:<nom>",
+            recordType, strings.Join(preview.Unmaintained, ", "), recordType)
+    }
+    if opts.yes {
+        return true
+    }
+    
+    // L'invite va sur msgOut, la sortie d'erreur en format machine
+    fmt.Fprint(msgOut, "\nAppliquer ? [o/N] ")
+    answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+    switch strings.ToLower(strings.TrimSpace(answer)) {
+    case "o", "oui", "y", "yes":
+        return true
+    }
+    fmt.Fprintln(msgOut, "Annulé: rien n'a été écrit")
+    reportWrite(preview, false)
+    return false
+}
+
+// printPreview affiche les champs modifiés et les entrées d'index touchées
+func printPreview(preview *tpleveldb.WritePreview) {
+    verb := map[string]string{"put": "Écriture", "delete": "Suppression"}[preview.Op]
+    if preview.Op == "put" && preview.Old == nil {
+        verb = "Création"
+    }
+    fmt.Fprintf(msgOut, "%s: %s\n", verb, preview.Key)
+    fmt.Fprintln(msgOut, "════════════════════════════════════════")
+    if preview.Old != nil {
+        fmt.Fprintf(msgOut, "Version actuelle: hash %s (%s)\n", shortHash(preview.Old.Hash), preview.Old.Timestamp)
+    }
+    if preview.Op == "put" {
+        fmt.Fprintf(msgOut, "Nouvelle version: hash %s\n", shortHash(preview.Hash))
+    }
+    fmt.Fprintln(msgOut)
+    
+    if diffs := previewDiffs(preview); len(diffs) > 0 {
+        printFieldDiffs(diffs)
+    } else {
+        fmt.Fprintln(msgOut, "Aucun champ modifié")
+    }
+    
+    if len(preview.IndexAdded)+len(preview.IndexRemoved) > 0 {
+        fmt.Fprintln(msgOut, "\nIndex:")
+        for _, k := range preview.IndexRemoved {
+            fmt.Fprintf(msgOut, "  - %s\n", k)
+        }
+        for _, k := range preview.IndexAdded {
+            fmt.Fprintf(msgOut, "  + %s\n", k)
+        }
+    }
+    
+    if len(preview.Unmaintained) > 0 {
+        fmt.Fprintln(msgOut, "\n⚠ Index non déclarés, non mis à jour par l'écriture:")
+        for _, name := range preview.Unmaintained {
+            fmt.Fprintf(msgOut, "  %s\n", name)
+        }
+    }
+}
+
+// previewDiffs compare l'ancienne et la nouvelle version
+func previewDiffs(preview *tpleveldb.WritePreview) []tpleveldb.FieldDiff {
+    switch {
+    case preview.Op == "delete":
+        return []tpleveldb.FieldDiff{{Op: "removed", From: preview.Old.Data}}
+    case preview.Old == nil:
+        return []tpleveldb.FieldDiff{{Op: "added", To: json.RawMessage(preview.Data)}}
+    }
+    diffs, err := tpleveldb.DiffJSON(preview.Old.Data, preview.Data)
+    if err != nil {
+        log.Fatalf("Erreur comparaison: %v", err)
+    }
+    return diffs
+}
+
+// reportWrite écrit le résultat pour les scripts (format machine) ou confirme l'écriture
+func reportWrite(preview *tpleveldb.WritePreview, applied bool) {
+    if !machineOutput() {
+        if applied {
+            past := map[string]string{"put": "écrit", "delete": "supprimé"}[preview.Op]
+            fmt.Fprintf(msgOut, "\n✓ %s %s\n", preview.Key, past)
+        }
+        return
+    }
+    
+    out := newOutput("key", "op", "applied", "hash", "changes", "index_added", "index_removed")
+    out.Row(preview.Key, preview.Op, applied, preview.Hash, previewDiffs(preview), preview.IndexAdded, preview.IndexRemoved)
+    flushOutput(out)
+}
\ No newline at end of file
@@ -0,0 +1,215 @@
+// pkg/format/format.go
+// Sorties lisibles par les scripts: table alignée, JSON, NDJSON (une ligne par objet) et CSV
+
+package format
+
+import (
+    "bytes"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+)
+
+// Formats de sortie
+const (
+    Table  = "table"  // colonnes alignées (affichage humain)
+    JSON   = "json"   // tableau d'objets
+    NDJSON = "ndjson" // un objet JSON par ligne
+    CSV    = "csv"    // en-tête puis une ligne par enregistrement
+)
+
+// Largeur maximale d'une cellule en table
+const maxCellWidth = 40
+
+// Parse valide un nom de format ("" = table)
+func Parse(name string) (string, error) {
+    switch name {
+    case "":
+        return Table, nil
+    case Table, JSON, NDJSON, CSV:
+        return name, nil
+    }
+    return "", fmt.Errorf("format inconnu: %q (table, json, ndjson ou csv)", name)
+}
+
+// Writer écrit des enregistrements aux colonnes fixes. Les noms de colonnes sont
+// les clés des objets JSON et l'en-tête CSV: ils forment l'interface stable des scripts.
+// JSON, NDJSON et CSV sont écrits au fil de l'eau; seule la table, qui aligne
+// ses colonnes, attend Flush.
+type Writer struct {
+    out     io.Writer
+    format  string
+    columns []string
+    rows    [][]interface{}
+    count   int // enregistrements écrits (JSON)
+    csv     *csv.Writer
+    err     error
+}
+
+// NewWriter crée un Writer pour les colonnes données
+func NewWriter(out io.Writer, format string, columns ...string) *Writer {
+    w := &Writer{out: out, format: format, columns: columns}
+    if format == CSV {
+        w.csv = csv.NewWriter(out)
+        w.err = w.csv.Write(columns)
+    }
+    return w
+}
+
+// Row ajoute un enregistrement (une valeur par colonne, dans l'ordre)
+func (w *Writer) Row(values ...interface{}) error {
+    if w.err != nil {
+        return w.err
+    }
+    if len(values) != len(w.columns) {
+        return fmt.Errorf("%d valeurs pour %d colonnes", len(values), len(w.columns))
+    }
+    
+    switch w.format {
+    case JSON:
+        var buf bytes.Buffer
+        if w.count == 0 {
+            buf.WriteString("[")
+        } else {
+            buf.WriteString(",")
+        }
+        buf.WriteString("\n  ")
+        w.writeObject(&buf, values)
+        w.count++
+        _, w.err = w.out.Write(buf.Bytes())
+    case NDJSON:
+        var buf bytes.Buffer
+        w.writeObject(&buf, values)
+        buf.WriteByte('\n')
+        _, w.err = w.out.Write(buf.Bytes())
+    case CSV:
+        record := make([]string, len(values))
+        for i, v := range values {
+            record[i] = Text(v)
+        }
+        w.err = w.csv.Write(record)
+    default:
+        w.rows = append(w.rows, values)
+    }
+    return w.err
+}
+
+// Flush termine la sortie
+func (w *Writer) Flush() error {
+    if w.err != nil {
+        return w.err
+    }
+    
+    switch w.format {
+    case JSON:
+        end := "\n]\n"
+        if w.count == 0 {
+            end = "[]\n"
+        }
+        _, w.err = io.WriteString(w.out, end)
+    case CSV:
+        w.csv.Flush()
+        w.err = w.csv.Error()
+    case Table:
+        w.err = w.writeTable()
+    }
+    return w.err
+}
+
+// writeObject écrit un objet JSON dont les clés suivent l'ordre des colonnes
+func (w *Writer) writeObject(buf *bytes.Buffer, values []interface{}) {
+    buf.WriteByte('{')
+    for i, col := range w.columns {
+        if i > 0 {
+            buf.WriteByte(',')
+        }
+        name, _ := json.Marshal(col)
+        buf.Write(name)
+        buf.WriteByte(':')
+    
+        value, err := json.Marshal(values[i])
+        if err != nil {
+            value, _ = json.Marshal(fmt.Sprint(values[i]))
+        }
+        buf.Write(value)
+    }
+    buf.WriteByte('}')
+}
+
+// writeTable aligne les colonnes; les cellules longues sont tronquées
+func (w *Writer) writeTable() error {
+    cells := make([][]string, len(w.rows))
+    widths := make([]int, len(w.columns))
+    for i, col := range w.columns {
+        widths[i] = utf8.RuneCountInString(col)
+    }
+    for r, row := range w.rows {
+        cells[r] = make([]string, len(row))
+        for i, v := range row {
+            s := []rune(Text(v))
+            if len(s) > maxCellWidth {
+                s = append(s[:maxCellWidth-1], '…')
+            }
+            cells[r][i] = string(s)
+            if len(s) > widths[i] {
+                widths[i] = len(s)
+            }
+        }
+    }
+    
+    // %-*s compte des octets: le remplissage est calculé en caractères
+    pad := func(s string, width int) string {
+        return s + strings.Repeat(" ", width-utf8.RuneCountInString(s))
+    }
+    
+    separator := make([]string, len(w.columns))
+    for i := range w.columns {
+        separator[i] = strings.Repeat("─", widths[i])
+    }
+    
+    var buf bytes.Buffer
+    writeLine := func(line []string) {
+        for i, s := range line {
+            if i < len(line)-1 {
+                s = pad(s, widths[i]) + "  "
+            }
+            buf.WriteString(s)
+        }
+        buf.WriteString("\n")
+    }
+    writeLine(w.columns)
+    writeLine(separator)
+    for _, row := range cells {
+        writeLine(row)
+    }
+    
+    _, err := w.out.Write(buf.Bytes())
+    return err
+}
+
+// Text convertit une valeur en texte de cellule (JSON pour les objets et tableaux)
+func Text(v interface{}) string {
+    switch val := v.(type) {
+    case nil:
+        return ""
+    case string:
+        return val
+    case float64:
+        return strconv.FormatFloat(val, 'f', -1, 64)
+    case int:
+        return strconv.Itoa(val)
+    case bool:
+        return strconv.FormatBool(val)
+    case json.RawMessage:
+        return string(val)
+    }
+    b, err := json.Marshal(v)
+    if err != nil {
+        return fmt.Sprint(v)
+    }
+    return string(b)
+}
\ No newline at end of file
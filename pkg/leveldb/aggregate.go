@@ -0,0 +1,221 @@
+// pkg/leveldb/aggregate.go
+// Agrégations (count, sum, min, max, avg, percentiles, histogramme) groupées par champ indexé
+
+package leveldb
+
+import (
+    "fmt"
+    "math"
+    "sort"
+    "strconv"
+    "strings"
+    
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// AggQuery décrit une agrégation: les documents sont groupés par la valeur
+// d'un champ indexé et le champ numérique Field est agrégé dans chaque groupe
+type AggQuery struct {
+    RecordType  string
+    GroupBy     string    // champ indexé servant au regroupement
+    Field       string    // champ numérique agrégé (vide = comptage seul)
+    Percentiles []float64 // ex: 50, 90, 99
+    Buckets     int       // nombre de classes de l'histogramme (0 = pas d'histogramme)
+}
+
+// HistogramBucket est une classe [Low, High) de l'histogramme d'un groupe
+type HistogramBucket struct {
+    Low   float64 `json:"low"`
+    High  float64 `json:"high"`
+    Count int     `json:"count"`
+}
+
+// AggGroup est le résultat d'agrégation pour une valeur du champ de regroupement
+type AggGroup struct {
+    Value       string             `json:"value"`
+    Count       int                `json:"count"`
+    Numeric     int                `json:"numeric"` // documents dont Field est numérique
+    Sum         float64            `json:"sum"`
+    Min         float64            `json:"min"`
+    Max         float64            `json:"max"`
+    Avg         float64            `json:"avg"`
+    Percentiles map[string]float64 `json:"percentiles,omitempty"`
+    Histogram   []HistogramBucket  `json:"histogram,omitempty"`
+    
+    values []float64
+}
+
+// Aggregate parcourt l'index GroupBy dans l'ordre des valeurs: chaque groupe est
+// contigu et n'est lu qu'une fois. Le champ agrégé est lu dans la projection
+// d'un index couvrant si possible, sinon dans le document.
+func (idx *Indexer) Aggregate(q AggQuery) ([]AggGroup, error) {
+    if q.RecordType == "" || q.GroupBy == "" {
+        return nil, fmt.Errorf("type d'enregistrement et champ de regroupement requis")
+    }
+    
+    // Les valeurs de groupe sont rendues lisibles comme dans Stats
+    def, err := idx.GetIndexDef(q.RecordType, q.GroupBy)
+    if err != nil {
+        return nil, err
+    }
+    if def == nil && strings.Contains(q.GroupBy, compositeSeparator) {
+        // Index composite créé par CreateCompositeIndex sans définition enregistrée
+        def = &IndexDef{RecordType: q.RecordType, Kind: IndexKindComposite, Fields: strings.Split(q.GroupBy, compositeSeparator)}
+    }
+    
+    keepValues := len(q.Percentiles) > 0 || q.Buckets > 0
+    prefix := indexPrefix(q.RecordType, q.GroupBy)
+    
+    var groups []AggGroup
+    var current *AggGroup
+    stored := "" // valeur stockée du groupe courant
+    
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        primaryKey, projection := decodeIndexValue(iter.Value())
+        value := strings.TrimSuffix(strings.TrimPrefix(string(iter.Key()), prefix), ":"+primaryKey)
+        
+        if current == nil || stored != value {
+            if current != nil {
+                current.finish(q)
+                groups = append(groups, *current)
+            }
+            current = &AggGroup{Value: def.displayValue(value)}
+            stored = value
+        }
+        current.Count++
+        
+        if q.Field == "" {
+            continue
+        }
+        
+        raw, ok := projection[q.Field]
+        if !ok {
+            valueBytes, err := idx.db.Get([]byte(primaryKey), nil)
+            if err != nil {
+                continue
+            }
+            data, err := decodeDocument(valueBytes)
+            if err != nil {
+                continue
+            }
+            raw = data[q.Field]
+        }
+        
+        if n, ok := toFloat(raw); ok {
+            current.add(n, keepValues)
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur itération: %v", err)
+    }
+    
+    if current != nil {
+        current.finish(q)
+        groups = append(groups, *current)
+    }
+    
+    return groups, nil
+}
+
+func (g *AggGroup) add(n float64, keep bool) {
+    if g.Numeric == 0 || n < g.Min {
+        g.Min = n
+    }
+    if g.Numeric == 0 || n > g.Max {
+        g.Max = n
+    }
+    g.Numeric++
+    g.Sum += n
+    if keep {
+        g.values = append(g.values, n)
+    }
+}
+
+func (g *AggGroup) finish(q AggQuery) {
+    if g.Numeric > 0 {
+        g.Avg = g.Sum / float64(g.Numeric)
+    }
+    
+    if len(g.values) == 0 {
+        return
+    }
+    sort.Float64s(g.values)
+    
+    if len(q.Percentiles) > 0 {
+        g.Percentiles = make(map[string]float64, len(q.Percentiles))
+        for _, p := range q.Percentiles {
+            g.Percentiles[PercentileName(p)] = percentile(g.values, p)
+        }
+    }
+    
+    if q.Buckets > 0 {
+        g.Histogram = histogram(g.values, q.Buckets)
+    }
+    
+    g.values = nil
+}
+
+// PercentileName retourne le nom d'un percentile (90 → "p90", 99.9 → "p99.9")
+func PercentileName(p float64) string {
+    return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// percentile calcule un percentile par interpolation linéaire sur des valeurs triées
+func percentile(sorted []float64, p float64) float64 {
+    if len(sorted) == 1 {
+        return sorted[0]
+    }
+    
+    rank := p / 100 * float64(len(sorted)-1)
+    lower := int(math.Floor(rank))
+    upper := int(math.Ceil(rank))
+    if lower < 0 {
+        return sorted[0]
+    }
+    if upper >= len(sorted) {
+        return sorted[len(sorted)-1]
+    }
+    
+    return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
+// histogram répartit des valeurs triées en classes de même largeur entre min et max
+func histogram(sorted []float64, buckets int) []HistogramBucket {
+    low, high := sorted[0], sorted[len(sorted)-1]
+    width := (high - low) / float64(buckets)
+    if width == 0 {
+        return []HistogramBucket{{Low: low, High: high, Count: len(sorted)}}
+    }
+    
+    result := make([]HistogramBucket, buckets)
+    for i := range result {
+        result[i].Low = low + float64(i)*width
+        result[i].High = low + float64(i+1)*width
+    }
+    
+    for _, v := range sorted {
+        i := int((v - low) / width)
+        if i >= buckets {
+            i = buckets - 1
+        }
+        result[i].Count++
+    }
+    
+    return result
+}
+
+// toFloat convertit une valeur JSON (nombre ou chaîne numérique) en float64
+func toFloat(value interface{}) (float64, bool) {
+    switch v := value.(type) {
+    case float64:
+        return v, true
+    case string:
+        n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+        return n, err == nil
+    }
+    return 0, false
+}
\ No newline at end of file
@@ -0,0 +1,355 @@
+// pkg/leveldb/changes.go
+// Journal des changements (_changes:) alimenté par les écritures du Client
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Le journal est indexé par clé pour History:
+//
+//     _changes:<seq>     → Change
+//     _hist:<clé>:<seq>  → "" (une entrée par changement de la clé)
+//     _hist              → présent quand tout le journal est indexé par clé
+//
+// Chaque écriture y copie le document: le Client le purge donc lui-même, toutes
+// les changePruneInterval écritures, en ne gardant que ce que les vues n'ont pas
+// encore consommé et les DefaultChangeRetention derniers changements (historique
+// des clés, query -diff ~N). SetChangeRetention règle cette conservation.
+const (
+    changesPrefix     = "_changes:"
+    historyPrefix     = "_hist:"
+    historyIndexedKey = "_hist"
+)
+
+const (
+    DefaultChangeRetention = 10000 // changements conservés au-delà des vues
+    changePruneInterval    = 1000  // écritures entre deux purges automatiques
+)
+
+// Change est une entrée du journal: une écriture ou une suppression (tombstone)
+type Change struct {
+    Seq       uint64          `json:"seq"`
+    Op        string          `json:"op"` // "put" ou "delete"
+    Key       string          `json:"key"`
+    Timestamp string          `json:"timestamp"`
+    Entry     json.RawMessage `json:"entry,omitempty"` // Entry écrite (op put)
+}
+
+func changeKey(seq uint64) string {
+    return fmt.Sprintf("%s%020d", changesPrefix, seq)
+}
+
+func historyKey(key string, seq uint64) string {
+    return fmt.Sprintf("%s%s:%020d", historyPrefix, key, seq)
+}
+
+// appendChange ajoute au lot l'entrée de journal numéro seq
+func appendChange(batch *leveldb.Batch, seq uint64, op, key string, entryBytes []byte) {
+    change := Change{
+        Seq:       seq,
+        Op:        op,
+        Key:       key,
+        Timestamp: time.Now().Format(time.RFC3339),
+        Entry:     entryBytes,
+    }
+    
+    changeBytes, _ := json.Marshal(change)
+    batch.Put([]byte(changeKey(seq)), changeBytes)
+    batch.Put([]byte(historyKey(key, seq)), nil)
+}
+
+// indexHistory indexe par clé un journal écrit avant l'index _hist: (une fois,
+// à l'ouverture d'un nœud en écriture)
+func indexHistory(db *leveldb.DB) error {
+    indexed, err := db.Has([]byte(historyIndexedKey), nil)
+    if err != nil || indexed {
+        return err
+    }
+    
+    iter := db.NewIterator(util.BytesPrefix([]byte(changesPrefix)), nil)
+    defer iter.Release()
+    
+    batch := new(leveldb.Batch)
+    for iter.Next() {
+        var change Change
+        if err := json.Unmarshal(iter.Value(), &change); err != nil {
+            continue
+        }
+        batch.Put([]byte(historyKey(change.Key, change.Seq)), nil)
+        
+        if batch.Len() >= rebuildBatchSize {
+            if err := db.Write(batch, nil); err != nil {
+                return err
+            }
+            batch.Reset()
+        }
+    }
+    if err := iter.Error(); err != nil {
+        return err
+    }
+    
+    batch.Put([]byte(historyIndexedKey), nil)
+    return db.Write(batch, nil)
+}
+
+// firstChangeSeq lit le plus ancien numéro de séquence conservé (0 si vide)
+func firstChangeSeq(db *leveldb.DB) (uint64, error) {
+    iter := db.NewIterator(util.BytesPrefix([]byte(changesPrefix)), nil)
+    defer iter.Release()
+    
+    if !iter.First() {
+        return 0, iter.Error()
+    }
+    
+    seq, err := strconv.ParseUint(strings.TrimPrefix(string(iter.Key()), changesPrefix), 10, 64)
+    if err != nil {
+        return 0, fmt.Errorf("clé de journal invalide %s: %v", iter.Key(), err)
+    }
+    return seq, nil
+}
+
+// lastChangeSeq lit le dernier numéro de séquence du journal (0 si vide)
+func lastChangeSeq(db *leveldb.DB) (uint64, error) {
+    iter := db.NewIterator(util.BytesPrefix([]byte(changesPrefix)), nil)
+    defer iter.Release()
+    
+    if !iter.Last() {
+        return 0, iter.Error()
+    }
+    
+    seq, err := strconv.ParseUint(strings.TrimPrefix(string(iter.Key()), changesPrefix), 10, 64)
+    if err != nil {
+        return 0, fmt.Errorf("clé de journal invalide %s: %v", iter.Key(), err)
+    }
+    return seq, nil
+}
+
+// readChanges retourne au plus limit changements de numéro > since
+func readChanges(db *leveldb.DB, since uint64, limit int) ([]Change, error) {
+    iter := db.NewIterator(util.BytesPrefix([]byte(changesPrefix)), nil)
+    defer iter.Release()
+    
+    var changes []Change
+    for ok := iter.Seek([]byte(changeKey(since + 1))); ok; ok = iter.Next() {
+        var change Change
+        if err := json.Unmarshal(iter.Value(), &change); err != nil {
+            continue
+        }
+        changes = append(changes, change)
+        if limit > 0 && len(changes) >= limit {
+            break
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur lecture journal: %v", err)
+    }
+    return changes, nil
+}
+
+// Changes retourne les changements postérieurs à since (limit 0 = tous)
+func (c *Client) Changes(since uint64, limit int) ([]Change, error) {
+    return readChanges(c.db, since, limit)
+}
+
+// History retourne les changements journalisés d'une clé, du plus ancien au
+// plus récent, par l'index _hist: de la clé. Un journal non indexé (nœud ouvert
+// en lecture seule, jamais rouvert en écriture) est parcouru en entier.
+func (c *Client) History(key string) ([]Change, error) {
+    indexed, err := c.db.Has([]byte(historyIndexedKey), nil)
+    if err != nil {
+        return nil, fmt.Errorf("erreur lecture journal: %v", err)
+    }
+    if !indexed {
+        return c.scanHistory(key)
+    }
+    
+    prefix := historyPrefix + key + ":"
+    iter := c.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    var changes []Change
+    for iter.Next() {
+        // Les clés qui prolongent key ("order:1:x") partagent le préfixe
+        seq, err := strconv.ParseUint(string(iter.Key()[len(prefix):]), 10, 64)
+        if err != nil {
+            continue
+        }
+        
+        value, err := c.db.Get([]byte(changeKey(seq)), nil)
+        if err == leveldb.ErrNotFound {
+            continue
+        }
+        if err != nil {
+            return nil, fmt.Errorf("erreur lecture journal: %v", err)
+        }
+        
+        var change Change
+        if err := json.Unmarshal(value, &change); err != nil || change.Key != key {
+            continue
+        }
+        changes = append(changes, change)
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur lecture journal: %v", err)
+    }
+    return changes, nil
+}
+
+// scanHistory cherche les changements d'une clé en parcourant tout le journal
+func (c *Client) scanHistory(key string) ([]Change, error) {
+    iter := c.db.NewIterator(util.BytesPrefix([]byte(changesPrefix)), nil)
+    defer iter.Release()
+    
+    var changes []Change
+    for iter.Next() {
+        var change Change
+        if err := json.Unmarshal(iter.Value(), &change); err != nil || change.Key != key {
+            continue
+        }
+        changes = append(changes, change)
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur lecture journal: %v", err)
+    }
+    return changes, nil
+}
+
+// PruneChanges supprime du journal les changements de numéro < before, sans
+// dépasser la position des vues: un changement qu'une vue n'a pas encore
+// consommé est conservé, ainsi que le dernier changement (il porte le numéro
+// de séquence du nœud). Retourne le nombre de changements supprimés.
+// L'historique des clés (History, query -diff ~N) ne porte ensuite que sur le
+// journal restant; une vue créée ou réinitialisée après la purge est
+// construite depuis les documents.
+func (c *Client) PruneChanges(before uint64) (int, error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.pruneChanges(before)
+}
+
+// SetChangeRetention fixe le nombre de changements que la purge automatique
+// conserve au-delà de la position des vues (DefaultChangeRetention par défaut):
+// 0 ne garde que ce que les vues n'ont pas consommé, une valeur négative
+// désactive la purge automatique.
+func (c *Client) SetChangeRetention(n int) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.retention = n
+    c.pruneAt = 0
+}
+
+// autoPruneChanges purge le journal après une écriture (c.mu tenu), toutes les
+// changePruneInterval écritures. L'écriture est déjà validée: un échec de la
+// purge est ignoré, elle est retentée à l'intervalle suivant.
+func (c *Client) autoPruneChanges() {
+    if c.retention < 0 || c.seq < c.pruneAt {
+        return
+    }
+    c.pruneAt = c.seq + changePruneInterval
+    if c.seq > uint64(c.retention) {
+        c.pruneChanges(c.seq - uint64(c.retention) + 1)
+    }
+}
+
+// pruneChanges est PruneChanges, c.mu tenu
+func (c *Client) pruneChanges(before uint64) (int, error) {
+    consumed, err := c.minViewSeq()
+    if err != nil {
+        return 0, err
+    }
+    if consumed+1 < before {
+        before = consumed + 1
+    }
+    if before > c.seq {
+        before = c.seq
+    }
+    
+    iter := c.db.NewIterator(&util.Range{Start: []byte(changeKey(0)), Limit: []byte(changeKey(before))}, nil)
+    defer iter.Release()
+    
+    batch := new(leveldb.Batch)
+    pruned := 0
+    for iter.Next() {
+        batch.Delete(append([]byte(nil), iter.Key()...))
+        var change Change
+        if err := json.Unmarshal(iter.Value(), &change); err == nil {
+            batch.Delete([]byte(historyKey(change.Key, change.Seq)))
+        }
+        pruned++
+        
+        if batch.Len() >= rebuildBatchSize {
+            if err := c.db.Write(batch, nil); err != nil {
+                return pruned, fmt.Errorf("erreur écriture lot: %v", err)
+            }
+            batch.Reset()
+        }
+    }
+    if err := iter.Error(); err != nil {
+        return pruned, fmt.Errorf("erreur lecture journal: %v", err)
+    }
+    
+    if err := c.db.Write(batch, nil); err != nil {
+        return pruned, fmt.Errorf("erreur écriture lot: %v", err)
+    }
+    return pruned, nil
+}
+
+// minViewSeq retourne la plus petite position des vues, déclarées sur ce
+// client ou seulement persistées (c.seq sans vue)
+func (c *Client) minViewSeq() (uint64, error) {
+    consumed := c.seq
+    names := make(map[string]bool)
+    for name := range c.views {
+        names[name] = true
+    }
+    
+    // Une clé _view:<nom>:... par vue persistée: sauter au nom suivant
+    iter := c.db.NewIterator(util.BytesPrefix([]byte("_view:")), nil)
+    defer iter.Release()
+    for ok := iter.Next(); ok; {
+        rest := strings.TrimPrefix(string(iter.Key()), "_view:")
+        i := strings.Index(rest, ":")
+        if i < 0 {
+            ok = iter.Next()
+            continue
+        }
+        names[rest[:i]] = true
+        ok = iter.Seek(util.BytesPrefix([]byte(viewPrefix(rest[:i]))).Limit)
+    }
+    if err := iter.Error(); err != nil {
+        return 0, fmt.Errorf("erreur lecture vues: %v", err)
+    }
+    
+    for name := range names {
+        since := uint64(0)
+        raw, err := c.db.Get([]byte(viewSeqKey(name)), nil)
+        if err == nil {
+            json.Unmarshal(raw, &since)
+        } else if err != leveldb.ErrNotFound {
+            return 0, fmt.Errorf("erreur lecture vue %s: %v", name, err)
+        }
+        if since < consumed {
+            consumed = since
+        }
+    }
+    return consumed, nil
+}
+
+// LastSeq retourne le numéro du dernier changement journalisé
+func (c *Client) LastSeq() uint64 {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.seq
+}
\ No newline at end of file
@@ -0,0 +1,108 @@
+// pkg/leveldb/changes_test.go
+// Tests du journal des changements: purge automatique et vues construites après purge
+
+package leveldb
+
+import (
+    "fmt"
+    "testing"
+)
+
+func loadUsers(t *testing.T, client *Client, n int) {
+    t.Helper()
+    entries := make(map[string]interface{}, n)
+    for i := 0; i < n; i++ {
+        state := "sp"
+        if i%4 == 0 {
+            state = "rj"
+        }
+        entries[fmt.Sprintf("user:%05d", i)] = map[string]interface{}{"state": state}
+    }
+    if err := client.BatchInsert(entries); err != nil {
+        t.Fatalf("BatchInsert: %v", err)
+    }
+}
+
+// La purge automatique ne garde que les derniers changements; une vue créée
+// ensuite est construite depuis les documents et couvre toute la base
+func TestAutoPruneChanges(t *testing.T) {
+    client := newTestClient(t)
+    client.SetChangeRetention(5)
+    loadUsers(t, client, 1200)
+    
+    if n := countKeys(t, client, changesPrefix); n != 5 {
+        t.Errorf("changements conservés: %d, attendu 5", n)
+    }
+    if n := countKeys(t, client, historyPrefix); n != 5 {
+        t.Errorf("entrées _hist: conservées: %d, attendu 5", n)
+    }
+    if first, err := firstChangeSeq(client.GetDB()); err != nil || first != 1196 {
+        t.Errorf("premier changement: %d, %v (attendu 1196)", first, err)
+    }
+    
+    view := &View{
+        Name:       "by_state",
+        RecordType: "user",
+        Map: func(key string, doc map[string]interface{}, emit EmitFunc) {
+            emit([]string{fmt.Sprint(doc["state"])}, 1)
+        },
+        Reduce: ReduceCount,
+    }
+    if err := client.RegisterView(view); err != nil {
+        t.Fatalf("RegisterView: %v", err)
+    }
+    
+    // Écriture après la construction: la vue reprend depuis le journal
+    if err := client.Put("user:99999", map[string]interface{}{"state": "rj"}); err != nil {
+        t.Fatalf("Put: %v", err)
+    }
+    
+    rows, err := client.QueryView("by_state", ViewQuery{Reduce: true, Group: true})
+    if err != nil {
+        t.Fatalf("QueryView: %v", err)
+    }
+    counts := make(map[string]string)
+    for _, row := range rows {
+        counts[row.Key[0]] = fmt.Sprint(row.Value)
+    }
+    if counts["rj"] != "301" || counts["sp"] != "900" {
+        t.Errorf("vue après purge: %v, attendu rj=301 sp=900", counts)
+    }
+}
+
+// Le dernier changement est toujours conservé: le numéro de séquence survit à
+// la réouverture du nœud
+func TestPruneKeepsLastChange(t *testing.T) {
+    dir := t.TempDir()
+    client, err := NewClient(dir)
+    if err != nil {
+        t.Fatalf("NewClient: %v", err)
+    }
+    client.SetChangeRetention(0)
+    loadUsers(t, client, 10)
+    
+    if n := countKeys(t, client, changesPrefix); n != 1 {
+        t.Errorf("changements conservés: %d, attendu 1", n)
+    }
+    client.Close()
+    
+    client, err = NewClient(dir)
+    if err != nil {
+        t.Fatalf("NewClient: %v", err)
+    }
+    defer client.Close()
+    if seq := client.LastSeq(); seq != 10 {
+        t.Errorf("LastSeq après réouverture: %d, attendu 10", seq)
+    }
+}
+
+// Une rétention négative conserve tout le journal
+func TestChangeRetentionDisabled(t *testing.T) {
+    client := newTestClient(t)
+    client.SetChangeRetention(-1)
+    loadUsers(t, client, 1200)
+    
+    if n := countKeys(t, client, changesPrefix); n != 1200 {
+        t.Errorf("changements conservés: %d, attendu 1200", n)
+    }
+}
\ No newline at end of file
@@ -1,165 +1,327 @@
-// pkg/leveldb/client.go
-// Client LevelDB avec vérification d'intégrité cryptographique
-
-package leveldb
-
-import (
-    "crypto/sha256"
-    "encoding/hex"
-    "encoding/json"
-    "fmt"
-    "time"
-    
-    "github.com/syndtr/goleveldb/leveldb"
-    "github.com/syndtr/goleveldb/leveldb/opt"
-)
-
-type Client struct {
-    db   *leveldb.DB
-    node string
-}
-
-type Entry struct {
-    Data      json.RawMessage `json:"data"`
-    Hash      string          `json:"hash"`
-    Timestamp string          `json:"timestamp"`
-    Node      string          `json:"node"`
-}
-
-func NewClient(nodePath string) (*Client, error) {
-    opts := &opt.Options{
-        WriteBuffer: 4 * 1024 * 1024,
-        Compression: opt.SnappyCompression,
-    }
-    
-    // CORRECTION: Open prend directement le path
-    db, err := leveldb.OpenFile(nodePath, opts)
-    if err != nil {
-        return nil, fmt.Errorf("erreur ouverture LevelDB: %v", err)
-    }
-    
-    return &Client{
-        db:   db,
-        node: nodePath,
-    }, nil
-}
-
-func (c *Client) Put(key string, data interface{}) error {
-    dataBytes, err := json.Marshal(data)
-    if err != nil {
-        return fmt.Errorf("erreur sérialisation: %v", err)
-    }
-    
-    hash := calculateHash(dataBytes)
-    
-    entry := Entry{
-        Data:      dataBytes,
-        Hash:      hash,
-        Timestamp: time.Now().Format(time.RFC3339),
-        Node:      c.node,
-    }
-    
-    entryBytes, err := json.Marshal(entry)
-    if err != nil {
-        return fmt.Errorf("erreur sérialisation entry: %v", err)
-    }
-    
-    // CORRECTION: Set → Put
-    return c.db.Put([]byte(key), entryBytes, nil)
-}
-
-func (c *Client) Get(key string) (*Entry, error) {
-    value, err := c.db.Get([]byte(key), nil)
-    if err != nil {
-        return nil, fmt.Errorf("clé non trouvée: %v", err)
-    }
-    
-    var entry Entry
-    if err := json.Unmarshal(value, &entry); err != nil {
-        return nil, fmt.Errorf("erreur désérialisation: %v", err)
-    }
-    
-    return &entry, nil
-}
-
-func (c *Client) VerifyIntegrity(key string) (bool, error) {
-    entry, err := c.Get(key)
-    if err != nil {
-        return false, err
-    }
-    
-    computedHash := calculateHash(entry.Data)
-    
-    if computedHash != entry.Hash {
-        return false, fmt.Errorf("hash mismatch: attendu %s, obtenu %s", 
-            entry.Hash, computedHash)
-    }
-    
-    return true, nil
-}
-
-func (c *Client) Delete(key string) error {
-    return c.db.Delete([]byte(key), nil)
-}
-
-func (c *Client) BatchInsert(entries map[string]interface{}) error {
-    batch := new(leveldb.Batch)
-    
-    for key, data := range entries {
-        dataBytes, err := json.Marshal(data)
-        if err != nil {
-            return fmt.Errorf("erreur sérialisation %s: %v", key, err)
-        }
-        
-        hash := calculateHash(dataBytes)
-        
-        entry := Entry{
-            Data:      dataBytes,
-            Hash:      hash,
-            Timestamp: time.Now().Format(time.RFC3339),
-            Node:      c.node,
-        }
-        
-        entryBytes, _ := json.Marshal(entry)
-        
-        // CORRECTION: Set → Put
-        batch.Put([]byte(key), entryBytes)
-    }
-    
-    // CORRECTION: Apply → Write
-    return c.db.Write(batch, nil)
-}
-
-func (c *Client) Count() (int, error) {
-    // CORRECTION: Find → NewIterator
-    iter := c.db.NewIterator(nil, nil)
-    defer iter.Release()
-    
-    count := 0
-    for iter.Next() {
-        key := iter.Key()
-        if len(key) > 0 && key[0] != '_' {
-            count++
-        }
-    }
-    
-    if err := iter.Error(); err != nil {
-        return 0, err
-    }
-    
-    return count, nil
-}
-
-func (c *Client) Close() error {
-    return c.db.Close()
-}
-
-func (c *Client) GetDB() *leveldb.DB {
-    return c.db
-}
-
-func calculateHash(data []byte) string {
-    h := sha256.New()
-    h.Write(data)
-    return hex.EncodeToString(h.Sum(nil))
+// pkg/leveldb/client.go
+// Client LevelDB avec vérification d'intégrité cryptographique
+
+package leveldb
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+type Client struct {
+    db   *leveldb.DB
+    node string
+    
+    // Sérialise les écritures pour que les contraintes d'unicité soient
+    // vérifiées et appliquées atomiquement avec le document
+    mu sync.Mutex
+    
+    seq       uint64 // dernier numéro du journal des changements
+    retention int    // changements conservés par la purge automatique (<0: tous)
+    pruneAt   uint64 // numéro à partir duquel purger de nouveau le journal
+    
+    views   map[string]*View // vues map/reduce tenues à jour après chaque écriture
+    viewErr error            // échec de la mise à jour des vues après la dernière écriture
+}
+
+type Entry struct {
+    Data      json.RawMessage `json:"data"`
+    Hash      string          `json:"hash"`
+    Timestamp string          `json:"timestamp"`
+    Node      string          `json:"node"`
+}
+
+func NewClient(nodePath string) (*Client, error) {
+    opts := &opt.Options{
+        WriteBuffer: 4 * 1024 * 1024,
+        Compression: opt.SnappyCompression,
+    }
+    
+    // CORRECTION: Open prend directement le path
+    db, err := leveldb.OpenFile(nodePath, opts)
+    if err != nil {
+        return nil, fmt.Errorf("erreur ouverture LevelDB: %v", err)
+    }
+    
+    seq, err := lastChangeSeq(db)
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("erreur lecture journal des changements: %v", err)
+    }
+    
+    if err := indexHistory(db); err != nil {
+        db.Close()
+        return nil, fmt.Errorf("erreur indexation du journal des changements: %v", err)
+    }
+    
+    return &Client{
+        db:        db,
+        node:      nodePath,
+        seq:       seq,
+        retention: DefaultChangeRetention,
+        views:     make(map[string]*View),
+    }, nil
+}
+
+// OpenReadOnly ouvre une base existante en lecture seule (copie de nœud sous
+// snapshots/ par exemple): rien n'est créé ni écrit, les écritures échouent
+func OpenReadOnly(nodePath string) (*Client, error) {
+    opts := &opt.Options{
+        ReadOnly:       true,
+        ErrorIfMissing: true,
+    }
+    
+    db, err := leveldb.OpenFile(nodePath, opts)
+    if err != nil {
+        return nil, fmt.Errorf("erreur ouverture LevelDB: %v", err)
+    }
+    
+    seq, err := lastChangeSeq(db)
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("erreur lecture journal des changements: %v", err)
+    }
+    
+    return &Client{
+        db:        db,
+        node:      nodePath,
+        seq:       seq,
+        retention: DefaultChangeRetention,
+        views:     make(map[string]*View),
+    }, nil
+}
+
+func (c *Client) Put(key string, data interface{}) error {
+    dataBytes, err := json.Marshal(data)
+    if err != nil {
+        return fmt.Errorf("erreur sérialisation: %v", err)
+    }
+    
+    hash := calculateHash(dataBytes)
+    modified := time.Now()
+    
+    entry := Entry{
+        Data:      dataBytes,
+        Hash:      hash,
+        Timestamp: modified.Format(time.RFC3339),
+        Node:      c.node,
+    }
+    
+    entryBytes, err := json.Marshal(entry)
+    if err != nil {
+        return fmt.Errorf("erreur sérialisation entry: %v", err)
+    }
+    
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    
+    batch := new(leveldb.Batch)
+    txn := newIndexTxn(c.db, batch)
+    
+    // Index déclarés mis à jour dans le même lot que le document
+    if err := txn.put(key, dataBytes); err != nil {
+        return err
+    }
+    if err := updateMtime(c.db, batch, key, modified); err != nil {
+        return err
+    }
+    
+    // CORRECTION: Set → Put
+    batch.Put([]byte(key), entryBytes)
+    
+    seq := c.seq + 1
+    appendChange(batch, seq, "put", key, entryBytes)
+    
+    if err := txn.flush(); err != nil {
+        return err
+    }
+    
+    if err := c.db.Write(batch, nil); err != nil {
+        return err
+    }
+    c.seq = seq
+    
+    // Le lot est écrit: un échec des vues ne doit pas faire rejouer l'écriture
+    c.updateViews()
+    c.autoPruneChanges()
+    return nil
+}
+
+func (c *Client) Get(key string) (*Entry, error) {
+    value, err := c.db.Get([]byte(key), nil)
+    if err != nil {
+        return nil, fmt.Errorf("clé non trouvée: %v", err)
+    }
+    
+    var entry Entry
+    if err := json.Unmarshal(value, &entry); err != nil {
+        return nil, fmt.Errorf("erreur désérialisation: %v", err)
+    }
+    
+    return &entry, nil
+}
+
+func (c *Client) VerifyIntegrity(key string) (bool, error) {
+    entry, err := c.Get(key)
+    if err != nil {
+        return false, err
+    }
+    
+    computedHash := calculateHash(entry.Data)
+    
+    if computedHash != entry.Hash {
+        return false, fmt.Errorf("hash mismatch: attendu %s, obtenu %s", 
+            entry.Hash, computedHash)
+    }
+    
+    return true, nil
+}
+
+func (c *Client) Delete(key string) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    
+    batch := new(leveldb.Batch)
+    txn := newIndexTxn(c.db, batch)
+    
+    if err := txn.delete(key); err != nil {
+        return err
+    }
+    if err := updateMtime(c.db, batch, key, time.Time{}); err != nil {
+        return err
+    }
+    
+    batch.Delete([]byte(key))
+    
+    // Tombstone dans le journal pour que les vues retirent le document
+    seq := c.seq + 1
+    appendChange(batch, seq, "delete", key, nil)
+    
+    if err := txn.flush(); err != nil {
+        return err
+    }
+    
+    if err := c.db.Write(batch, nil); err != nil {
+        return err
+    }
+    c.seq = seq
+    
+    // Le lot est écrit: un échec des vues ne doit pas faire rejouer l'écriture
+    c.updateViews()
+    c.autoPruneChanges()
+    return nil
+}
+
+func (c *Client) BatchInsert(entries map[string]interface{}) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    
+    batch := new(leveldb.Batch)
+    txn := newIndexTxn(c.db, batch)
+    seq := c.seq
+    
+    // Ordre déterministe pour que les conflits d'unicité soient reproductibles
+    keys := make([]string, 0, len(entries))
+    for key := range entries {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    
+    // Retirer d'abord toutes les anciennes entrées d'index: une valeur unique
+    // déplacée d'une clé à une autre du lot est libre quel que soit leur ordre
+    for _, key := range keys {
+        if err := txn.delete(key); err != nil {
+            return err
+        }
+    }
+    
+    for _, key := range keys {
+        data := entries[key]
+        dataBytes, err := json.Marshal(data)
+        if err != nil {
+            return fmt.Errorf("erreur sérialisation %s: %v", key, err)
+        }
+        
+        hash := calculateHash(dataBytes)
+        modified := time.Now()
+        
+        entry := Entry{
+            Data:      dataBytes,
+            Hash:      hash,
+            Timestamp: modified.Format(time.RFC3339),
+            Node:      c.node,
+        }
+        
+        entryBytes, _ := json.Marshal(entry)
+        
+        if err := txn.add(key, dataBytes); err != nil {
+            return err
+        }
+        if err := updateMtime(c.db, batch, key, modified); err != nil {
+            return err
+        }
+        
+        // CORRECTION: Set → Put
+        batch.Put([]byte(key), entryBytes)
+        
+        seq++
+        appendChange(batch, seq, "put", key, entryBytes)
+    }
+    
+    if err := txn.flush(); err != nil {
+        return err
+    }
+    
+    // CORRECTION: Apply → Write
+    if err := c.db.Write(batch, nil); err != nil {
+        return err
+    }
+    c.seq = seq
+    
+    // Le lot est écrit: un échec des vues ne doit pas faire rejouer l'écriture
+    c.updateViews()
+    c.autoPruneChanges()
+    return nil
+}
+
+func (c *Client) Count() (int, error) {
+    // CORRECTION: Find → NewIterator
+    iter := c.db.NewIterator(nil, nil)
+    defer iter.Release()
+    
+    count := 0
+    for iter.Next() {
+        key := iter.Key()
+        if len(key) > 0 && key[0] != '_' {
+            count++
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return 0, err
+    }
+    
+    return count, nil
+}
+
+func (c *Client) Close() error {
+    return c.db.Close()
+}
+
+func (c *Client) GetDB() *leveldb.DB {
+    return c.db
+}
+
+func calculateHash(data []byte) string {
+    h := sha256.New()
+    h.Write(data)
+    return hex.EncodeToString(h.Sum(nil))
 }
\ No newline at end of file
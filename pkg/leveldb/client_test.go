@@ -0,0 +1,215 @@
+// pkg/leveldb/client_test.go
+// Tests des écritures du Client: index déclarés, compteurs et unicité
+
+package leveldb
+
+import (
+    "fmt"
+    "testing"
+)
+
+// assertStats vérifie l'en-tête des compteurs d'un index
+func assertStats(t *testing.T, idx *Indexer, recordType, name string, entries, distinct int) {
+    t.Helper()
+    header, err := idx.readStatsHeader(recordType, name)
+    if err != nil || header == nil {
+        t.Fatalf("compteurs %s:%s: %+v, %v", recordType, name, header, err)
+    }
+    if header.Entries != entries || header.Distinct != distinct {
+        t.Errorf("compteurs %s:%s: %d entrées / %d distinctes, attendu %d / %d",
+            recordType, name, header.Entries, header.Distinct, entries, distinct)
+    }
+}
+
+// Réécrire un document inchangé ne modifie pas les compteurs, y compris pour
+// un tableau dont une valeur est répétée
+func TestPutSameDocumentKeepsCounts(t *testing.T) {
+    client := newTestClient(t)
+    idx := NewIndexer(client.GetDB())
+    
+    for _, field := range []string{"email", "tags"} {
+        if _, err := idx.RebuildIndex(IndexDef{RecordType: "user", Field: field}); err != nil {
+            t.Fatalf("RebuildIndex %s: %v", field, err)
+        }
+    }
+    
+    doc := map[string]interface{}{"email": "a@x", "tags": []string{"x", "x", "y"}}
+    for i := 0; i < 2; i++ {
+        if err := client.Put("user:1", doc); err != nil {
+            t.Fatalf("Put: %v", err)
+        }
+    }
+    assertStats(t, idx, "user", "email", 1, 1)
+    assertStats(t, idx, "user", "tags", 2, 2)
+    
+    if err := client.BatchInsert(map[string]interface{}{"user:1": doc, "user:2": doc}); err != nil {
+        t.Fatalf("BatchInsert: %v", err)
+    }
+    assertStats(t, idx, "user", "email", 2, 1)
+    assertStats(t, idx, "user", "tags", 4, 2)
+    
+    for i := 0; i < 2; i++ {
+        if err := client.Delete("user:2"); err != nil {
+            t.Fatalf("Delete: %v", err)
+        }
+    }
+    assertStats(t, idx, "user", "email", 1, 1)
+    assertStats(t, idx, "user", "tags", 2, 2)
+}
+
+// Une entrée absente du disque n'est pas décomptée quand son document est
+// réécrit: les compteurs suivent les entrées réellement présentes
+func TestPutCountsOnlyExistingEntries(t *testing.T) {
+    client := newTestClient(t)
+    idx := NewIndexer(client.GetDB())
+    
+    // Document écrit avant la déclaration de l'index: aucune entrée
+    if err := client.Put("user:1", map[string]interface{}{"email": "a@x"}); err != nil {
+        t.Fatalf("Put: %v", err)
+    }
+    if err := idx.DefineIndex(IndexDef{RecordType: "user", Field: "email"}); err != nil {
+        t.Fatalf("DefineIndex: %v", err)
+    }
+    if err := idx.RefreshStats("user", "email"); err != nil {
+        t.Fatalf("RefreshStats: %v", err)
+    }
+    
+    if err := client.Put("user:2", map[string]interface{}{"email": "a@x"}); err != nil {
+        t.Fatalf("Put: %v", err)
+    }
+    if err := client.Put("user:1", map[string]interface{}{"email": "a@x"}); err != nil {
+        t.Fatalf("Put: %v", err)
+    }
+    assertStats(t, idx, "user", "email", 2, 1)
+    
+    if n := countKeys(t, client, indexPrefix("user", "email")); n != 2 {
+        t.Errorf("entrées d'index: %d, attendu 2", n)
+    }
+}
+// Un lot qui échange ou déplace une valeur unique entre deux clés est accepté
+// quel que soit l'ordre des clés; un vrai doublon reste refusé
+func TestBatchInsertMovesUniqueValue(t *testing.T) {
+    client := newTestClient(t)
+    idx := NewIndexer(client.GetDB())
+    
+    if _, err := idx.RebuildIndex(IndexDef{RecordType: "user", Field: "email", Unique: true}); err != nil {
+        t.Fatalf("RebuildIndex: %v", err)
+    }
+    
+    user := func(email string) map[string]interface{} {
+        return map[string]interface{}{"email": email}
+    }
+    if err := client.BatchInsert(map[string]interface{}{"user:1": user("a@x"), "user:9": user("b@x")}); err != nil {
+        t.Fatalf("BatchInsert: %v", err)
+    }
+    
+    // Échange: chaque clé prend la valeur de l'autre
+    if err := client.BatchInsert(map[string]interface{}{"user:1": user("b@x"), "user:9": user("a@x")}); err != nil {
+        t.Fatalf("échange: %v", err)
+    }
+    
+    // Déplacement vers une clé rangée avant la clé qui libère la valeur
+    if err := client.BatchInsert(map[string]interface{}{"user:1": user("a@x"), "user:9": user("c@x")}); err != nil {
+        t.Fatalf("déplacement: %v", err)
+    }
+    
+    for value, want := range map[string]string{"a@x": "user:1", "c@x": "user:9"} {
+        keys, err := idx.SearchByIndex("user", "email", value)
+        if err != nil || len(keys) != 1 || keys[0] != want {
+            t.Errorf("SearchByIndex %s: %v, %v (attendu %s)", value, keys, err, want)
+        }
+    }
+    if keys, _ := idx.SearchByIndex("user", "email", "b@x"); len(keys) != 0 {
+        t.Errorf("SearchByIndex b@x: %v, attendu aucun résultat", keys)
+    }
+    assertStats(t, idx, "user", "email", 2, 2)
+    
+    err := client.BatchInsert(map[string]interface{}{"user:1": user("d@x"), "user:5": user("d@x")})
+    if _, ok := err.(*ConstraintError); !ok {
+        t.Fatalf("doublon dans le lot: ConstraintError attendue, obtenu %v", err)
+    }
+    err = client.BatchInsert(map[string]interface{}{"user:5": user("c@x")})
+    if _, ok := err.(*ConstraintError); !ok {
+        t.Fatalf("doublon avec le disque: ConstraintError attendue, obtenu %v", err)
+    }
+}
+// Les index présents sur disque sans définition sont signalés par l'aperçu
+// d'écriture: le Client ne les met pas à jour
+func TestPreviewReportsUnmaintainedIndexes(t *testing.T) {
+    client := newTestClient(t)
+    idx := NewIndexer(client.GetDB())
+    
+    if err := client.Put("user:1", map[string]interface{}{"email": "a@x", "state": "sp"}); err != nil {
+        t.Fatalf("Put: %v", err)
+    }
+    if _, err := idx.RebuildIndex(IndexDef{RecordType: "user", Field: "email"}); err != nil {
+        t.Fatalf("RebuildIndex: %v", err)
+    }
+    for _, field := range []string{"region", "state"} {
+        if err := idx.CreateIndex("user", field, "x", "user:1"); err != nil {
+            t.Fatalf("CreateIndex %s: %v", field, err)
+        }
+    }
+    if err := idx.CreateIndex("users", "zone", "x", "users:1"); err != nil {
+        t.Fatalf("CreateIndex: %v", err)
+    }
+    
+    preview, err := client.PreviewPut("user:1", map[string]interface{}{"email": "b@x"})
+    if err != nil {
+        t.Fatalf("PreviewPut: %v", err)
+    }
+    if got := fmt.Sprint(preview.Unmaintained); got != "[region state]" {
+        t.Errorf("index non maintenus: %s, attendu [region state]", got)
+    }
+    
+    if names, err := idx.UnmaintainedIndexes("order"); err != nil || len(names) != 0 {
+        t.Errorf("UnmaintainedIndexes sans index: %v, %v", names, err)
+    }
+}
+// Une valeur refusée par le normaliseur d'un index composite n'est indexée ni
+// par le Client ni par CreateCompositeIndex, et ne trouve aucun document
+func TestCompositeRejectedValueNotIndexed(t *testing.T) {
+    client := newTestClient(t)
+    idx := NewIndexer(client.GetDB())
+    fields := []string{"size", "weight"}
+    
+    if err := idx.DefineIndex(IndexDef{RecordType: "item", Kind: IndexKindComposite, Fields: fields, Normalizer: "numeric"}); err != nil {
+        t.Fatalf("DefineIndex: %v", err)
+    }
+    if err := client.Put("item:1", map[string]interface{}{"size": "2", "weight": "lourd"}); err != nil {
+        t.Fatalf("Put: %v", err)
+    }
+    if err := client.Put("item:2", map[string]interface{}{"size": "2", "weight": "10"}); err != nil {
+        t.Fatalf("Put: %v", err)
+    }
+    if err := idx.CreateCompositeIndex("item", fields, []string{"2", "lourd"}, "item:3"); err != nil {
+        t.Fatalf("CreateCompositeIndex: %v", err)
+    }
+    
+    if n := countKeys(t, client, indexPrefix("item", compositeIndexName(fields))); n != 1 {
+        t.Errorf("entrées d'index: %d, attendu 1", n)
+    }
+    
+    tests := []struct {
+        values []string
+        want   int
+    }{
+        {[]string{"2"}, 1},
+        {[]string{"2", "10"}, 1},
+        {[]string{"2", "lourd"}, 0},
+        {[]string{"grand"}, 0},
+    }
+    for _, tt := range tests {
+        keys, err := idx.SearchByCompositeIndex("item", fields, tt.values)
+        if err != nil {
+            t.Fatalf("SearchByCompositeIndex(%v): %v", tt.values, err)
+        }
+        if len(keys) != tt.want {
+            t.Errorf("SearchByCompositeIndex(%v) = %v, attendu %d résultat(s)", tt.values, keys, tt.want)
+        }
+    }
+    
+    if _, err := idx.SearchCompositeRange("item", fields, []string{"2"}, "léger", ""); err == nil {
+        t.Error("borne refusée par le normaliseur acceptée")
+    }
+}
\ No newline at end of file
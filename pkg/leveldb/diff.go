@@ -0,0 +1,102 @@
+// pkg/leveldb/diff.go
+// Différence champ par champ entre deux versions d'un document JSON
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "sort"
+    "strconv"
+)
+
+// FieldDiff est une différence entre deux versions d'un document. Path suit la
+// syntaxe des chemins d'index (address.city, items[0].price); il est vide quand
+// le document n'est pas un objet et diffère en entier.
+type FieldDiff struct {
+    Path string      `json:"path"`
+    Op   string      `json:"op"` // "added", "removed" ou "changed"
+    From interface{} `json:"from,omitempty"`
+    To   interface{} `json:"to,omitempty"`
+}
+
+// DiffJSON compare deux documents JSON. Les objets sont comparés champ par
+// champ (dans l'ordre des noms), les tableaux élément par élément; les autres
+// valeurs sont comparées après décodage (1 et 1.0 sont égaux).
+func DiffJSON(from, to []byte) ([]FieldDiff, error) {
+    var a, b interface{}
+    if err := json.Unmarshal(from, &a); err != nil {
+        return nil, fmt.Errorf("document source invalide: %v", err)
+    }
+    if err := json.Unmarshal(to, &b); err != nil {
+        return nil, fmt.Errorf("document cible invalide: %v", err)
+    }
+    
+    var diffs []FieldDiff
+    diffValues("", a, b, &diffs)
+    return diffs, nil
+}
+
+func diffValues(path string, a, b interface{}, diffs *[]FieldDiff) {
+    switch av := a.(type) {
+    case map[string]interface{}:
+        if bv, ok := b.(map[string]interface{}); ok {
+            diffObjects(path, av, bv, diffs)
+            return
+        }
+    case []interface{}:
+        if bv, ok := b.([]interface{}); ok {
+            diffArrays(path, av, bv, diffs)
+            return
+        }
+    }
+    
+    if !reflect.DeepEqual(a, b) {
+        *diffs = append(*diffs, FieldDiff{Path: path, Op: "changed", From: a, To: b})
+    }
+}
+
+func diffObjects(path string, a, b map[string]interface{}, diffs *[]FieldDiff) {
+    names := make([]string, 0, len(a)+len(b))
+    for name := range a {
+        names = append(names, name)
+    }
+    for name := range b {
+        if _, ok := a[name]; !ok {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    
+    for _, name := range names {
+        child := name
+        if path != "" {
+            child = path + "." + name
+        }
+        av, inA := a[name]
+        bv, inB := b[name]
+        switch {
+        case !inA:
+            *diffs = append(*diffs, FieldDiff{Path: child, Op: "added", To: bv})
+        case !inB:
+            *diffs = append(*diffs, FieldDiff{Path: child, Op: "removed", From: av})
+        default:
+            diffValues(child, av, bv, diffs)
+        }
+    }
+}
+
+func diffArrays(path string, a, b []interface{}, diffs *[]FieldDiff) {
+    for i := 0; i < len(a) || i < len(b); i++ {
+        child := path + "[" + strconv.Itoa(i) + "]"
+        switch {
+        case i >= len(a):
+            *diffs = append(*diffs, FieldDiff{Path: child, Op: "added", To: b[i]})
+        case i >= len(b):
+            *diffs = append(*diffs, FieldDiff{Path: child, Op: "removed", From: a[i]})
+        default:
+            diffValues(child, a[i], b[i], diffs)
+        }
+    }
+}
\ No newline at end of file
@@ -0,0 +1,196 @@
+// pkg/leveldb/explain.go
+// Plans d'exécution mesurés: chemin d'accès, lignes estimées et réelles, pas d'itérateur, lectures et temps
+
+package leveldb
+
+import (
+    "fmt"
+    "strconv"
+    "time"
+)
+
+// PlanStep est un nœud du plan d'exécution. Les opérations sont celles du
+// planificateur (INDEX, RANGE, UNION, INTERSECT, FILTER, SCAN) et des étapes
+// qui le suivent (FETCH, SORT, LIMIT, COUNT).
+//
+// Steps compte les déplacements d'itérateur faits par le nœud lui-même, Reads
+// ses lectures ponctuelles (Get/Has). Duration inclut le temps des enfants.
+type PlanStep struct {
+    Op       string        `json:"op"`
+    Detail   string        `json:"detail,omitempty"`
+    Estimate int           `json:"estimate"` // -1: pas d'estimation (parcours complet)
+    Rows     int           `json:"rows"`
+    Steps    int           `json:"steps"`
+    Reads    int           `json:"reads"`
+    Duration time.Duration `json:"duration_ns"`
+    Children []*PlanStep   `json:"children,omitempty"`
+}
+
+// Walk appelle fn pour le nœud puis ses descendants, en profondeur d'abord
+func (s *PlanStep) Walk(fn func(step *PlanStep, depth int)) {
+    s.walk(fn, 0)
+}
+
+func (s *PlanStep) walk(fn func(step *PlanStep, depth int), depth int) {
+    fn(s, depth)
+    for _, child := range s.Children {
+        child.walk(fn, depth+1)
+    }
+}
+
+// Stage est une phase chronométrée de l'exécution (planification, exécution, tri...)
+type Stage struct {
+    Name     string        `json:"name"`
+    Duration time.Duration `json:"duration_ns"`
+}
+
+// Explain décrit l'exécution mesurée d'une requête
+type Explain struct {
+    Statement string    `json:"statement"`
+    Plan      *PlanStep `json:"plan"`
+    Stages    []Stage   `json:"stages"`
+    Rows      int       `json:"rows"`
+}
+
+// AddStage ajoute une phase chronométrée (l'appelant peut compléter le plan,
+// par exemple avec la lecture des documents)
+func (e *Explain) AddStage(name string, d time.Duration) {
+    e.Stages = append(e.Stages, Stage{Name: name, Duration: d})
+}
+
+// tracedStream mesure les lignes produites par un flux et le temps passé dans
+// Next/Seek. Une clé retournée plusieurs fois (Seek sans déplacement) n'est
+// comptée qu'une fois: les clés d'un flux sont croissantes.
+type tracedStream struct {
+    inner keyStream
+    step  *PlanStep
+    last  string
+    seen  bool
+}
+
+func (s *tracedStream) Next() bool {
+    start := time.Now()
+    ok := s.inner.Next()
+    s.record(ok, start)
+    return ok
+}
+
+func (s *tracedStream) Seek(target string) bool {
+    start := time.Now()
+    ok := s.inner.Seek(target)
+    s.record(ok, start)
+    return ok
+}
+
+func (s *tracedStream) record(ok bool, start time.Time) {
+    s.step.Duration += time.Since(start)
+    if !ok {
+        return
+    }
+    if key := s.inner.Key(); !s.seen || key != s.last {
+        s.step.Rows++
+        s.last, s.seen = key, true
+    }
+}
+
+func (s *tracedStream) Key() string  { return s.inner.Key() }
+func (s *tracedStream) Error() error { return s.inner.Error() }
+func (s *tracedStream) Release()     { s.inner.Release() }
+
+// planStep retourne le plan du curseur, sous un nœud LIMIT s'il est borné
+func (c *Cursor) planStep() *PlanStep {
+    if c.limit <= 0 {
+        return c.step
+    }
+    
+    estimate := c.limit
+    if c.step.Estimate >= 0 && c.step.Estimate < estimate {
+        estimate = c.step.Estimate
+    }
+    return &PlanStep{
+        Op:       "LIMIT",
+        Detail:   strconv.Itoa(c.limit),
+        Estimate: estimate,
+        Rows:     c.count,
+        Duration: c.step.Duration,
+        Children: []*PlanStep{c.step},
+    }
+}
+
+// ExplainSQL exécute une requête en mesurant chaque nœud du plan
+func (idx *Indexer) ExplainSQL(src string) (*SQLResult, *Explain, error) {
+    q, err := ParseSQL(src)
+    if err != nil {
+        return nil, nil, err
+    }
+    
+    result, explain, err := idx.runSQL(q, true)
+    if err != nil {
+        return nil, nil, err
+    }
+    explain.Statement = src
+    return result, explain, nil
+}
+
+// ExplainSearch exécute une recherche par index (une page, comme SearchPage)
+// et décrit le parcours: index de la valeur, ou index de tri filtré par
+// appartenance à l'index recherché quand opts.OrderBy est un autre champ
+func (idx *Indexer) ExplainSearch(recordType, field, value string, opts SearchOptions) (*SearchPage, *Explain, error) {
+    explain := &Explain{Statement: fmt.Sprintf("%s.%s = %s", recordType, field, value)}
+    
+    start := time.Now()
+    normalizedValue := idx.searchValue(recordType, field, value)
+    estimate := idx.estimateValue(recordType, field, normalizedValue)
+    explain.AddStage("estimation", time.Since(start))
+    
+    start = time.Now()
+    page, it, err := idx.searchPage(recordType, field, value, opts)
+    if err != nil {
+        return nil, nil, err
+    }
+    elapsed := time.Since(start)
+    explain.AddStage("exécution", elapsed)
+    
+    // Une entrée de plus est lue pour savoir s'il reste une page
+    rows := len(page.Keys)
+    if page.Next != "" {
+        rows++
+    }
+    
+    scan := &PlanStep{
+        Op:       "INDEX",
+        Detail:   fmt.Sprintf("%s.%s=%s", recordType, field, normalizedValue),
+        Estimate: estimate,
+        Rows:     rows,
+        Steps:    it.steps,
+        Reads:    it.reads,
+        Duration: elapsed,
+    }
+    if it.filterPrefix != "" {
+        scan.Op = "INDEX ORDER"
+        scan.Detail = fmt.Sprintf("%s.%s, appartenance à %s.%s=%s", recordType, it.orderBy, recordType, field, normalizedValue)
+    }
+    if opts.Desc {
+        scan.Detail += " (décroissant)"
+    }
+    
+    pageSize := opts.PageSize
+    if pageSize <= 0 {
+        pageSize = defaultPageSize
+    }
+    limitEstimate := pageSize
+    if estimate < limitEstimate {
+        limitEstimate = estimate
+    }
+    explain.Plan = &PlanStep{
+        Op:       "LIMIT",
+        Detail:   strconv.Itoa(pageSize),
+        Estimate: limitEstimate,
+        Rows:     len(page.Keys),
+        Duration: elapsed,
+        Children: []*PlanStep{scan},
+    }
+    explain.Rows = len(page.Keys)
+    
+    return page, explain, nil
+}
\ No newline at end of file
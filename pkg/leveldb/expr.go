@@ -0,0 +1,550 @@
+// pkg/leveldb/expr.go
+// Petit langage d'expressions des index calculés: arithmétique, fonctions de chaînes et de dates
+
+package leveldb
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Une expression est évaluée sur un document et produit un nombre (float64), une
+// chaîne, ou nil si un champ manque ou n'a pas le bon type. Exemples:
+//
+//     length_cm * height_cm * width_cm
+//     lower(trim(city))
+//     date_trunc('month', order_purchase_timestamp)
+//     round(price / 100, 2)
+type exprNode interface {
+    eval(data map[string]interface{}) interface{}
+}
+
+type (
+    numberNode float64
+    stringNode string
+    fieldNode  string
+    
+    unaryNode struct {
+        arg exprNode
+    }
+    
+    binaryNode struct {
+        op          byte
+        left, right exprNode
+    }
+    
+    callNode struct {
+        name string
+        fn   exprFunc
+        args []exprNode
+    }
+)
+
+type exprFunc func(args []interface{}) interface{}
+
+// Type du résultat d'une expression, connu à l'analyse
+const (
+    exprKindAny    = ""       // dépend du document (champ seul)
+    exprKindNumber = "number" // arithmétique, length, round, year...
+    exprKindString = "string" // lower, substr, concat, date_trunc...
+)
+
+// Fonctions dont le résultat est une chaîne (les autres retournent un nombre)
+var exprStringFuncs = map[string]bool{
+    "lower": true, "upper": true, "trim": true, "substr": true, "concat": true, "date_trunc": true,
+}
+
+// exprResultKind retourne le type du résultat d'une expression compilée
+func exprResultKind(node exprNode) string {
+    switch n := node.(type) {
+    case numberNode, *unaryNode, *binaryNode:
+        return exprKindNumber
+    case stringNode:
+        return exprKindString
+    case *callNode:
+        if exprStringFuncs[n.name] {
+            return exprKindString
+        }
+        return exprKindNumber
+    }
+    return exprKindAny
+}
+
+// exprFuncs liste les fonctions disponibles et leur nombre d'arguments (min, max; -1 = illimité)
+var exprFuncs = map[string]struct {
+    min, max int
+    fn       exprFunc
+}{
+    "lower":      {1, 1, func(a []interface{}) interface{} { return mapString(a[0], strings.ToLower) }},
+    "upper":      {1, 1, func(a []interface{}) interface{} { return mapString(a[0], strings.ToUpper) }},
+    "trim":       {1, 1, func(a []interface{}) interface{} { return mapString(a[0], strings.TrimSpace) }},
+    "length":     {1, 1, exprLength},
+    "substr":     {2, 3, exprSubstr},
+    "concat":     {1, -1, exprConcat},
+    "abs":        {1, 1, func(a []interface{}) interface{} { return mapFloat(a[0], math.Abs) }},
+    "floor":      {1, 1, func(a []interface{}) interface{} { return mapFloat(a[0], math.Floor) }},
+    "ceil":       {1, 1, func(a []interface{}) interface{} { return mapFloat(a[0], math.Ceil) }},
+    "round":      {1, 2, exprRound},
+    "year":       {1, 1, func(a []interface{}) interface{} { return datePart(a[0], "year") }},
+    "month":      {1, 1, func(a []interface{}) interface{} { return datePart(a[0], "month") }},
+    "day":        {1, 1, func(a []interface{}) interface{} { return datePart(a[0], "day") }},
+    "date_trunc": {2, 2, exprDateTrunc},
+}
+
+// Formats de date reconnus dans les documents
+var exprDateLayouts = []string{
+    time.RFC3339,
+    "2006-01-02 15:04:05",
+    "2006-01-02T15:04:05",
+    "2006-01-02",
+}
+
+var compiledExprs sync.Map // texte → exprNode
+
+// compileExpr analyse une expression (résultat mis en cache)
+func compileExpr(src string) (exprNode, error) {
+    if node, ok := compiledExprs.Load(src); ok {
+        return node.(exprNode), nil
+    }
+    
+    p := &exprParser{src: src}
+    node, err := p.parseExpr()
+    if err == nil && p.peek() != 0 {
+        err = p.errorf("caractère inattendu %q", p.peek())
+    }
+    if err != nil {
+        return nil, err
+    }
+    
+    compiledExprs.Store(src, node)
+    return node, nil
+}
+
+// EvalExpr évalue une expression sur un document (nil si non calculable)
+func EvalExpr(src string, data map[string]interface{}) (interface{}, error) {
+    node, err := compileExpr(src)
+    if err != nil {
+        return nil, err
+    }
+    return node.eval(data), nil
+}
+
+// exprParser est un analyseur descendant récursif:
+//
+//     expr  := term (('+' | '-') term)*
+//     term  := unary (('*' | '/' | '%') unary)*
+//     unary := '-' unary | primary
+//     primary := nombre | 'chaîne' | champ | fonction '(' expr (',' expr)* ')' | '(' expr ')'
+type exprParser struct {
+    src string
+    pos int
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+    return fmt.Errorf("expression invalide (position %d): %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) peek() byte {
+    for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+        p.pos++
+    }
+    if p.pos >= len(p.src) {
+        return 0
+    }
+    return p.src[p.pos]
+}
+
+func (p *exprParser) parseExpr() (exprNode, error) {
+    left, err := p.parseTerm()
+    if err != nil {
+        return nil, err
+    }
+    
+    for {
+        op := p.peek()
+        if op != '+' && op != '-' {
+            return left, nil
+        }
+        p.pos++
+        
+        right, err := p.parseTerm()
+        if err != nil {
+            return nil, err
+        }
+        left = &binaryNode{op: op, left: left, right: right}
+    }
+}
+
+func (p *exprParser) parseTerm() (exprNode, error) {
+    left, err := p.parseUnary()
+    if err != nil {
+        return nil, err
+    }
+    
+    for {
+        op := p.peek()
+        if op != '*' && op != '/' && op != '%' {
+            return left, nil
+        }
+        p.pos++
+        
+        right, err := p.parseUnary()
+        if err != nil {
+            return nil, err
+        }
+        left = &binaryNode{op: op, left: left, right: right}
+    }
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+    if p.peek() == '-' {
+        p.pos++
+        arg, err := p.parseUnary()
+        if err != nil {
+            return nil, err
+        }
+        return &unaryNode{arg: arg}, nil
+    }
+    return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+    c := p.peek()
+    
+    switch {
+    case c == 0:
+        return nil, p.errorf("fin d'expression inattendue")
+    
+    case c == '(':
+        p.pos++
+        node, err := p.parseExpr()
+        if err != nil {
+            return nil, err
+        }
+        if p.peek() != ')' {
+            return nil, p.errorf("')' attendu")
+        }
+        p.pos++
+        return node, nil
+    
+    case c == '\'':
+        end := strings.IndexByte(p.src[p.pos+1:], '\'')
+        if end < 0 {
+            return nil, p.errorf("chaîne non terminée")
+        }
+        s := p.src[p.pos+1 : p.pos+1+end]
+        p.pos += end + 2
+        return stringNode(s), nil
+    
+    case c >= '0' && c <= '9' || c == '.':
+        start := p.pos
+        for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+            p.pos++
+        }
+        n, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+        if err != nil {
+            return nil, p.errorf("nombre invalide %q", p.src[start:p.pos])
+        }
+        return numberNode(n), nil
+    
+    case isIdentByte(c):
+        name := p.parseIdent()
+        if p.peek() != '(' {
+            if _, err := parsePath(name); isJSONPath(name) && err != nil {
+                return nil, err
+            }
+            return fieldNode(name), nil
+        }
+        return p.parseCall(name)
+    }
+    
+    return nil, p.errorf("caractère inattendu %q", c)
+}
+
+// parseIdent lit un nom de champ ou un chemin JSON (address.city, items[0].price)
+func (p *exprParser) parseIdent() string {
+    start := p.pos
+    for p.pos < len(p.src) {
+        c := p.src[p.pos]
+        switch {
+        case isIdentByte(c) || c >= '0' && c <= '9' || c == '.':
+            p.pos++
+        case c == '[':
+            end := strings.IndexByte(p.src[p.pos:], ']')
+            if end < 0 {
+                return p.src[start:]
+            }
+            p.pos += end + 1
+        default:
+            return p.src[start:p.pos]
+        }
+    }
+    return p.src[start:p.pos]
+}
+
+func (p *exprParser) parseCall(name string) (exprNode, error) {
+    spec, ok := exprFuncs[strings.ToLower(name)]
+    if !ok {
+        return nil, p.errorf("fonction inconnue %s", name)
+    }
+    p.pos++ // '('
+    
+    var args []exprNode
+    if p.peek() == ')' {
+        p.pos++
+    } else {
+        for {
+            arg, err := p.parseExpr()
+            if err != nil {
+                return nil, err
+            }
+            args = append(args, arg)
+            
+            c := p.peek()
+            p.pos++
+            if c == ')' {
+                break
+            }
+            if c != ',' {
+                return nil, p.errorf("',' ou ')' attendu dans l'appel de %s", name)
+            }
+        }
+    }
+    
+    if len(args) < spec.min || spec.max >= 0 && len(args) > spec.max {
+        return nil, p.errorf("nombre d'arguments invalide pour %s: %d", name, len(args))
+    }
+    return &callNode{name: name, fn: spec.fn, args: args}, nil
+}
+
+func isIdentByte(c byte) bool {
+    return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func (n numberNode) eval(map[string]interface{}) interface{} {
+    return float64(n)
+}
+
+func (n stringNode) eval(map[string]interface{}) interface{} {
+    return string(n)
+}
+
+// Un champ absent, nul, objet ou tableau vaut nil
+func (n fieldNode) eval(data map[string]interface{}) interface{} {
+    var value interface{}
+    if isJSONPath(string(n)) {
+        steps, _ := parsePath(string(n))
+        values := evalPath(data, steps)
+        if len(values) == 0 {
+            return nil
+        }
+        value = values[0]
+    } else {
+        value = data[string(n)]
+    }
+    
+    switch value.(type) {
+    case string, float64:
+        return value
+    case bool:
+        return strconv.FormatBool(value.(bool))
+    }
+    return nil
+}
+
+func (n *unaryNode) eval(data map[string]interface{}) interface{} {
+    return mapFloat(n.arg.eval(data), func(f float64) float64 { return -f })
+}
+
+// Les opérateurs sont numériques: les chaînes numériques ("225") sont converties
+func (n *binaryNode) eval(data map[string]interface{}) interface{} {
+    a, ok := toFloat(n.left.eval(data))
+    if !ok {
+        return nil
+    }
+    b, ok := toFloat(n.right.eval(data))
+    if !ok {
+        return nil
+    }
+    
+    switch n.op {
+    case '+':
+        return a + b
+    case '-':
+        return a - b
+    case '*':
+        return a * b
+    case '/':
+        if b == 0 {
+            return nil
+        }
+        return a / b
+    case '%':
+        if b == 0 {
+            return nil
+        }
+        return math.Mod(a, b)
+    }
+    return nil
+}
+
+func (n *callNode) eval(data map[string]interface{}) interface{} {
+    args := make([]interface{}, len(n.args))
+    for i, arg := range n.args {
+        args[i] = arg.eval(data)
+    }
+    return n.fn(args)
+}
+
+func mapString(v interface{}, fn func(string) string) interface{} {
+    s, ok := exprString(v)
+    if !ok {
+        return nil
+    }
+    return fn(s)
+}
+
+func mapFloat(v interface{}, fn func(float64) float64) interface{} {
+    f, ok := toFloat(v)
+    if !ok {
+        return nil
+    }
+    return fn(f)
+}
+
+// exprString convertit un résultat en chaîne (les nombres sans exposant)
+func exprString(v interface{}) (string, bool) {
+    switch v := v.(type) {
+    case string:
+        return v, true
+    case float64:
+        return strconv.FormatFloat(v, 'f', -1, 64), true
+    }
+    return "", false
+}
+
+func exprLength(a []interface{}) interface{} {
+    s, ok := exprString(a[0])
+    if !ok {
+        return nil
+    }
+    return float64(len([]rune(s)))
+}
+
+// substr(s, début, longueur): début compte à partir de 1, comme en SQL
+func exprSubstr(a []interface{}) interface{} {
+    s, ok := exprString(a[0])
+    start, ok2 := toFloat(a[1])
+    if !ok || !ok2 {
+        return nil
+    }
+    
+    runes := []rune(s)
+    from := int(start) - 1
+    if from < 0 {
+        from = 0
+    }
+    if from > len(runes) {
+        return ""
+    }
+    
+    to := len(runes)
+    if len(a) == 3 {
+        n, ok := toFloat(a[2])
+        if !ok {
+            return nil
+        }
+        if from+int(n) < to {
+            to = from + int(n)
+        }
+    }
+    if to < from {
+        return ""
+    }
+    return string(runes[from:to])
+}
+
+func exprConcat(a []interface{}) interface{} {
+    var b strings.Builder
+    for _, v := range a {
+        s, ok := exprString(v)
+        if !ok {
+            return nil
+        }
+        b.WriteString(s)
+    }
+    return b.String()
+}
+
+func exprRound(a []interface{}) interface{} {
+    f, ok := toFloat(a[0])
+    if !ok {
+        return nil
+    }
+    
+    digits := 0.0
+    if len(a) == 2 {
+        if digits, ok = toFloat(a[1]); !ok {
+            return nil
+        }
+    }
+    
+    scale := math.Pow(10, digits)
+    return math.Round(f*scale) / scale
+}
+
+func parseExprDate(v interface{}) (time.Time, bool) {
+    s, ok := v.(string)
+    if !ok {
+        return time.Time{}, false
+    }
+    s = strings.TrimSpace(s)
+    
+    for _, layout := range exprDateLayouts {
+        if t, err := time.Parse(layout, s); err == nil {
+            return t, true
+        }
+    }
+    return time.Time{}, false
+}
+
+func datePart(v interface{}, part string) interface{} {
+    t, ok := parseExprDate(v)
+    if !ok {
+        return nil
+    }
+    
+    switch part {
+    case "year":
+        return float64(t.Year())
+    case "month":
+        return float64(t.Month())
+    }
+    return float64(t.Day())
+}
+
+// date_trunc('year'|'month'|'day'|'hour', date) retourne la date tronquée sous
+// forme de chaîne triable: 2017, 2017-10, 2017-10-02, 2017-10-02 10
+func exprDateTrunc(a []interface{}) interface{} {
+    unit, ok := a[0].(string)
+    t, ok2 := parseExprDate(a[1])
+    if !ok || !ok2 {
+        return nil
+    }
+    
+    switch strings.ToLower(unit) {
+    case "year":
+        return t.Format("2006")
+    case "month":
+        return t.Format("2006-01")
+    case "day":
+        return t.Format("2006-01-02")
+    case "hour":
+        return t.Format("2006-01-02 15")
+    }
+    return nil
+}
\ No newline at end of file
@@ -0,0 +1,149 @@
+// pkg/leveldb/expr_test.go
+// Tests de l'évaluation des expressions et de l'encodage des index calculés
+
+package leveldb
+
+import (
+    "testing"
+)
+
+var exprTestData = map[string]interface{}{
+    "length_cm": 2.0,
+    "height_cm": 5.0,
+    "width_cm":  6.0,
+    "a":         7.0,
+    "price":     12345.0,
+    "qty":       "4",
+    "city":      "  Campinas ",
+    "state":     "SP",
+    "zip":       "01310-100",
+    "ts":        "2017-10-02 10:56:38",
+}
+
+func TestEvalExpr(t *testing.T) {
+    cases := []struct {
+        expr string
+        want interface{}
+    }{
+        {"length_cm * height_cm * width_cm", 60.0},
+        {"a + height_cm * 2", 17.0},
+        {"(a + height_cm) * 2", 24.0},
+        {"a - height_cm - 1", 1.0},
+        {"-a + 10", 3.0},
+        {"a % 3", 1.0},
+        {"a / 2", 3.5},
+        {"a / 0", nil},
+        {"qty * 2", 8.0},
+        {"missing", nil},
+        {"missing + 1", nil},
+        {"lower(trim(city))", "campinas"},
+        {"upper(state)", "SP"},
+        {"substr(zip, 1, 2)", "01"},
+        {"concat(state, '-', lower(trim(city)))", "SP-campinas"},
+        {"length('été')", 3.0},
+        {"length(state)", 2.0},
+        {"abs(height_cm - a)", 2.0},
+        {"floor(a / 2)", 3.0},
+        {"ceil(a / 2)", 4.0},
+        {"round(price / 100, 2)", 123.45},
+        {"year(ts)", 2017.0},
+        {"month(ts)", 10.0},
+        {"day(ts)", 2.0},
+        {"date_trunc('year', ts)", "2017"},
+        {"date_trunc('month', ts)", "2017-10"},
+        {"date_trunc('day', ts)", "2017-10-02"},
+        {"date_trunc('hour', ts)", "2017-10-02 10"},
+        {"year(city)", nil},
+    }
+    
+    for _, c := range cases {
+        got, err := EvalExpr(c.expr, exprTestData)
+        if err != nil {
+            t.Errorf("EvalExpr(%q): %v", c.expr, err)
+            continue
+        }
+        if got != c.want {
+            t.Errorf("EvalExpr(%q) = %#v, attendu %#v", c.expr, got, c.want)
+        }
+    }
+}
+
+func TestCompileExprErrors(t *testing.T) {
+    for _, src := range []string{"", "a +", "(a + 1", "a b", "foo(a)", "lower()", "lower(a, b)", "'abc"} {
+        if _, err := compileExpr(src); err == nil {
+            t.Errorf("compileExpr(%q) devrait échouer", src)
+        }
+    }
+}
+
+func TestExprResultKind(t *testing.T) {
+    cases := []struct {
+        expr string
+        want string
+    }{
+        {"price", exprKindAny},
+        {"price / 100", exprKindNumber},
+        {"-price", exprKindNumber},
+        {"42", exprKindNumber},
+        {"'sp'", exprKindString},
+        {"length(city)", exprKindNumber},
+        {"year(ts)", exprKindNumber},
+        {"lower(city)", exprKindString},
+        {"substr(zip, 1, 2)", exprKindString},
+        {"date_trunc('month', ts)", exprKindString},
+    }
+    
+    for _, c := range cases {
+        node, err := compileExpr(c.expr)
+        if err != nil {
+            t.Errorf("compileExpr(%q): %v", c.expr, err)
+            continue
+        }
+        if got := exprResultKind(node); got != c.want {
+            t.Errorf("exprResultKind(%q) = %q, attendu %q", c.expr, got, c.want)
+        }
+    }
+}
+
+// La valeur indexée à l'écriture doit être celle que produit la recherche
+// de la même valeur saisie en texte
+func TestExprIndexValueMatchesSearch(t *testing.T) {
+    cases := []struct {
+        expr   string
+        search string
+    }{
+        {"date_trunc('year', ts)", "2017"},
+        {"date_trunc('month', ts)", "2017-10"},
+        {"substr(zip, 1, 2)", "01"},
+        {"lower(trim(city))", "Campinas"},
+        {"length_cm * height_cm * width_cm", "60"},
+        {"year(ts)", "2017"},
+        {"price / 100", "123.45"},
+        {"qty", "4"},
+    }
+    
+    for _, c := range cases {
+        def := &IndexDef{RecordType: "product", Kind: IndexKindExpr, Expr: c.expr}
+        
+        stored, ok := def.exprIndexValue(exprTestData)
+        if !ok {
+            t.Errorf("%s: aucune valeur indexée", c.expr)
+            continue
+        }
+        searched, ok := def.exprEncode(c.search)
+        if !ok || searched != stored {
+            t.Errorf("%s: recherche %q encodée %q, valeur indexée %q", c.expr, c.search, searched, stored)
+        }
+    }
+    
+    // Un résultat numérique est rangé dans l'ordre numérique, pas textuel
+    def := &IndexDef{RecordType: "product", Kind: IndexKindExpr, Expr: "price / 100"}
+    nine, _ := def.exprEncode("9")
+    ten, _ := def.exprEncode("10")
+    if nine >= ten {
+        t.Errorf("price / 100: 9 (%q) devrait précéder 10 (%q)", nine, ten)
+    }
+    if _, ok := def.exprEncode("abc"); ok {
+        t.Errorf("price / 100: une recherche non numérique ne devrait pas être encodée")
+    }
+}
\ No newline at end of file
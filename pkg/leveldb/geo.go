@@ -0,0 +1,363 @@
+// pkg/leveldb/geo.go
+// Index géographique: geohash du centroïde des préfixes de code postal et recherches de proximité
+
+package leveldb
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "math"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+    geoZipPrefix     = "_geo:zip:"
+    geohashPrecision = 9 // ~5 m, largement suffisant pour des centroïdes
+    geohashBase32    = "0123456789bcdefghjkmnpqrstuvwxyz"
+    earthRadiusKm    = 6371.0
+)
+
+// ZipCentroid est le centroïde d'un préfixe de code postal
+type ZipCentroid struct {
+    Lat float64 `json:"lat"`
+    Lon float64 `json:"lon"`
+}
+
+// GeoHit est un document trouvé par une recherche géographique
+type GeoHit struct {
+    Key        string
+    Lat        float64
+    Lon        float64
+    DistanceKm float64
+}
+
+// normalizeZip supprime les zéros non significatifs (le CSV des vendeurs n'en
+// a pas toujours autant que celui de géolocalisation)
+func normalizeZip(zip string) string {
+    zip = strings.TrimLeft(strings.TrimSpace(zip), "0")
+    if zip == "" {
+        return "0"
+    }
+    return zip
+}
+
+// LoadZipCentroids lit un CSV de géolocalisation (format olist: préfixe, lat, lng;
+// plusieurs lignes par préfixe) et enregistre le centroïde de chaque préfixe.
+// Retourne le nombre de préfixes enregistrés.
+func (idx *Indexer) LoadZipCentroids(csvPath string) (int, error) {
+    file, err := os.Open(csvPath)
+    if err != nil {
+        return 0, fmt.Errorf("erreur ouverture %s: %v", csvPath, err)
+    }
+    defer file.Close()
+    
+    reader := csv.NewReader(file)
+    header, err := reader.Read()
+    if err != nil {
+        return 0, fmt.Errorf("erreur lecture en-tête: %v", err)
+    }
+    
+    column := func(names ...string) int {
+        for i, h := range header {
+            for _, name := range names {
+                if strings.TrimSpace(h) == name {
+                    return i
+                }
+            }
+        }
+        return -1
+    }
+    
+    zipCol := column("geolocation_zip_code_prefix", "zip_code_prefix")
+    latCol := column("geolocation_lat", "lat")
+    lonCol := column("geolocation_lng", "lng", "lon")
+    if zipCol < 0 || latCol < 0 || lonCol < 0 {
+        return 0, fmt.Errorf("colonnes préfixe/lat/lng introuvables dans %s", csvPath)
+    }
+    
+    type sum struct {
+        lat, lon float64
+        n        int
+    }
+    sums := make(map[string]*sum)
+    
+    for {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            continue
+        }
+        
+        lat, err1 := strconv.ParseFloat(record[latCol], 64)
+        lon, err2 := strconv.ParseFloat(record[lonCol], 64)
+        if err1 != nil || err2 != nil || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+            continue
+        }
+        
+        zip := normalizeZip(record[zipCol])
+        s, ok := sums[zip]
+        if !ok {
+            s = &sum{}
+            sums[zip] = s
+        }
+        s.lat += lat
+        s.lon += lon
+        s.n++
+    }
+    
+    batch := new(leveldb.Batch)
+    for zip, s := range sums {
+        centroid := ZipCentroid{Lat: s.lat / float64(s.n), Lon: s.lon / float64(s.n)}
+        centroidBytes, _ := json.Marshal(centroid)
+        batch.Put([]byte(geoZipPrefix+zip), centroidBytes)
+        
+        if batch.Len() >= rebuildBatchSize {
+            if err := idx.db.Write(batch, nil); err != nil {
+                return 0, fmt.Errorf("erreur écriture lot: %v", err)
+            }
+            batch.Reset()
+        }
+    }
+    
+    if err := idx.db.Write(batch, nil); err != nil {
+        return 0, fmt.Errorf("erreur écriture lot: %v", err)
+    }
+    
+    return len(sums), nil
+}
+
+// ZipCentroid retourne le centroïde enregistré pour un préfixe de code postal
+func (idx *Indexer) ZipCentroid(zip string) (*ZipCentroid, error) {
+    value, err := idx.db.Get([]byte(geoZipPrefix+normalizeZip(zip)), nil)
+    if err == leveldb.ErrNotFound {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    
+    var centroid ZipCentroid
+    if err := json.Unmarshal(value, &centroid); err != nil {
+        return nil, fmt.Errorf("centroïde invalide pour %s: %v", zip, err)
+    }
+    return &centroid, nil
+}
+
+// zipGeohash retourne le geohash du centroïde d'un préfixe (faux si inconnu)
+func (idx *Indexer) zipGeohash(zip string) (string, bool) {
+    centroid, err := idx.ZipCentroid(zip)
+    if err != nil || centroid == nil {
+        return "", false
+    }
+    return geohashEncode(centroid.Lat, centroid.Lon, geohashPrecision), true
+}
+
+// SearchNear retourne les documents de l'index géographique name situés à moins
+// de radiusKm du point (lat, lon), triés par distance croissante
+func (idx *Indexer) SearchNear(recordType, name string, lat, lon, radiusKm float64) ([]GeoHit, error) {
+    if radiusKm <= 0 {
+        return nil, fmt.Errorf("rayon invalide: %v", radiusKm)
+    }
+    
+    // Boîte englobante du cercle
+    dLat := radiusKm / earthRadiusKm * 180 / math.Pi
+    cosLat := math.Max(math.Cos(lat*math.Pi/180), 0.01)
+    dLon := math.Min(dLat/cosLat, 180)
+    
+    return idx.searchGeo(recordType, name, lat-dLat, lon-dLon, lat+dLat, lon+dLon, func(hit *GeoHit) bool {
+        hit.DistanceKm = haversineKm(lat, lon, hit.Lat, hit.Lon)
+        return hit.DistanceKm <= radiusKm
+    })
+}
+
+// SearchBox retourne les documents de l'index géographique name situés dans le
+// rectangle [minLat, maxLat] x [minLon, maxLon], triés par distance au centre
+func (idx *Indexer) SearchBox(recordType, name string, minLat, minLon, maxLat, maxLon float64) ([]GeoHit, error) {
+    if minLat > maxLat || minLon > maxLon {
+        return nil, fmt.Errorf("rectangle invalide")
+    }
+    
+    centerLat, centerLon := (minLat+maxLat)/2, (minLon+maxLon)/2
+    
+    return idx.searchGeo(recordType, name, minLat, minLon, maxLat, maxLon, func(hit *GeoHit) bool {
+        hit.DistanceKm = haversineKm(centerLat, centerLon, hit.Lat, hit.Lon)
+        return hit.Lat >= minLat && hit.Lat <= maxLat && hit.Lon >= minLon && hit.Lon <= maxLon
+    })
+}
+
+// searchGeo parcourt les cellules geohash couvrant le rectangle puis filtre
+// chaque entrée par la position décodée de son geohash
+func (idx *Indexer) searchGeo(recordType, name string, minLat, minLon, maxLat, maxLon float64, keep func(*GeoHit) bool) ([]GeoHit, error) {
+    def, err := idx.GetIndexDef(recordType, name)
+    if err != nil {
+        return nil, err
+    }
+    if def == nil || def.Kind != IndexKindGeo {
+        return nil, fmt.Errorf("index géographique %s:%s non déclaré", recordType, name)
+    }
+    
+    minLat, maxLat = math.Max(minLat, -90), math.Min(maxLat, 90)
+    minLon, maxLon = math.Max(minLon, -180), math.Min(maxLon, 180)
+    
+    var hits []GeoHit
+    for _, cell := range geohashCover(minLat, minLon, maxLat, maxLon) {
+        prefix := indexPrefix(recordType, name) + cell
+        iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+        
+        for iter.Next() {
+            primaryKey, _ := decodeIndexValue(iter.Value())
+            hash := strings.TrimSuffix(strings.TrimPrefix(string(iter.Key()), indexPrefix(recordType, name)), ":"+primaryKey)
+            
+            hitLat, hitLon := geohashDecode(hash)
+            hit := GeoHit{Key: primaryKey, Lat: hitLat, Lon: hitLon}
+            if keep(&hit) {
+                hits = append(hits, hit)
+            }
+        }
+        
+        err := iter.Error()
+        iter.Release()
+        if err != nil {
+            return nil, fmt.Errorf("erreur itération: %v", err)
+        }
+    }
+    
+    sort.Slice(hits, func(i, j int) bool {
+        if hits[i].DistanceKm != hits[j].DistanceKm {
+            return hits[i].DistanceKm < hits[j].DistanceKm
+        }
+        return hits[i].Key < hits[j].Key
+    })
+    
+    return hits, nil
+}
+
+// geohashEncode encode une position en geohash base32
+func geohashEncode(lat, lon float64, precision int) string {
+    latRange := [2]float64{-90, 90}
+    lonRange := [2]float64{-180, 180}
+    
+    var b strings.Builder
+    bit, ch, even := 0, 0, true
+    
+    for b.Len() < precision {
+        rng, v := &latRange, lat
+        if even {
+            rng, v = &lonRange, lon
+        }
+        
+        mid := (rng[0] + rng[1]) / 2
+        ch <<= 1
+        if v >= mid {
+            ch |= 1
+            rng[0] = mid
+        } else {
+            rng[1] = mid
+        }
+        even = !even
+        
+        if bit++; bit == 5 {
+            b.WriteByte(geohashBase32[ch])
+            bit, ch = 0, 0
+        }
+    }
+    
+    return b.String()
+}
+
+// geohashDecode retourne le centre de la cellule d'un geohash
+func geohashDecode(hash string) (float64, float64) {
+    latRange := [2]float64{-90, 90}
+    lonRange := [2]float64{-180, 180}
+    even := true
+    
+    for i := 0; i < len(hash); i++ {
+        ch := strings.IndexByte(geohashBase32, hash[i])
+        if ch < 0 {
+            break
+        }
+        for mask := 16; mask > 0; mask >>= 1 {
+            rng := &latRange
+            if even {
+                rng = &lonRange
+            }
+            mid := (rng[0] + rng[1]) / 2
+            if ch&mask != 0 {
+                rng[0] = mid
+            } else {
+                rng[1] = mid
+            }
+            even = !even
+        }
+    }
+    
+    return (latRange[0] + latRange[1]) / 2, (lonRange[0] + lonRange[1]) / 2
+}
+
+// geohashCellSize retourne la taille (degrés de latitude, de longitude) d'une
+// cellule de la précision donnée
+func geohashCellSize(precision int) (float64, float64) {
+    bits := precision * 5
+    lonBits := (bits + 1) / 2
+    latBits := bits / 2
+    return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lonBits))
+}
+
+// geohashCover retourne les geohashs couvrant un rectangle, à la précision la
+// plus fine pour laquelle le rectangle tient dans une grille d'environ 4x4 cellules
+func geohashCover(minLat, minLon, maxLat, maxLon float64) []string {
+    precision := 1
+    for p := geohashPrecision; p >= 1; p-- {
+        latSize, lonSize := geohashCellSize(p)
+        if (maxLat-minLat)/latSize <= 4 && (maxLon-minLon)/lonSize <= 4 {
+            precision = p
+            break
+        }
+    }
+    
+    latSize, lonSize := geohashCellSize(precision)
+    seen := make(map[string]bool)
+    var cells []string
+    
+    for lat := minLat; ; lat += latSize {
+        lat = math.Min(lat, maxLat)
+        for lon := minLon; ; lon += lonSize {
+            lon = math.Min(lon, maxLon)
+            cell := geohashEncode(lat, lon, precision)
+            if !seen[cell] {
+                seen[cell] = true
+                cells = append(cells, cell)
+            }
+            if lon >= maxLon {
+                break
+            }
+        }
+        if lat >= maxLat {
+            break
+        }
+    }
+    
+    sort.Strings(cells)
+    return cells
+}
+
+// haversineKm retourne la distance orthodromique entre deux positions
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+    toRad := math.Pi / 180
+    dLat := (lat2 - lat1) * toRad
+    dLon := (lon2 - lon1) * toRad
+    
+    a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+        math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+    return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
\ No newline at end of file
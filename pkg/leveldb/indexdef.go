@@ -0,0 +1,379 @@
+// pkg/leveldb/indexdef.go
+// Registre des définitions d'index secondaires (clés système _index:)
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+    "time"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Types d'index
+const (
+    IndexKindValue = ""    // valeur normalisée du champ
+    IndexKindGeo   = "geo" // geohash du centroïde du préfixe de code postal
+    
+    IndexKindComposite = "composite" // tuple des valeurs de Fields
+    IndexKindExpr      = "expr"      // résultat d'une expression calculée à l'écriture
+)
+
+// IndexDef décrit un index secondaire déclaré sur un nœud
+type IndexDef struct {
+    RecordType string   `json:"record_type"`
+    Name       string   `json:"name,omitempty"` // nom de l'index dans les clés idx: (défaut: Field)
+    Field      string   `json:"field"`          // champ source du document
+    Fields     []string `json:"fields,omitempty"` // champs d'un index composite, dans l'ordre
+    Expr       string   `json:"expr,omitempty"`   // expression d'un index calculé (ex: length_cm * width_cm)
+    Kind       string   `json:"kind,omitempty"`
+    Project    []string `json:"project,omitempty"` // champs recopiés dans l'entrée (index couvrant)
+    Unique     bool     `json:"unique,omitempty"`  // une valeur ne peut appartenir qu'à une clé primaire
+    Normalizer string   `json:"normalizer,omitempty"` // casefold (défaut), exact, accentfold, numeric ou normaliseur enregistré
+    CreatedAt  string   `json:"created_at"`
+}
+
+// IndexName retourne le nom sous lequel l'index est stocké (idx:<type>:<nom>:...)
+func (d *IndexDef) IndexName() string {
+    if d.Name != "" {
+        return d.Name
+    }
+    if d.Kind == IndexKindComposite {
+        return compositeIndexName(d.Fields)
+    }
+    return d.Field
+}
+
+// sourceFields retourne les champs du document lus par l'index
+func (d *IndexDef) sourceFields() []string {
+    switch d.Kind {
+    case IndexKindComposite:
+        return d.Fields
+    case IndexKindExpr:
+        return nil
+    }
+    return []string{d.Field}
+}
+
+// indexValues retourne les valeurs normalisées qu'un document produit pour un index
+func (idx *Indexer) indexValues(def *IndexDef, data map[string]interface{}) []string {
+    if def.Kind == IndexKindComposite {
+        values, ok := compositeValues(data, def.Fields, def.normalize)
+        if !ok {
+            return nil
+        }
+        return []string{encodeTuple(values)}
+    }
+    
+    if def.Kind == IndexKindExpr {
+        if value, ok := def.exprIndexValue(data); ok {
+            return []string{value}
+        }
+        return nil
+    }
+    
+    values := def.normalizeAll(fieldScalars(data, def.Field))
+    
+    switch def.Kind {
+    case IndexKindGeo:
+        var hashes []string
+        for _, zip := range values {
+            if hash, ok := idx.zipGeohash(zip); ok && !containsString(hashes, hash) {
+                hashes = append(hashes, hash)
+            }
+        }
+        return hashes
+    }
+    
+    return values
+}
+
+// exprIndexValue calcule la valeur indexée d'une expression (voir exprEncode)
+func (d *IndexDef) exprIndexValue(data map[string]interface{}) (string, bool) {
+    node, err := compileExpr(d.Expr)
+    if err != nil {
+        return "", false
+    }
+    return d.exprEncode(node.eval(data))
+}
+
+// exprEncode encode un résultat d'expression, calculé à l'écriture ou
+// recherché, selon le type de résultat de l'expression: un nombre est encodé
+// pour que l'ordre des clés soit l'ordre numérique, une chaîne est normalisée
+// (les nombres y sont écrits en texte). Quand le type dépend du document
+// (champ seul), une chaîne numérique est traitée comme un nombre des deux côtés.
+func (d *IndexDef) exprEncode(v interface{}) (string, bool) {
+    kind := d.exprKind()
+    
+    if kind != exprKindString {
+        if n, ok := toFloat(v); ok {
+            if math.IsNaN(n) || math.IsInf(n, 0) {
+                return "", false
+            }
+            return encodeSortableFloat(n), true
+        }
+        if kind == exprKindNumber {
+            return "", false
+        }
+    }
+    
+    s, ok := exprString(v)
+    if !ok {
+        return "", false
+    }
+    return d.normalize(s)
+}
+
+// exprKind retourne le type de résultat de l'expression d'un index calculé
+func (d *IndexDef) exprKind() string {
+    node, err := compileExpr(d.Expr)
+    if err != nil {
+        return exprKindAny
+    }
+    return exprResultKind(node)
+}
+
+// displayValue retourne la forme lisible d'une valeur stockée dans l'index. Le
+// tuple d'un index composite est décodé, ses éléments joints par '+' comme les
+// champs dans le nom de l'index (state+city → sp+campinas).
+func (d *IndexDef) displayValue(stored string) string {
+    if d != nil && d.Kind == IndexKindComposite {
+        elems, err := decodeTuple(stored)
+        if err != nil {
+            return stored
+        }
+        elemDef := &IndexDef{Normalizer: d.Normalizer}
+        for i := range elems {
+            elems[i] = elemDef.displayValue(elems[i])
+        }
+        return strings.Join(elems, compositeSeparator)
+    }
+    if d != nil && d.Kind == IndexKindExpr && d.exprKind() == exprKindString {
+        return stored
+    }
+    if d != nil && (d.Kind == IndexKindExpr || d.Normalizer == NormalizerNumeric) {
+        if n, ok := decodeSortableFloat(stored); ok {
+            return strconv.FormatFloat(n, 'f', -1, 64)
+        }
+    }
+    return stored
+}
+
+// searchValue convertit une valeur recherchée dans la forme stockée par l'index
+// name: passée par son normaliseur, ou encodée comme à l'écriture pour un index calculé
+func (idx *Indexer) searchValue(recordType, name, value string) string {
+    def, err := idx.GetIndexDef(recordType, name)
+    if err != nil {
+        def = nil
+    }
+    if def != nil && def.Kind == IndexKindExpr {
+        if encoded, ok := def.exprEncode(value); ok {
+            return encoded
+        }
+    }
+    if normalized, ok := def.normalize(value); ok {
+        return normalized
+    }
+    return value
+}
+
+// Covers indique si l'index contient tous les champs demandés
+func (d *IndexDef) Covers(fields []string) bool {
+    for _, f := range fields {
+        if f == d.Field && d.Kind == IndexKindValue {
+            continue
+        }
+        if !containsString(d.Project, f) {
+            return false
+        }
+    }
+    return true
+}
+
+// entryValue construit la valeur stockée pour une entrée d'index: la clé
+// primaire seule, ou un objet JSON avec la projection pour un index couvrant
+func (d *IndexDef) entryValue(primaryKey string, data map[string]interface{}) []byte {
+    if d == nil || len(d.Project) == 0 {
+        return []byte(primaryKey)
+    }
+    
+    fields := make(map[string]interface{}, len(d.Project))
+    for _, f := range d.Project {
+        if value, ok := data[f]; ok {
+            fields[f] = value
+        }
+    }
+    
+    value, err := json.Marshal(indexEntry{Key: primaryKey, Fields: fields})
+    if err != nil {
+        return []byte(primaryKey)
+    }
+    return value
+}
+
+// indexEntry est la valeur JSON d'une entrée d'index couvrant
+type indexEntry struct {
+    Key    string                 `json:"key"`
+    Fields map[string]interface{} `json:"fields"`
+}
+
+// decodeIndexValue extrait la clé primaire (et la projection éventuelle) d'une valeur d'index
+func decodeIndexValue(value []byte) (string, map[string]interface{}) {
+    if len(value) > 0 && value[0] == '{' {
+        var entry indexEntry
+        if err := json.Unmarshal(value, &entry); err == nil {
+            return entry.Key, entry.Fields
+        }
+    }
+    return string(value), nil
+}
+
+func indexDefKey(recordType, field string) string {
+    return fmt.Sprintf("_index:%s:%s", recordType, field)
+}
+
+// DefineIndex enregistre (ou remplace) la définition d'un index
+func (idx *Indexer) DefineIndex(def IndexDef) error {
+    if err := def.validate(); err != nil {
+        return err
+    }
+    
+    if def.CreatedAt == "" {
+        def.CreatedAt = time.Now().Format(time.RFC3339)
+    }
+    
+    defBytes, err := json.Marshal(def)
+    if err != nil {
+        return fmt.Errorf("erreur sérialisation définition: %v", err)
+    }
+    
+    return idx.db.Put([]byte(indexDefKey(def.RecordType, def.IndexName())), defBytes, nil)
+}
+
+// validate vérifie qu'une définition est complète et que son nom, son chemin ou
+// son expression sont valides
+func (d *IndexDef) validate() error {
+    if d.RecordType == "" || (d.Field == "" && len(d.Fields) == 0 && d.Expr == "") {
+        return fmt.Errorf("définition d'index incomplète: type et champ requis")
+    }
+    if d.Kind == IndexKindExpr {
+        if d.Name == "" {
+            return fmt.Errorf("un index calculé doit être nommé")
+        }
+        if _, err := compileExpr(d.Expr); err != nil {
+            return err
+        }
+    }
+    if d.Kind == IndexKindComposite && len(d.Fields) == 0 {
+        return fmt.Errorf("index composite sans champs")
+    }
+    if _, err := lookupNormalizer(d.Normalizer); err != nil {
+        return err
+    }
+    if isJSONPath(d.Field) {
+        if _, err := parsePath(d.Field); err != nil {
+            return err
+        }
+    }
+    if strings.Contains(d.IndexName(), ":") {
+        return fmt.Errorf("nom d'index invalide (':' interdit): %s", d.IndexName())
+    }
+    return nil
+}
+
+// GetIndexDef retourne la définition d'un index par nom, ou nil s'il n'est pas déclaré
+func (idx *Indexer) GetIndexDef(recordType, name string) (*IndexDef, error) {
+    value, err := idx.db.Get([]byte(indexDefKey(recordType, name)), nil)
+    if err == leveldb.ErrNotFound {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lecture définition: %v", err)
+    }
+    
+    var def IndexDef
+    if err := json.Unmarshal(value, &def); err != nil {
+        return nil, fmt.Errorf("erreur désérialisation définition: %v", err)
+    }
+    
+    return &def, nil
+}
+
+// ListIndexDefs retourne les définitions déclarées, éventuellement filtrées par type
+func (idx *Indexer) ListIndexDefs(recordType string) ([]IndexDef, error) {
+    prefix := "_index:"
+    if recordType != "" {
+        prefix += recordType + ":"
+    }
+    
+    var defs []IndexDef
+    
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        var def IndexDef
+        if err := json.Unmarshal(iter.Value(), &def); err != nil {
+            continue
+        }
+        defs = append(defs, def)
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur itération: %v", err)
+    }
+    
+    return defs, nil
+}
+
+// UnmaintainedIndexes retourne les index d'un type présents sur disque sans
+// définition (créés par CreateIndex ou CreateCompositeIndex): les écritures du
+// Client ne les mettent pas à jour. -reindex type:nom les déclare.
+func (idx *Indexer) UnmaintainedIndexes(recordType string) ([]string, error) {
+    if recordType == "" {
+        return nil, nil
+    }
+    
+    prefix := "idx:" + recordType + ":"
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    // Une entrée lue par index: sauter ensuite au nom suivant
+    var names []string
+    for ok := iter.Next(); ok; {
+        rest := strings.TrimPrefix(string(iter.Key()), prefix)
+        j := strings.Index(rest, ":")
+        if j < 0 {
+            ok = iter.Next()
+            continue
+        }
+        name := rest[:j]
+        
+        def, err := idx.GetIndexDef(recordType, name)
+        if err != nil {
+            return nil, err
+        }
+        if def == nil {
+            names = append(names, name)
+        }
+        ok = iter.Seek(util.BytesPrefix([]byte(indexPrefix(recordType, name))).Limit)
+    }
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur itération: %v", err)
+    }
+    return names, nil
+}
+
+// DropIndexDef supprime la définition d'un index (les entrées idx: sont conservées)
+func (idx *Indexer) DropIndexDef(recordType, name string) error {
+    // Les compteurs ne seraient plus maintenus par les écritures
+    if err := idx.dropStats(recordType, name); err != nil {
+        return err
+    }
+    return idx.db.Delete([]byte(indexDefKey(recordType, name)), nil)
+}
\ No newline at end of file
@@ -1,244 +1,604 @@
-// pkg/leveldb/indexer.go
-// Implémentation d'indexation secondaire pour LevelDB
-
-package leveldb
-
-import (
-    "encoding/json"
-    "fmt"
-    "strings"
-    
-    "github.com/syndtr/goleveldb/leveldb"
-    "github.com/syndtr/goleveldb/leveldb/util"
-)
-
-type Indexer struct {
-    db *leveldb.DB
-}
-
-func NewIndexer(db *leveldb.DB) *Indexer {
-    return &Indexer{db: db}
-}
-
-func (idx *Indexer) CreateIndex(recordType, field, value, primaryKey string) error {
-    normalizedValue := strings.ToLower(strings.TrimSpace(value))
-    
-    indexKey := fmt.Sprintf("idx:%s:%s:%s:%s", 
-        recordType, field, normalizedValue, primaryKey)
-    
-    // CORRECTION: Set → Put
-    return idx.db.Put([]byte(indexKey), []byte(primaryKey), nil)
-}
-
-func (idx *Indexer) SearchByIndex(recordType, field, value string) ([]string, error) {
-    normalizedValue := strings.ToLower(strings.TrimSpace(value))
-    
-    prefix := fmt.Sprintf("idx:%s:%s:%s:", recordType, field, normalizedValue)
-    
-    var results []string
-    
-    // CORRECTION: Find → NewIterator avec util.BytesPrefix
-    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
-    defer iter.Release()
-    
-    for iter.Next() {
-        key := string(iter.Key())
-        
-        if !strings.HasPrefix(key, prefix) {
-            break
-        }
-        
-        primaryKey := string(iter.Value())
-        results = append(results, primaryKey)
-    }
-    
-    if err := iter.Error(); err != nil {
-        return nil, fmt.Errorf("erreur itération: %v", err)
-    }
-    
-    return results, nil
-}
-
-func (idx *Indexer) GetByIndex(recordType, field, value string) ([]Entry, error) {
-    primaryKeys, err := idx.SearchByIndex(recordType, field, value)
-    if err != nil {
-        return nil, err
-    }
-    
-    var entries []Entry
-    
-    for _, pk := range primaryKeys {
-        valueBytes, err := idx.db.Get([]byte(pk), nil)
-        if err != nil {
-            continue
-        }
-        
-        var entry Entry
-        if err := json.Unmarshal(valueBytes, &entry); err != nil {
-            continue
-        }
-        
-        entries = append(entries, entry)
-    }
-    
-    return entries, nil
-}
-
-func (idx *Indexer) CountByIndex(recordType, field, value string) (int, error) {
-    results, err := idx.SearchByIndex(recordType, field, value)
-    if err != nil {
-        return 0, err
-    }
-    return len(results), nil
-}
-
-func (idx *Indexer) UpdateIndexes(recordType, primaryKey string, oldData, newData map[string]interface{}) error {
-    if oldData != nil {
-        for field, value := range oldData {
-            if !isIndexableField(field) {
-                continue
-            }
-            
-            valueStr := fmt.Sprintf("%v", value)
-            normalizedValue := strings.ToLower(strings.TrimSpace(valueStr))
-            
-            oldIndexKey := fmt.Sprintf("idx:%s:%s:%s:%s", 
-                recordType, field, normalizedValue, primaryKey)
-            
-            idx.db.Delete([]byte(oldIndexKey), nil)
-        }
-    }
-    
-    if newData != nil {
-        for field, value := range newData {
-            if !isIndexableField(field) {
-                continue
-            }
-            
-            valueStr := fmt.Sprintf("%v", value)
-            if err := idx.CreateIndex(recordType, field, valueStr, primaryKey); err != nil {
-                return fmt.Errorf("erreur création index %s: %v", field, err)
-            }
-        }
-    }
-    
-    return nil
-}
-
-func (idx *Indexer) DeleteIndexes(recordType, primaryKey string, data map[string]interface{}) error {
-    if data == nil {
-        return nil
-    }
-    
-    for field, value := range data {
-        if !isIndexableField(field) {
-            continue
-        }
-        
-        valueStr := fmt.Sprintf("%v", value)
-        normalizedValue := strings.ToLower(strings.TrimSpace(valueStr))
-        
-        indexKey := fmt.Sprintf("idx:%s:%s:%s:%s", 
-            recordType, field, normalizedValue, primaryKey)
-        
-        if err := idx.db.Delete([]byte(indexKey), nil); err != nil {
-            return fmt.Errorf("erreur suppression index %s: %v", field, err)
-        }
-    }
-    
-    return nil
-}
-
-func (idx *Indexer) ListIndexes(recordType, field string) (map[string]int, error) {
-    prefix := fmt.Sprintf("idx:%s:%s:", recordType, field)
-    
-    counts := make(map[string]int)
-    
-    // CORRECTION: Find → NewIterator
-    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
-    defer iter.Release()
-    
-    for iter.Next() {
-        key := string(iter.Key())
-        
-        if !strings.HasPrefix(key, prefix) {
-            break
-        }
-        
-        parts := strings.Split(key, ":")
-        if len(parts) >= 4 {
-            value := parts[3]
-            counts[value]++
-        }
-    }
-    
-    if err := iter.Error(); err != nil {
-        return nil, err
-    }
-    
-    return counts, nil
-}
-
-func (idx *Indexer) CreateCompositeIndex(recordType string, fields []string, values []string, primaryKey string) error {
-    if len(fields) != len(values) {
-        return fmt.Errorf("nombre de champs et valeurs différent")
-    }
-    
-    compositeField := strings.Join(fields, "-")
-    
-    normalizedValues := make([]string, len(values))
-    for i, v := range values {
-        normalizedValues[i] = strings.ToLower(strings.TrimSpace(v))
-    }
-    compositeValue := strings.Join(normalizedValues, "-")
-    
-    indexKey := fmt.Sprintf("idx:%s:%s:%s:%s", 
-        recordType, compositeField, compositeValue, primaryKey)
-    
-    // CORRECTION: Set → Put
-    return idx.db.Put([]byte(indexKey), []byte(primaryKey), nil)
-}
-
-func (idx *Indexer) SearchByCompositeIndex(recordType string, fields []string, values []string) ([]string, error) {
-    compositeField := strings.Join(fields, "-")
-    
-    normalizedValues := make([]string, len(values))
-    for i, v := range values {
-        normalizedValues[i] = strings.ToLower(strings.TrimSpace(v))
-    }
-    compositeValue := strings.Join(normalizedValues, "-")
-    
-    prefix := fmt.Sprintf("idx:%s:%s:%s:", recordType, compositeField, compositeValue)
-    
-    var results []string
-    
-    // CORRECTION: Find → NewIterator
-    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
-    defer iter.Release()
-    
-    for iter.Next() {
-        key := string(iter.Key())
-        if !strings.HasPrefix(key, prefix) {
-            break
-        }
-        results = append(results, string(iter.Value()))
-    }
-    
-    return results, nil
-}
-
-func isIndexableField(field string) bool {
-    systemFields := map[string]bool{
-        "hash":       true,
-        "timestamp":  true,
-        "node":       true,
-        "ledger_type": false,
-        "data":       true,
-    }
-    
-    if excluded, exists := systemFields[field]; exists {
-        return !excluded
-    }
-    
-    return true
+// pkg/leveldb/indexer.go
+// Implémentation d'indexation secondaire pour LevelDB
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+type Indexer struct {
+    db *leveldb.DB
+}
+
+func NewIndexer(db *leveldb.DB) *Indexer {
+    return &Indexer{db: db}
+}
+
+func (idx *Indexer) CreateIndex(recordType, field, value, primaryKey string) error {
+    // Même normaliseur que les recherches (searchValue)
+    def, err := idx.fieldIndexDef(recordType, field)
+    if err != nil {
+        return err
+    }
+    normalizedValue, ok := def.normalize(value)
+    if !ok {
+        // Valeur refusée par le normaliseur (ex: non numérique): rien à indexer
+        return nil
+    }
+    
+    key := indexKey(recordType, field, normalizedValue, primaryKey)
+    
+    // CORRECTION: Set → Put
+    b := idx.newIndexBatch()
+    if err := b.put(def, normalizedValue, key, []byte(primaryKey)); err != nil {
+        return err
+    }
+    return b.write()
+}
+
+func (idx *Indexer) SearchByIndex(recordType, field, value string) ([]string, error) {
+    normalizedValue := idx.searchValue(recordType, field, value)
+    
+    prefix := indexValuePrefix(recordType, field, normalizedValue)
+    
+    var results []string
+    
+    // CORRECTION: Find → NewIterator avec util.BytesPrefix
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        key := string(iter.Key())
+        
+        if !strings.HasPrefix(key, prefix) {
+            break
+        }
+        
+        primaryKey, _ := decodeIndexValue(iter.Value())
+        results = append(results, primaryKey)
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur itération: %v", err)
+    }
+    
+    return results, nil
+}
+
+func (idx *Indexer) GetByIndex(recordType, field, value string) ([]Entry, error) {
+    primaryKeys, err := idx.SearchByIndex(recordType, field, value)
+    if err != nil {
+        return nil, err
+    }
+    
+    var entries []Entry
+    
+    for _, pk := range primaryKeys {
+        valueBytes, err := idx.db.Get([]byte(pk), nil)
+        if err != nil {
+            continue
+        }
+        
+        var entry Entry
+        if err := json.Unmarshal(valueBytes, &entry); err != nil {
+            continue
+        }
+        
+        entries = append(entries, entry)
+    }
+    
+    return entries, nil
+}
+
+// IndexHit est un résultat lu directement depuis une entrée d'index
+type IndexHit struct {
+    Key    string
+    Value  string                 // valeur indexée (décodée pour un index calculé numérique)
+    Fields map[string]interface{} // projection de l'index couvrant (nil sinon)
+}
+
+// ScanIndex retourne les entrées d'index pour une valeur, avec la projection
+// stockée par un index couvrant: aucun document n'est relu.
+func (idx *Indexer) ScanIndex(recordType, field, value string) ([]IndexHit, error) {
+    def, err := idx.GetIndexDef(recordType, field)
+    if err != nil {
+        return nil, err
+    }
+    
+    stored := idx.searchValue(recordType, field, value)
+    prefix := indexValuePrefix(recordType, field, stored)
+    value = def.displayValue(stored)
+    
+    var hits []IndexHit
+    
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        primaryKey, fields := decodeIndexValue(iter.Value())
+        hits = append(hits, IndexHit{Key: primaryKey, Value: value, Fields: fields})
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur itération: %v", err)
+    }
+    
+    return hits, nil
+}
+
+func (idx *Indexer) CountByIndex(recordType, field, value string) (int, error) {
+    // Compter les clés du préfixe sans matérialiser la liste des résultats
+    prefix := indexValuePrefix(recordType, field, idx.searchValue(recordType, field, value))
+    return countPrefix(idx.db, prefix), nil
+}
+
+// SearchRange retourne les entrées d'index dont la valeur est dans [from, to]
+// (bornes incluses, "" = non bornée), dans l'ordre de l'index. Sur un index
+// calculé, les valeurs et les bornes numériques sont comparées numériquement.
+func (idx *Indexer) SearchRange(recordType, field, from, to string) ([]IndexHit, error) {
+    var hits []IndexHit
+    err := idx.EachInRange(recordType, field, from, to, func(hit IndexHit) bool {
+        hits = append(hits, hit)
+        return true
+    })
+    if err != nil {
+        return nil, err
+    }
+    return hits, nil
+}
+
+// EachInRange appelle fn pour chaque entrée de SearchRange, dans l'ordre de
+// l'index, sans matérialiser la liste; fn retourne faux pour arrêter le parcours
+func (idx *Indexer) EachInRange(recordType, field, from, to string, fn func(hit IndexHit) bool) error {
+    def, err := idx.GetIndexDef(recordType, field)
+    if err != nil {
+        return err
+    }
+    
+    prefix := indexPrefix(recordType, field)
+    low := idx.searchValue(recordType, field, from)
+    high := idx.searchValue(recordType, field, to)
+    
+    rng := util.BytesPrefix([]byte(prefix))
+    if from != "" {
+        rng.Start = []byte(prefix + low)
+    }
+    if to != "" {
+        rng.Limit = []byte(prefix + rangeLimit(high))
+    }
+    
+    iter := idx.db.NewIterator(rng, nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        primaryKey, fields := decodeIndexValue(iter.Value())
+        value := strings.TrimSuffix(strings.TrimPrefix(string(iter.Key()), prefix), ":"+primaryKey)
+        
+        // Les bornes de l'itérateur sont larges: une valeur préfixe d'une autre
+        // n'est pas forcément rangée avant elle ("b:" > "b-x:")
+        if from != "" && value < low || to != "" && value > high {
+            continue
+        }
+        
+        if !fn(IndexHit{Key: primaryKey, Value: def.displayValue(value), Fields: fields}) {
+            break
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return fmt.Errorf("erreur itération: %v", err)
+    }
+    
+    return nil
+}
+
+// rangeLimit retourne une borne de clé supérieure à toutes les entrées dont la
+// valeur est <= high: high est tronqué avant le premier octet <= ':', qui
+// pourrait trier une valeur plus courte après lui
+func rangeLimit(high string) string {
+    for i := 0; i < len(high); i++ {
+        if high[i] <= ':' {
+            return high[:i] + "\xff"
+        }
+    }
+    return high + "\xff"
+}
+
+func (idx *Indexer) UpdateIndexes(recordType, primaryKey string, oldData, newData map[string]interface{}) error {
+    b := idx.newIndexBatch()
+    
+    if oldData != nil {
+        for field := range oldData {
+            if !isIndexableField(field) {
+                continue
+            }
+            
+            def, err := idx.fieldIndexDef(recordType, field)
+            if err != nil {
+                return err
+            }
+            
+            for _, normalizedValue := range idx.indexValues(def, oldData) {
+                oldIndexKey := indexKey(recordType, field, normalizedValue, primaryKey)
+                
+                if err := b.delete(def, normalizedValue, oldIndexKey); err != nil {
+                    return err
+                }
+            }
+        }
+    }
+    
+    if newData != nil {
+        for field := range newData {
+            if !isIndexableField(field) {
+                continue
+            }
+            
+            // Index couvrant: la valeur porte la projection déclarée
+            def, err := idx.fieldIndexDef(recordType, field)
+            if err != nil {
+                return err
+            }
+            
+            // Un tableau produit une entrée par élément; un objet n'est pas indexé
+            for _, normalizedValue := range idx.indexValues(def, newData) {
+                key := indexKey(recordType, field, normalizedValue, primaryKey)
+                if err := b.put(def, normalizedValue, key, def.entryValue(primaryKey, newData)); err != nil {
+                    return err
+                }
+            }
+        }
+    }
+    
+    return b.write()
+}
+
+func (idx *Indexer) DeleteIndexes(recordType, primaryKey string, data map[string]interface{}) error {
+    if data == nil {
+        return nil
+    }
+    
+    b := idx.newIndexBatch()
+    
+    for field := range data {
+        if !isIndexableField(field) {
+            continue
+        }
+        
+        def, err := idx.fieldIndexDef(recordType, field)
+        if err != nil {
+            return err
+        }
+        
+        for _, normalizedValue := range idx.indexValues(def, data) {
+            key := indexKey(recordType, field, normalizedValue, primaryKey)
+            
+            if err := b.delete(def, normalizedValue, key); err != nil {
+                return err
+            }
+        }
+    }
+    
+    return b.write()
+}
+
+// fieldIndexDef retourne la définition de l'index d'un champ, ou une définition
+// par défaut (casefold) si aucune n'est déclarée: écritures et recherches
+// appliquent ainsi le même normaliseur
+func (idx *Indexer) fieldIndexDef(recordType, field string) (*IndexDef, error) {
+    def, err := idx.GetIndexDef(recordType, field)
+    if err != nil {
+        return nil, err
+    }
+    if def == nil {
+        def = &IndexDef{RecordType: recordType, Field: field}
+    }
+    return def, nil
+}
+
+// indexBatch regroupe les écritures d'index d'un appel en un lot, avec la
+// variation des compteurs de statistiques (_icount:). Une entrée n'est comptée
+// que si son existence change: réécrire une entrée présente ne compte rien.
+type indexBatch struct {
+    db      *leveldb.DB
+    batch   *leveldb.Batch
+    stats   statsDelta
+    present map[string]bool // existence des entrées après les opérations du lot
+}
+
+func (idx *Indexer) newIndexBatch() *indexBatch {
+    return &indexBatch{db: idx.db, batch: new(leveldb.Batch), present: make(map[string]bool)}
+}
+
+func (b *indexBatch) exists(key string) (bool, error) {
+    if present, ok := b.present[key]; ok {
+        return present, nil
+    }
+    present, err := b.db.Has([]byte(key), nil)
+    if err != nil {
+        return false, fmt.Errorf("erreur lecture %s: %v", key, err)
+    }
+    return present, nil
+}
+
+func (b *indexBatch) put(def *IndexDef, normalizedValue, key string, value []byte) error {
+    present, err := b.exists(key)
+    if err != nil {
+        return err
+    }
+    if !present {
+        b.stats.add(def, normalizedValue, 1)
+    }
+    b.present[key] = true
+    b.batch.Put([]byte(key), value)
+    return nil
+}
+
+func (b *indexBatch) delete(def *IndexDef, normalizedValue, key string) error {
+    present, err := b.exists(key)
+    if err != nil {
+        return err
+    }
+    if present {
+        b.stats.add(def, normalizedValue, -1)
+    }
+    b.present[key] = false
+    b.batch.Delete([]byte(key))
+    return nil
+}
+
+// write ajoute les compteurs au lot et l'écrit
+func (b *indexBatch) write() error {
+    if err := b.stats.apply(b.db, b.batch); err != nil {
+        return err
+    }
+    if err := b.db.Write(b.batch, nil); err != nil {
+        return fmt.Errorf("erreur écriture index: %v", err)
+    }
+    return nil
+}
+
+func (idx *Indexer) ListIndexes(recordType, field string) (map[string]int, error) {
+    prefix := indexPrefix(recordType, field)
+    
+    counts := make(map[string]int)
+    
+    // CORRECTION: Find → NewIterator
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        key := string(iter.Key())
+        
+        if !strings.HasPrefix(key, prefix) {
+            break
+        }
+        
+        parts := strings.Split(key, ":")
+        if len(parts) >= 4 {
+            value := parts[3]
+            counts[value]++
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, err
+    }
+    
+    return counts, nil
+}
+
+// Séparateur des champs dans le nom d'un index composite (idx:<type>:state+city:...)
+const compositeSeparator = "+"
+
+func compositeIndexName(fields []string) string {
+    return strings.Join(fields, compositeSeparator)
+}
+
+// compositeValues retourne les valeurs normalisées des champs d'un index
+// composite, ou faux si l'un d'eux est absent du document ou refusé par normalize
+func compositeValues(data map[string]interface{}, fields []string, normalize Normalizer) ([]string, bool) {
+    values := make([]string, len(fields))
+    for i, f := range fields {
+        value, ok := data[f]
+        if !ok {
+            return nil, false
+        }
+        if values[i], ok = normalize(fieldValueString(value)); !ok {
+            return nil, false
+        }
+    }
+    return values, true
+}
+
+// normalizeIndexValues normalise des valeurs données d'un index composite, ou
+// retourne faux si normalize en refuse une: comme pour compositeValues, aucune
+// entrée ne peut alors porter ces valeurs
+func normalizeIndexValues(values []string, normalize Normalizer) ([]string, bool) {
+    normalized := make([]string, len(values))
+    for i, v := range values {
+        n, ok := normalize(v)
+        if !ok {
+            return nil, false
+        }
+        normalized[i] = n
+    }
+    return normalized, true
+}
+
+// CreateCompositeIndex ajoute une entrée d'index composite. Les valeurs sont
+// encodées en tuple et peuvent donc contenir n'importe quel caractère ('-', ':'...).
+// Une valeur refusée par le normaliseur de l'index n'ajoute aucune entrée, comme
+// pour un document écrit par le Client.
+func (idx *Indexer) CreateCompositeIndex(recordType string, fields []string, values []string, primaryKey string) error {
+    if len(fields) != len(values) {
+        return fmt.Errorf("nombre de champs et valeurs différent")
+    }
+    
+    // Valeurs normalisées comme dans SearchCompositeRange
+    def, err := idx.GetIndexDef(recordType, compositeIndexName(fields))
+    if err != nil {
+        return err
+    }
+    if def == nil {
+        def = &IndexDef{RecordType: recordType, Kind: IndexKindComposite, Fields: fields}
+    }
+    
+    normalized, ok := normalizeIndexValues(values, def.normalize)
+    if !ok {
+        return nil
+    }
+    normalizedValue := encodeTuple(normalized)
+    key := indexKey(recordType, compositeIndexName(fields), normalizedValue, primaryKey)
+    
+    // CORRECTION: Set → Put
+    b := idx.newIndexBatch()
+    if err := b.put(def, normalizedValue, key, []byte(primaryKey)); err != nil {
+        return err
+    }
+    return b.write()
+}
+
+// SearchByCompositeIndex retourne les documents dont les premiers champs de l'index
+// composite valent values: toutes les valeurs, ou seulement un préfixe (state, state+city...)
+func (idx *Indexer) SearchByCompositeIndex(recordType string, fields []string, values []string) ([]string, error) {
+    return idx.SearchCompositeRange(recordType, fields, values, "", "")
+}
+
+// SearchCompositeRange restreint en plus le champ qui suit values à la plage
+// [from, to] (bornes incluses, "" = non bornée). Les bornes sont normalisées
+// et comparées comme les valeurs indexées, octet par octet.
+func (idx *Indexer) SearchCompositeRange(recordType string, fields []string, values []string, from, to string) ([]string, error) {
+    var results []string
+    err := idx.EachInCompositeRange(recordType, fields, values, from, to, func(key string) bool {
+        results = append(results, key)
+        return true
+    })
+    if err != nil {
+        return nil, err
+    }
+    return results, nil
+}
+
+// EachInCompositeRange appelle fn pour chaque clé de SearchCompositeRange, dans
+// l'ordre de l'index, sans matérialiser la liste; fn retourne faux pour arrêter
+// le parcours
+func (idx *Indexer) EachInCompositeRange(recordType string, fields []string, values []string, from, to string, fn func(key string) bool) error {
+    if len(values) > len(fields) {
+        return fmt.Errorf("plus de valeurs (%d) que de champs (%d)", len(values), len(fields))
+    }
+    if (from != "" || to != "") && len(values) == len(fields) {
+        return fmt.Errorf("plage impossible: aucun champ après les %d valeurs données", len(values))
+    }
+    
+    // Valeurs et bornes passent par le normaliseur déclaré de l'index
+    def, err := idx.GetIndexDef(recordType, compositeIndexName(fields))
+    if err != nil {
+        return err
+    }
+    normalize := def.normalize
+    
+    // Une valeur refusée n'est portée par aucune entrée: pas de résultat
+    normalized, ok := normalizeIndexValues(values, normalize)
+    if !ok {
+        return nil
+    }
+    prefix := indexPrefix(recordType, compositeIndexName(fields)) + encodeTuple(normalized)
+    
+    rng := &util.Range{Start: []byte(prefix), Limit: []byte(tupleUpperBound(prefix))}
+    for _, bound := range []string{from, to} {
+        if bound == "" {
+            continue
+        }
+        normalized, ok := normalizeIndexValues([]string{bound}, normalize)
+        if !ok {
+            return fmt.Errorf("borne %q refusée par le normaliseur de l'index", bound)
+        }
+        if bound == from {
+            rng.Start = []byte(prefix + encodeTuple(normalized))
+        }
+        if bound == to {
+            rng.Limit = []byte(tupleUpperBound(prefix + encodeTuple(normalized)))
+        }
+    }
+    
+    iter := idx.db.NewIterator(rng, nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        primaryKey, _ := decodeIndexValue(iter.Value())
+        if !fn(primaryKey) {
+            break
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return fmt.Errorf("erreur itération: %v", err)
+    }
+    
+    return nil
+}
+
+func isIndexableField(field string) bool {
+    systemFields := map[string]bool{
+        "hash":       true,
+        "timestamp":  true,
+        "node":       true,
+        "ledger_type": false,
+        "data":       true,
+    }
+    
+    if excluded, exists := systemFields[field]; exists {
+        return !excluded
+    }
+    
+    return true
+}
+
+// normalizeIndexValue applique la normalisation commune aux écritures et aux recherches
+func normalizeIndexValue(value string) string {
+    return strings.ToLower(strings.TrimSpace(value))
+}
+
+// fieldValueString convertit la valeur d'un champ JSON en chaîne indexable
+func fieldValueString(value interface{}) string {
+    if s, ok := scalarString(value); ok {
+        return s
+    }
+    if value == nil {
+        return ""
+    }
+    
+    // Objet ou tableau: JSON plutôt que la forme Go (map[...])
+    raw, _ := json.Marshal(value)
+    return string(raw)
+}
+
+// indexPrefix retourne le préfixe commun à toutes les entrées d'un index
+func indexPrefix(recordType, field string) string {
+    return fmt.Sprintf("idx:%s:%s:", recordType, field)
+}
+
+// indexValuePrefix retourne le préfixe des entrées d'un index pour une valeur normalisée
+func indexValuePrefix(recordType, field, normalizedValue string) string {
+    return fmt.Sprintf("idx:%s:%s:%s:", recordType, field, normalizedValue)
+}
+
+// indexKey construit la clé d'une entrée d'index: idx:<type>:<champ>:<valeur>:<clé primaire>
+func indexKey(recordType, field, normalizedValue, primaryKey string) string {
+    return fmt.Sprintf("idx:%s:%s:%s:%s", recordType, field, normalizedValue, primaryKey)
+}
+
+// recordTypeOf extrait le type d'enregistrement d'une clé primaire (order:xxx → order)
+func recordTypeOf(key string) string {
+    if i := strings.Index(key, ":"); i > 0 {
+        return key[:i]
+    }
+    return ""
 }
\ No newline at end of file
@@ -0,0 +1,212 @@
+// pkg/leveldb/indextxn.go
+// Maintenance des index déclarés lors des écritures du Client et contraintes d'unicité
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// ConstraintError signale la violation d'un index unique
+type ConstraintError struct {
+    RecordType  string
+    Field       string
+    Value       string
+    Key         string // clé primaire refusée
+    ExistingKey string // clé primaire qui détient déjà la valeur
+}
+
+func (e *ConstraintError) Error() string {
+    return fmt.Sprintf("contrainte d'unicité violée: %s.%s=%q déjà utilisé par %s (écriture de %s refusée)",
+        e.RecordType, e.Field, e.Value, e.ExistingKey, e.Key)
+}
+
+// indexTxn ajoute à un lot d'écriture les mises à jour des index déclarés
+// (_index:) pour les documents écrits ou supprimés dans ce lot
+type indexTxn struct {
+    db       *leveldb.DB
+    idx      *Indexer
+    entries  *indexBatch // entrées et compteurs, ajoutés au lot du Client
+    defs     map[string][]IndexDef
+    reserved map[string]string // préfixe de valeur unique → clé primaire du lot
+}
+
+func newIndexTxn(db *leveldb.DB, batch *leveldb.Batch) *indexTxn {
+    return &indexTxn{
+        db:       db,
+        idx:      NewIndexer(db),
+        entries:  &indexBatch{db: db, batch: batch, present: make(map[string]bool)},
+        defs:     make(map[string][]IndexDef),
+        reserved: make(map[string]string),
+    }
+}
+
+func (t *indexTxn) defsFor(recordType string) ([]IndexDef, error) {
+    if defs, ok := t.defs[recordType]; ok {
+        return defs, nil
+    }
+    
+    var defs []IndexDef
+    if recordType != "" {
+        var err error
+        defs, err = t.idx.ListIndexDefs(recordType)
+        if err != nil {
+            return nil, err
+        }
+    }
+    
+    // Un normaliseur personnalisé non enregistré dans ce processus produirait
+    // des entrées incohérentes: l'écriture est refusée
+    for _, def := range defs {
+        if _, err := lookupNormalizer(def.Normalizer); err != nil {
+            return nil, fmt.Errorf("index %s:%s: %v", def.RecordType, def.IndexName(), err)
+        }
+    }
+    
+    t.defs[recordType] = defs
+    return defs, nil
+}
+
+// put remplace les entrées d'index de l'ancienne version de key par celles de
+// la nouvelle, en refusant toute valeur déjà prise dans un index unique
+func (t *indexTxn) put(key string, dataBytes []byte) error {
+    if err := t.delete(key); err != nil {
+        return err
+    }
+    return t.add(key, dataBytes)
+}
+
+// add ajoute les entrées d'index de la nouvelle version de key. Les anciennes
+// entrées doivent déjà être retirées (delete): pour un lot de plusieurs
+// documents, toutes les suppressions précèdent les vérifications d'unicité,
+// sans quoi une valeur passée d'une clé à une autre ne serait acceptée que
+// dans un sens.
+func (t *indexTxn) add(key string, dataBytes []byte) error {
+    recordType := recordTypeOf(key)
+    defs, err := t.defsFor(recordType)
+    if err != nil || len(defs) == 0 {
+        return err
+    }
+    
+    var newData map[string]interface{}
+    if err := json.Unmarshal(dataBytes, &newData); err != nil {
+        newData = nil
+    }
+    
+    for i := range defs {
+        def := &defs[i]
+        
+        for _, normalizedValue := range t.idx.indexValues(def, newData) {
+            if def.Unique {
+                if err := t.checkUnique(def, normalizedValue, key); err != nil {
+                    return err
+                }
+            }
+            
+            newKey := indexKey(recordType, def.IndexName(), normalizedValue, key)
+            if err := t.entries.put(def, normalizedValue, newKey, def.entryValue(key, newData)); err != nil {
+                return err
+            }
+        }
+    }
+    
+    return nil
+}
+
+// delete retire les entrées d'index de la version actuelle de key
+func (t *indexTxn) delete(key string) error {
+    recordType := recordTypeOf(key)
+    defs, err := t.defsFor(recordType)
+    if err != nil || len(defs) == 0 {
+        return err
+    }
+    
+    oldData, err := t.oldData(key)
+    if err != nil {
+        return err
+    }
+    
+    for i := range defs {
+        if err := t.removeEntries(&defs[i], key, oldData); err != nil {
+            return err
+        }
+    }
+    
+    return nil
+}
+
+// removeEntries supprime du lot les entrées d'index produites par oldData
+func (t *indexTxn) removeEntries(def *IndexDef, key string, oldData map[string]interface{}) error {
+    for _, normalizedValue := range t.idx.indexValues(def, oldData) {
+        oldKey := indexKey(def.RecordType, def.IndexName(), normalizedValue, key)
+        if err := t.entries.delete(def, normalizedValue, oldKey); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// flush ajoute au lot les compteurs de statistiques des index modifiés; à
+// appeler une fois toutes les écritures du lot préparées
+func (t *indexTxn) flush() error {
+    return t.entries.stats.apply(t.db, t.entries.batch)
+}
+
+// checkUnique vérifie qu'aucune autre clé primaire (sur disque ou plus tôt
+// dans le lot) ne détient déjà la valeur
+func (t *indexTxn) checkUnique(def *IndexDef, normalizedValue, key string) error {
+    prefix := indexValuePrefix(def.RecordType, def.IndexName(), normalizedValue)
+    
+    violation := func(existing string) error {
+        return &ConstraintError{
+            RecordType:  def.RecordType,
+            Field:       def.IndexName(),
+            Value:       normalizedValue,
+            Key:         key,
+            ExistingKey: existing,
+        }
+    }
+    
+    if owner, ok := t.reserved[prefix]; ok && owner != key {
+        return violation(owner)
+    }
+    
+    iter := t.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        primaryKey, _ := decodeIndexValue(iter.Value())
+        if present, ok := t.entries.present[string(iter.Key())]; primaryKey == key || (ok && !present) {
+            continue
+        }
+        return violation(primaryKey)
+    }
+    
+    if err := iter.Error(); err != nil {
+        return fmt.Errorf("erreur vérification unicité: %v", err)
+    }
+    
+    t.reserved[prefix] = key
+    return nil
+}
+
+// oldData retourne les données actuellement stockées pour key (nil si absente)
+func (t *indexTxn) oldData(key string) (map[string]interface{}, error) {
+    value, err := t.db.Get([]byte(key), nil)
+    if err == leveldb.ErrNotFound {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lecture %s: %v", key, err)
+    }
+    
+    data, err := decodeDocument(value)
+    if err != nil {
+        return nil, nil
+    }
+    return data, nil
+}
\ No newline at end of file
@@ -0,0 +1,230 @@
+// pkg/leveldb/join.go
+// Jointures entre registres (lead → seller, ...): boucle imbriquée sur index et jointure par hachage
+
+package leveldb
+
+import (
+    "fmt"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+)
+
+// Stratégies de jointure
+const (
+    JoinAuto       = ""       // boucle imbriquée si le côté droit est accessible par clé ou index, sinon hachage
+    JoinNestedLoop = "nested" // une recherche (clé primaire ou index) par document gauche
+    JoinHash       = "hash"   // un parcours du côté droit, puis sondage d'une table en mémoire
+)
+
+// JoinSpec décrit une jointure d'égalité Left.LeftField = Right.RightField
+type JoinSpec struct {
+    Left       string    // type gauche (ex: lead)
+    LeftField  string    // champ de référence, champ ou chemin JSON (ex: seller_id)
+    Right      string    // type droit (ex: seller)
+    RightField string    // champ référencé; vide = clé primaire <Right>:<valeur>
+    Where      Predicate // filtre sur les documents gauches (optionnel)
+    Strategy   string
+    LeftOuter  bool // conserver les documents gauches sans correspondance
+    Limit      int  // nombre maximal de lignes (0 = illimité)
+}
+
+// JoinRow associe un document gauche à un document droit (Right nil en jointure externe)
+type JoinRow struct {
+    LeftKey  string
+    Left     map[string]interface{}
+    RightKey string
+    Right    map[string]interface{}
+}
+
+// JoinResult contient les lignes et la façon dont la jointure a été exécutée
+type JoinResult struct {
+    Rows     []JoinRow
+    Strategy string
+    Plan     string // plan d'accès du côté gauche
+    Lookups  int    // recherches côté droit (boucle imbriquée) ou documents droits hachés
+}
+
+type joinDoc struct {
+    key  string
+    data map[string]interface{}
+}
+
+// Join exécute une jointure d'égalité entre deux types d'enregistrements
+func (idx *Indexer) Join(spec JoinSpec) (*JoinResult, error) {
+    if spec.Left == "" || spec.LeftField == "" || spec.Right == "" {
+        return nil, fmt.Errorf("jointure incomplète: type gauche, champ de référence et type droit requis")
+    }
+    
+    strategy := spec.Strategy
+    if strategy == JoinAuto {
+        strategy = JoinHash
+        if spec.RightField == "" || idx.hasIndex(spec.Right, spec.RightField) {
+            strategy = JoinNestedLoop
+        }
+    }
+    
+    var rightDef *IndexDef
+    if spec.RightField != "" {
+        var err error
+        if rightDef, err = idx.GetIndexDef(spec.Right, spec.RightField); err != nil {
+            return nil, err
+        }
+    }
+    
+    var lookup func(value string) ([]joinDoc, error)
+    result := &JoinResult{Strategy: strategy}
+    
+    switch strategy {
+    case JoinNestedLoop:
+        if spec.RightField != "" && !idx.hasIndex(spec.Right, spec.RightField) {
+            return nil, fmt.Errorf("boucle imbriquée impossible: %s.%s n'est pas indexé", spec.Right, spec.RightField)
+        }
+        lookup = func(value string) ([]joinDoc, error) {
+            result.Lookups++
+            return idx.joinLookup(spec.Right, spec.RightField, value)
+        }
+    
+    case JoinHash:
+        table, err := idx.joinHashTable(spec.Right, spec.RightField, rightDef)
+        if err != nil {
+            return nil, err
+        }
+        for _, docs := range table {
+            result.Lookups += len(docs)
+        }
+        lookup = func(value string) ([]joinDoc, error) {
+            return table[value], nil
+        }
+    
+    default:
+        return nil, fmt.Errorf("stratégie de jointure inconnue: %q", spec.Strategy)
+    }
+    
+    cursor, err := idx.Query(Query{RecordType: spec.Left, Where: spec.Where})
+    if err != nil {
+        return nil, err
+    }
+    defer cursor.Close()
+    result.Plan = cursor.Plan()
+    
+    for cursor.Next() {
+        leftKey := cursor.Key()
+        left, err := idx.getDocument(leftKey)
+        if err != nil {
+            return nil, err
+        }
+        if left == nil {
+            continue
+        }
+        
+        // Les clés primaires sont sensibles à la casse; SearchByIndex applique le
+        // normaliseur de l'index droit, la table de hachage est déjà normalisée
+        values := fieldScalars(left, spec.LeftField)
+        if strategy == JoinHash && spec.RightField != "" {
+            values = rightDef.normalizeAll(values)
+        }
+        
+        matched := false
+        for _, value := range values {
+            docs, err := lookup(value)
+            if err != nil {
+                return nil, err
+            }
+            for _, right := range docs {
+                result.Rows = append(result.Rows, JoinRow{LeftKey: leftKey, Left: left, RightKey: right.key, Right: right.data})
+                matched = true
+                if spec.Limit > 0 && len(result.Rows) >= spec.Limit {
+                    return result, nil
+                }
+            }
+        }
+        
+        if !matched && spec.LeftOuter {
+            result.Rows = append(result.Rows, JoinRow{LeftKey: leftKey, Left: left})
+            if spec.Limit > 0 && len(result.Rows) >= spec.Limit {
+                return result, nil
+            }
+        }
+    }
+    
+    if err := cursor.Error(); err != nil {
+        return nil, fmt.Errorf("erreur parcours %s: %v", spec.Left, err)
+    }
+    
+    return result, nil
+}
+
+// joinLookup retrouve les documents droits d'une valeur: par clé primaire si
+// field est vide, sinon par l'index field
+func (idx *Indexer) joinLookup(recordType, field, value string) ([]joinDoc, error) {
+    var keys []string
+    if field == "" {
+        keys = []string{recordType + ":" + value}
+    } else {
+        var err error
+        keys, err = idx.SearchByIndex(recordType, field, value)
+        if err != nil {
+            return nil, err
+        }
+    }
+    
+    var docs []joinDoc
+    for _, key := range keys {
+        data, err := idx.getDocument(key)
+        if err != nil {
+            return nil, err
+        }
+        if data != nil {
+            docs = append(docs, joinDoc{key: key, data: data})
+        }
+    }
+    return docs, nil
+}
+
+// joinHashTable parcourt une fois le côté droit et range ses documents par
+// valeur normalisée du champ référencé (ou par identifiant de clé primaire).
+// Le normaliseur est celui de l'index déclaré sur le champ (casefold sinon).
+func (idx *Indexer) joinHashTable(recordType, field string, def *IndexDef) (map[string][]joinDoc, error) {
+    table := make(map[string][]joinDoc)
+    prefix := recordType + ":"
+    
+    err := idx.scanDocuments(recordType, func(primaryKey string, data map[string]interface{}) error {
+        doc := joinDoc{key: primaryKey, data: data}
+        if field == "" {
+            table[primaryKey[len(prefix):]] = append(table[primaryKey[len(prefix):]], doc)
+            return nil
+        }
+        for _, value := range def.normalizeAll(fieldScalars(data, field)) {
+            table[value] = append(table[value], doc)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    
+    return table, nil
+}
+
+// hasIndex indique si un champ est indexé par valeur (définition ou entrées existantes)
+func (idx *Indexer) hasIndex(recordType, field string) bool {
+    p := &planner{idx: idx, recordType: recordType, indexed: make(map[string]bool)}
+    return p.isIndexed(field)
+}
+
+// getDocument lit et décode un document (nil s'il est absent ou illisible)
+func (idx *Indexer) getDocument(key string) (map[string]interface{}, error) {
+    value, err := idx.db.Get([]byte(key), nil)
+    if err == leveldb.ErrNotFound {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lecture %s: %v", key, err)
+    }
+    
+    data, err := decodeDocument(value)
+    if err != nil {
+        return nil, nil
+    }
+    return data, nil
+}
\ No newline at end of file
@@ -0,0 +1,183 @@
+// pkg/leveldb/jsonpath.go
+// Chemins JSON des index (address.city, items[*].product_id) et valeurs indexables typées
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// pathStep est une étape d'un chemin: un champ d'objet, un élément de tableau ou [*]
+type pathStep struct {
+    field    string
+    index    int
+    isIndex  bool
+    wildcard bool
+}
+
+// isJSONPath indique si un nom de champ d'index est un chemin et non un champ de premier niveau
+func isJSONPath(field string) bool {
+    return strings.ContainsAny(field, ".[")
+}
+
+// parsePath découpe un chemin: a.b, a[0].b, a[*].b, a[*][*]
+func parsePath(path string) ([]pathStep, error) {
+    var steps []pathStep
+    
+    for _, part := range strings.Split(path, ".") {
+        name := part
+        brackets := ""
+        if i := strings.IndexByte(part, '['); i >= 0 {
+            name, brackets = part[:i], part[i:]
+        }
+        
+        if name == "" && (brackets == "" || len(steps) == 0) {
+            return nil, fmt.Errorf("chemin invalide %q: segment vide", path)
+        }
+        if name != "" {
+            steps = append(steps, pathStep{field: name})
+        }
+        
+        for brackets != "" {
+            end := strings.IndexByte(brackets, ']')
+            if brackets[0] != '[' || end < 0 {
+                return nil, fmt.Errorf("chemin invalide %q: crochet non fermé", path)
+            }
+            
+            inner := brackets[1:end]
+            if inner == "*" {
+                steps = append(steps, pathStep{wildcard: true})
+            } else {
+                n, err := strconv.Atoi(inner)
+                if err != nil || n < 0 {
+                    return nil, fmt.Errorf("chemin invalide %q: indice %q", path, inner)
+                }
+                steps = append(steps, pathStep{index: n, isIndex: true})
+            }
+            brackets = brackets[end+1:]
+        }
+    }
+    
+    return steps, nil
+}
+
+// evalPath retourne les valeurs atteintes par un chemin; [*] produit une valeur
+// par élément du tableau
+func evalPath(data interface{}, steps []pathStep) []interface{} {
+    current := []interface{}{data}
+    
+    for _, step := range steps {
+        var next []interface{}
+        for _, v := range current {
+            switch {
+            case step.wildcard:
+                if arr, ok := v.([]interface{}); ok {
+                    next = append(next, arr...)
+                }
+            case step.isIndex:
+                if arr, ok := v.([]interface{}); ok && step.index < len(arr) {
+                    next = append(next, arr[step.index])
+                }
+            default:
+                if obj, ok := v.(map[string]interface{}); ok {
+                    if child, ok := obj[step.field]; ok {
+                        next = append(next, child)
+                    }
+                }
+            }
+        }
+        current = next
+    }
+    
+    return current
+}
+
+// FieldValue retourne la valeur d'un champ, ou les valeurs atteintes par un
+// chemin JSON (items[*].price): nil si aucune, la valeur seule, ou une liste
+func FieldValue(data map[string]interface{}, field string) interface{} {
+    if !isJSONPath(field) {
+        return data[field]
+    }
+    
+    steps, err := parsePath(field)
+    if err != nil {
+        return nil
+    }
+    values := evalPath(data, steps)
+    switch len(values) {
+    case 0:
+        return nil
+    case 1:
+        return values[0]
+    }
+    return values
+}
+
+// indexFieldValues retourne les valeurs normalisées (sans doublon) qu'un champ
+// ou un chemin produit pour un document
+func indexFieldValues(data map[string]interface{}, field string) []string {
+    var values []string
+    for _, s := range fieldScalars(data, field) {
+        if normalized := normalizeIndexValue(s); !containsString(values, normalized) {
+            values = append(values, normalized)
+        }
+    }
+    return values
+}
+
+// fieldScalars retourne les scalaires (sans doublon, non normalisés) qu'un champ
+// ou un chemin produit. Un tableau de scalaires produit une valeur par élément,
+// un objet n'en produit aucune.
+func fieldScalars(data map[string]interface{}, field string) []string {
+    var raw []interface{}
+    if isJSONPath(field) {
+        steps, err := parsePath(field)
+        if err != nil {
+            return nil
+        }
+        raw = evalPath(data, steps)
+    } else if value, ok := data[field]; ok {
+        raw = []interface{}{value}
+    }
+    
+    var values []string
+    for _, v := range raw {
+        if arr, ok := v.([]interface{}); ok {
+            for _, elem := range arr {
+                values = appendScalarValue(values, elem)
+            }
+            continue
+        }
+        values = appendScalarValue(values, v)
+    }
+    
+    return values
+}
+
+func appendScalarValue(values []string, v interface{}) []string {
+    s, ok := scalarString(v)
+    if !ok || containsString(values, s) {
+        return values
+    }
+    return append(values, s)
+}
+
+// scalarString convertit un scalaire JSON en chaîne canonique: les nombres sans
+// notation exponentielle (1e+06 → 1000000), les booléens en true/false.
+// Retourne faux pour null, les objets et les tableaux.
+func scalarString(value interface{}) (string, bool) {
+    switch v := value.(type) {
+    case string:
+        return v, true
+    case float64:
+        return strconv.FormatFloat(v, 'f', -1, 64), true
+    case json.Number:
+        return v.String(), true
+    case bool:
+        return strconv.FormatBool(v), true
+    }
+    return "", false
+}
\ No newline at end of file
@@ -0,0 +1,154 @@
+// pkg/leveldb/mtime.go
+// Index intégré des dates de modification (_mtime:) pour les requêtes "modifié depuis"
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Chaque document écrit par le Client a une entrée:
+//
+//     _mtime:<horodatage UTC RFC3339>:<clé> → ""
+//
+// L'horodatage UTC a une largeur fixe: l'ordre des clés est l'ordre chronologique.
+const mtimePrefix = "_mtime:"
+
+// Largeur d'un horodatage UTC RFC3339 (2006-01-02T15:04:05Z)
+const mtimeWidth = len("2006-01-02T15:04:05Z")
+
+func mtimeKey(ts time.Time, key string) string {
+    return mtimePrefix + ts.UTC().Format(time.RFC3339) + ":" + key
+}
+
+// entryMtimeKey retourne l'entrée _mtime: d'une Entry stockée (faux si son
+// horodatage est illisible)
+func entryMtimeKey(entryBytes []byte, key string) (string, bool) {
+    var entry Entry
+    if err := json.Unmarshal(entryBytes, &entry); err != nil {
+        return "", false
+    }
+    
+    ts, err := time.Parse(time.RFC3339, entry.Timestamp)
+    if err != nil {
+        return "", false
+    }
+    return mtimeKey(ts, key), true
+}
+
+// updateMtime ajoute au lot le déplacement de l'entrée _mtime: de key: l'entrée
+// de la version actuelle est retirée, celle de modified ajoutée (zéro pour une suppression)
+func updateMtime(db *leveldb.DB, batch *leveldb.Batch, key string, modified time.Time) error {
+    old, err := db.Get([]byte(key), nil)
+    if err != nil && err != leveldb.ErrNotFound {
+        return fmt.Errorf("erreur lecture %s: %v", key, err)
+    }
+    if err == nil {
+        if oldKey, ok := entryMtimeKey(old, key); ok {
+            batch.Delete([]byte(oldKey))
+        }
+    }
+    
+    if !modified.IsZero() {
+        batch.Put([]byte(mtimeKey(modified, key)), nil)
+    }
+    return nil
+}
+
+// ModifiedBetween retourne, dans l'ordre chronologique, les clés modifiées entre
+// from et to (bornes incluses, à la seconde; zéro = non bornée)
+func (c *Client) ModifiedBetween(from, to time.Time) ([]string, error) {
+    var keys []string
+    err := c.EachModified(from, to, func(key string) bool {
+        keys = append(keys, key)
+        return true
+    })
+    if err != nil {
+        return nil, err
+    }
+    return keys, nil
+}
+
+// EachModified appelle fn pour chaque clé modifiée entre from et to, dans
+// l'ordre chronologique, sans matérialiser la liste; fn retourne faux pour
+// arrêter le parcours
+func (c *Client) EachModified(from, to time.Time, fn func(key string) bool) error {
+    rng := util.BytesPrefix([]byte(mtimePrefix))
+    if !from.IsZero() {
+        rng.Start = []byte(mtimePrefix + from.UTC().Format(time.RFC3339))
+    }
+    if !to.IsZero() {
+        // ';' suit ':' : toutes les clés de la dernière seconde sont incluses
+        rng.Limit = []byte(mtimePrefix + to.UTC().Format(time.RFC3339) + ";")
+    }
+    
+    iter := c.db.NewIterator(rng, nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        // _mtime:2024-01-01T00:00:00Z:<clé>, horodatage de largeur fixe
+        rest := strings.TrimPrefix(string(iter.Key()), mtimePrefix)
+        if len(rest) <= mtimeWidth+1 {
+            continue
+        }
+        if !fn(rest[mtimeWidth+1:]) {
+            break
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return fmt.Errorf("erreur itération: %v", err)
+    }
+    return nil
+}
+
+// RebuildModifiedIndex reconstruit l'index _mtime: depuis les horodatages des
+// documents (données chargées avant l'index ou restaurées sans le Client)
+func (c *Client) RebuildModifiedIndex() (int, error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    
+    if err := NewIndexer(c.db).deletePrefix(mtimePrefix); err != nil {
+        return 0, fmt.Errorf("erreur purge index %s: %v", mtimePrefix, err)
+    }
+    
+    iter := c.db.NewIterator(nil, nil)
+    defer iter.Release()
+    
+    batch := new(leveldb.Batch)
+    created := 0
+    for iter.Next() {
+        key := string(iter.Key())
+        if key == "" || key[0] == '_' || strings.HasPrefix(key, "idx:") {
+            continue
+        }
+        
+        mkey, ok := entryMtimeKey(iter.Value(), key)
+        if !ok {
+            continue
+        }
+        batch.Put([]byte(mkey), nil)
+        created++
+        
+        if batch.Len() >= rebuildBatchSize {
+            if err := c.db.Write(batch, nil); err != nil {
+                return created, fmt.Errorf("erreur écriture lot: %v", err)
+            }
+            batch.Reset()
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return created, fmt.Errorf("erreur itération: %v", err)
+    }
+    if err := c.db.Write(batch, nil); err != nil {
+        return created, fmt.Errorf("erreur écriture lot: %v", err)
+    }
+    return created, nil
+}
\ No newline at end of file
@@ -0,0 +1,118 @@
+// pkg/leveldb/normalize.go
+// Normaliseurs des valeurs d'index: exact, casse, accents, numérique et fonctions enregistrées
+
+package leveldb
+
+import (
+    "fmt"
+    "strings"
+    "sync"
+)
+
+// Normaliseurs prédéfinis
+const (
+    NormalizerCaseFold   = "casefold"   // minuscules, espaces de bord retirés (défaut)
+    NormalizerExact      = "exact"      // valeur inchangée (identifiants sensibles à la casse)
+    NormalizerAccentFold = "accentfold" // casefold sans accents: "São Paulo" → "sao paulo"
+    NormalizerNumeric    = "numeric"    // nombre encodé pour un tri numérique des clés
+)
+
+// Normalizer convertit une valeur en sa forme indexée; faux si la valeur ne
+// doit pas être indexée (ex: texte dans un index numérique)
+type Normalizer func(value string) (string, bool)
+
+var (
+    normalizersMu sync.RWMutex
+    normalizers   = map[string]Normalizer{
+        NormalizerCaseFold:   caseFold,
+        NormalizerExact:      func(v string) (string, bool) { return v, true },
+        NormalizerAccentFold: accentFold,
+        NormalizerNumeric:    numericNormalizer,
+    }
+)
+
+// Table de repli des caractères accentués du portugais (et du français)
+var accentReplacer = strings.NewReplacer(
+    "á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+    "é", "e", "è", "e", "ê", "e", "ë", "e",
+    "í", "i", "ì", "i", "î", "i", "ï", "i",
+    "ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+    "ú", "u", "ù", "u", "û", "u", "ü", "u",
+    "ç", "c", "ñ", "n", "ÿ", "y",
+)
+
+// RegisterNormalizer enregistre un normaliseur personnalisé utilisable dans
+// IndexDef.Normalizer. Il doit être enregistré avant toute écriture ou recherche
+// sur les index qui l'utilisent, dans chaque processus.
+func RegisterNormalizer(name string, fn Normalizer) error {
+    if name == "" || strings.Contains(name, ":") || fn == nil {
+        return fmt.Errorf("normaliseur invalide: %q", name)
+    }
+    
+    normalizersMu.Lock()
+    defer normalizersMu.Unlock()
+    
+    switch name {
+    case NormalizerCaseFold, NormalizerExact, NormalizerAccentFold, NormalizerNumeric:
+        return fmt.Errorf("normaliseur prédéfini non remplaçable: %s", name)
+    }
+    normalizers[name] = fn
+    return nil
+}
+
+// lookupNormalizer retourne un normaliseur par nom ("" = casefold)
+func lookupNormalizer(name string) (Normalizer, error) {
+    if name == "" {
+        name = NormalizerCaseFold
+    }
+    
+    normalizersMu.RLock()
+    fn, ok := normalizers[name]
+    normalizersMu.RUnlock()
+    
+    if !ok {
+        return nil, fmt.Errorf("normaliseur %q non enregistré", name)
+    }
+    return fn, nil
+}
+
+func caseFold(value string) (string, bool) {
+    return normalizeIndexValue(value), true
+}
+
+func accentFold(value string) (string, bool) {
+    return accentReplacer.Replace(normalizeIndexValue(value)), true
+}
+
+func numericNormalizer(value string) (string, bool) {
+    n, ok := toFloat(value)
+    if !ok {
+        return "", false
+    }
+    return encodeSortableFloat(n), true
+}
+
+// normalize applique le normaliseur de l'index (casefold pour une définition
+// absente). Un normaliseur non enregistré n'indexe rien: defsFor refuse alors l'écriture.
+func (d *IndexDef) normalize(value string) (string, bool) {
+    if d == nil {
+        return caseFold(value)
+    }
+    
+    fn, err := lookupNormalizer(d.Normalizer)
+    if err != nil {
+        return "", false
+    }
+    return fn(value)
+}
+
+// normalizeAll applique le normaliseur à chaque valeur, sans doublon
+func (d *IndexDef) normalizeAll(values []string) []string {
+    var normalized []string
+    for _, v := range values {
+        if n, ok := d.normalize(v); ok && !containsString(normalized, n) {
+            normalized = append(normalized, n)
+        }
+    }
+    return normalized
+}
\ No newline at end of file
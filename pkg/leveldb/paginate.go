@@ -0,0 +1,344 @@
+// pkg/leveldb/paginate.go
+// Résultats d'index triés, pagination par jeton de continuation et itérateur en flux
+
+package leveldb
+
+import (
+    "bytes"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "strings"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/iterator"
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Taille de page par défaut de SearchPage
+const defaultPageSize = 100
+
+// SearchOptions contrôle l'ordre et la pagination d'une recherche par index
+type SearchOptions struct {
+    OrderBy  string // champ indexé servant au tri (vide = ordre de clé primaire)
+    Desc     bool
+    PageSize int
+    After    string // jeton retourné par la page précédente
+}
+
+// SearchPage est une page de résultats et le jeton de la page suivante
+type SearchPage struct {
+    Keys []string
+    Next string // vide s'il n'y a plus de résultats
+}
+
+// pageToken repère la dernière entrée lue: valeur de tri et clé primaire
+type pageToken struct {
+    OrderBy string `json:"o,omitempty"`
+    Value   string `json:"v,omitempty"`
+    Key     string `json:"k"`
+    Missing bool   `json:"m,omitempty"` // parmi les documents sans valeur de tri
+}
+
+func encodePageToken(t pageToken) string {
+    raw, _ := json.Marshal(t)
+    return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodePageToken(s string) (*pageToken, error) {
+    raw, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil {
+        return nil, fmt.Errorf("jeton de continuation invalide: %v", err)
+    }
+    
+    var t pageToken
+    if err := json.Unmarshal(raw, &t); err != nil {
+        return nil, fmt.Errorf("jeton de continuation invalide: %v", err)
+    }
+    return &t, nil
+}
+
+// IndexIterator parcourt en flux les clés primaires d'une recherche par index,
+// sans matérialiser la liste complète des résultats
+type IndexIterator struct {
+    db           *leveldb.DB
+    idx          *Indexer
+    iter         iterator.Iterator
+    prefix       string // préfixe parcouru (index de filtre ou de tri)
+    filterPrefix string // avec OrderBy: préfixe de la valeur recherchée dans l'index de filtre
+    field        string    // champ recherché
+    filterDef    *IndexDef // définition de l'index recherché (nil si non déclaré)
+    sortDef      *IndexDef // avec OrderBy: index de tri, pour relire les valeurs d'un document
+    missing      bool      // avec OrderBy: passe des documents sans valeur de tri
+    orderBy      string
+    desc         bool
+    resume       []byte
+    started      bool
+    key          string
+    sortValue    string
+    fields       map[string]interface{} // projection de l'entrée courante (index couvrant)
+    err          error
+    steps        int // pas d'itérateur (voir ExplainSearch)
+    reads        int // tests d'appartenance à l'index de filtre
+}
+
+// IterateIndex retourne un itérateur sur les documents où field = value,
+// triés par clé primaire ou par le champ indexé opts.OrderBy.
+//
+// Avec un autre champ de tri, l'index de tri est parcouru depuis le début (ou
+// le jeton) et chaque entrée est testée dans l'index recherché: une page coûte
+// jusqu'à un parcours complet de l'index de tri, plus une lecture de document
+// par résultat. Un document dont le champ de tri est un tableau apparaît une
+// fois, à sa plus petite valeur (à la plus grande en ordre décroissant); les
+// documents sans valeur de tri viennent en fin de résultats, par clé primaire.
+func (idx *Indexer) IterateIndex(recordType, field, value string, opts SearchOptions) (*IndexIterator, error) {
+    valuePrefix := indexValuePrefix(recordType, field, idx.searchValue(recordType, field, value))
+    
+    filterDef, err := idx.GetIndexDef(recordType, field)
+    if err != nil {
+        return nil, err
+    }
+    
+    it := &IndexIterator{db: idx.db, idx: idx, desc: opts.Desc, orderBy: opts.OrderBy, field: field, filterDef: filterDef}
+    
+    if opts.OrderBy == "" || opts.OrderBy == field {
+        it.prefix = valuePrefix
+        it.orderBy = ""
+    } else {
+        // Parcours de l'index de tri, filtré par appartenance à l'index recherché
+        sortDef, err := idx.fieldIndexDef(recordType, opts.OrderBy)
+        if err != nil {
+            return nil, err
+        }
+        it.sortDef = sortDef
+        it.prefix = indexPrefix(recordType, opts.OrderBy)
+        it.filterPrefix = valuePrefix
+    }
+    
+    if opts.After != "" {
+        token, err := decodePageToken(opts.After)
+        if err != nil {
+            return nil, err
+        }
+        if token.OrderBy != it.orderBy {
+            return nil, fmt.Errorf("jeton de continuation émis pour un autre tri (%q)", token.OrderBy)
+        }
+        if token.Missing && it.filterPrefix != "" {
+            it.missing = true
+            it.prefix = it.filterPrefix
+            it.resume = []byte(it.prefix + token.Key)
+        } else if it.filterPrefix != "" {
+            it.resume = []byte(it.prefix + token.Value + ":" + token.Key)
+        } else {
+            it.resume = []byte(it.prefix + token.Key)
+        }
+    }
+    
+    it.iter = idx.db.NewIterator(util.BytesPrefix([]byte(it.prefix)), nil)
+    return it, nil
+}
+
+func (it *IndexIterator) Next() bool {
+    for {
+        var ok bool
+        switch {
+        case !it.started:
+            it.started = true
+            ok = it.first()
+        case it.desc:
+            ok = it.iter.Prev()
+        default:
+            ok = it.iter.Next()
+        }
+        it.steps++
+        if !ok {
+            if it.filterPrefix == "" || it.missing || it.iter.Error() != nil {
+                return false
+            }
+            // Index de tri épuisé: documents de la recherche sans valeur de tri
+            it.iter.Release()
+            it.iter = it.db.NewIterator(util.BytesPrefix([]byte(it.filterPrefix)), nil)
+            it.prefix = it.filterPrefix
+            it.missing = true
+            it.started = false
+            it.resume = nil
+            continue
+        }
+        
+        primaryKey, fields := decodeIndexValue(it.iter.Value())
+        sortValue := ""
+        if it.filterPrefix != "" && !it.missing {
+            sortValue = strings.TrimSuffix(strings.TrimPrefix(string(it.iter.Key()), it.prefix), ":"+primaryKey)
+        }
+        
+        if it.filterPrefix != "" {
+            emit, err := it.accept(primaryKey, sortValue)
+            if err != nil {
+                it.err = err
+                return false
+            }
+            if !emit {
+                continue
+            }
+        }
+        
+        it.key = primaryKey
+        it.sortValue = sortValue
+        it.fields = fields
+        return true
+    }
+}
+
+// accept indique si une entrée de l'index de tri (ou, dans la passe des
+// documents sans valeur de tri, de l'index recherché) doit être retournée
+func (it *IndexIterator) accept(primaryKey, sortValue string) (bool, error) {
+    if !it.missing {
+        it.reads++
+        member, err := it.db.Has([]byte(it.filterPrefix+primaryKey), nil)
+        if err != nil || !member {
+            return false, err
+        }
+    }
+    
+    it.reads++
+    value, err := it.db.Get([]byte(primaryKey), nil)
+    if err == leveldb.ErrNotFound {
+        return !it.missing, nil
+    }
+    if err != nil {
+        return false, err
+    }
+    data, err := decodeDocument(value)
+    if err != nil {
+        return !it.missing, nil
+    }
+    
+    values := it.idx.indexValues(it.sortDef, data)
+    if it.missing {
+        return len(values) == 0, nil
+    }
+    
+    // Un tableau a une entrée par valeur: seule la première rencontrée compte
+    for _, v := range values {
+        if it.desc && v > sortValue || !it.desc && v < sortValue {
+            return false, nil
+        }
+    }
+    return true, nil
+}
+
+// first positionne l'itérateur au début du parcours ou juste après le jeton
+func (it *IndexIterator) first() bool {
+    if it.resume == nil {
+        if it.desc {
+            return it.iter.Last()
+        }
+        return it.iter.First()
+    }
+    
+    found := it.iter.Seek(it.resume)
+    if it.desc {
+        if found {
+            return it.iter.Prev()
+        }
+        return it.iter.Last()
+    }
+    if found && bytes.Equal(it.iter.Key(), it.resume) {
+        return it.iter.Next()
+    }
+    return found
+}
+
+// Key retourne la clé primaire courante
+func (it *IndexIterator) Key() string {
+    return it.key
+}
+
+// SortValue retourne la valeur (normalisée) du champ de tri pour l'entrée courante
+func (it *IndexIterator) SortValue() string {
+    return it.sortValue
+}
+
+// Fields retourne la projection stockée dans l'entrée courante (nil si l'index
+// parcouru n'est pas couvrant)
+func (it *IndexIterator) Fields() map[string]interface{} {
+    return it.fields
+}
+
+// Covers indique si l'index parcouru pour l'entrée courante (index recherché,
+// ou index de tri avec OrderBy) projette tous les champs demandés, le champ
+// recherché excepté: Fields suffit alors sans relire le document
+func (it *IndexIterator) Covers(fields []string) bool {
+    def := it.filterDef
+    if it.filterPrefix != "" && !it.missing {
+        def = it.sortDef
+    }
+    if def == nil || len(def.Project) == 0 {
+        return false
+    }
+    for _, f := range fields {
+        if f != it.field && !containsString(def.Project, f) {
+            return false
+        }
+    }
+    return true
+}
+
+// Token retourne le jeton permettant de reprendre après l'entrée courante
+func (it *IndexIterator) Token() string {
+    t := pageToken{OrderBy: it.orderBy, Key: it.key, Missing: it.missing}
+    if it.orderBy != "" {
+        t.Value = it.sortValue
+    }
+    return encodePageToken(t)
+}
+
+func (it *IndexIterator) Error() error {
+    if it.err != nil {
+        return it.err
+    }
+    return it.iter.Error()
+}
+
+func (it *IndexIterator) Release() {
+    it.iter.Release()
+}
+
+// SearchPage retourne une page de résultats triés et le jeton de la suivante
+func (idx *Indexer) SearchPage(recordType, field, value string, opts SearchOptions) (*SearchPage, error) {
+    page, _, err := idx.searchPage(recordType, field, value, opts)
+    return page, err
+}
+
+// searchPage lit une page et retourne aussi l'itérateur (libéré) et ses compteurs
+func (idx *Indexer) searchPage(recordType, field, value string, opts SearchOptions) (*SearchPage, *IndexIterator, error) {
+    pageSize := opts.PageSize
+    if pageSize <= 0 {
+        pageSize = defaultPageSize
+    }
+    
+    it, err := idx.IterateIndex(recordType, field, value, opts)
+    if err != nil {
+        return nil, nil, err
+    }
+    defer it.Release()
+    
+    page := &SearchPage{}
+    last := ""
+    
+    for it.Next() {
+        if len(page.Keys) == pageSize {
+            // Il reste au moins un résultat: la page suivante reprend après le dernier retenu
+            page.Next = last
+            break
+        }
+        page.Keys = append(page.Keys, it.Key())
+        last = it.Token()
+    }
+    
+    if err := it.Error(); err != nil {
+        return nil, nil, fmt.Errorf("erreur itération: %v", err)
+    }
+    
+    return page, it, nil
+}
\ No newline at end of file
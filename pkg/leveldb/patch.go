@@ -0,0 +1,44 @@
+// pkg/leveldb/patch.go
+// Fusion d'un document avec un patch JSON (RFC 7386, JSON Merge Patch)
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+)
+
+// MergePatch applique patch à doc: les champs du patch remplacent ceux du
+// document, null supprime un champ, un objet est fusionné récursivement.
+// Un patch qui n'est pas un objet remplace le document entier.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+    var target, changes interface{}
+    if err := json.Unmarshal(doc, &target); err != nil {
+        return nil, fmt.Errorf("document invalide: %v", err)
+    }
+    if err := json.Unmarshal(patch, &changes); err != nil {
+        return nil, fmt.Errorf("patch invalide: %v", err)
+    }
+    
+    return json.Marshal(mergeValue(target, changes))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+    fields, ok := patch.(map[string]interface{})
+    if !ok {
+        return patch
+    }
+    
+    doc, ok := target.(map[string]interface{})
+    if !ok {
+        doc = make(map[string]interface{})
+    }
+    for name, value := range fields {
+        if value == nil {
+            delete(doc, name)
+            continue
+        }
+        doc[name] = mergeValue(doc[name], value)
+    }
+    return doc
+}
\ No newline at end of file
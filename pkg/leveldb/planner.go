@@ -0,0 +1,971 @@
+// pkg/leveldb/planner.go
+// Planificateur de requêtes multi-prédicats (AND/OR/IN/NOT) sur index secondaires
+
+package leveldb
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/iterator"
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Predicate est un nœud de l'arbre de conditions d'une requête
+type Predicate interface {
+    Match(data map[string]interface{}) bool
+}
+
+// EqPredicate: field = value
+type EqPredicate struct {
+    Field string
+    Value string
+    
+    def *IndexDef // index du champ, associé par Query (normaliseur déclaré)
+}
+
+// InPredicate: field IN (values...)
+type InPredicate struct {
+    Field  string
+    Values []string
+    
+    def *IndexDef
+}
+
+// RangePredicate: field <, <=, > ou >= value. Une valeur numérique est comparée
+// numériquement (les valeurs non numériques du document ne correspondent pas),
+// une chaîne est comparée après normalisation.
+type RangePredicate struct {
+    Field string
+    Op    string
+    Value string
+    
+    def *IndexDef
+}
+
+// AndPredicate: toutes les conditions doivent être vraies
+type AndPredicate struct {
+    Preds []Predicate
+}
+
+// OrPredicate: au moins une condition doit être vraie
+type OrPredicate struct {
+    Preds []Predicate
+}
+
+// NotPredicate: négation d'une condition
+type NotPredicate struct {
+    Pred Predicate
+}
+
+func Eq(field, value string) Predicate {
+    return &EqPredicate{Field: field, Value: value}
+}
+
+func In(field string, values ...string) Predicate {
+    return &InPredicate{Field: field, Values: values}
+}
+
+func Range(field, op, value string) Predicate {
+    return &RangePredicate{Field: field, Op: op, Value: value}
+}
+
+func And(preds ...Predicate) Predicate {
+    return &AndPredicate{Preds: preds}
+}
+
+func Or(preds ...Predicate) Predicate {
+    return &OrPredicate{Preds: preds}
+}
+
+func Not(pred Predicate) Predicate {
+    return &NotPredicate{Pred: pred}
+}
+
+// Match est vrai si le champ (ou l'un des éléments atteints par le chemin) vaut
+// Value, après le normaliseur de l'index du champ (casefold sans index déclaré)
+func (p *EqPredicate) Match(data map[string]interface{}) bool {
+    want, ok := p.def.normalize(p.Value)
+    if !ok {
+        return false
+    }
+    return containsString(p.def.normalizeAll(fieldScalars(data, p.Field)), want)
+}
+
+func (p *InPredicate) Match(data map[string]interface{}) bool {
+    for _, v := range p.Values {
+        if (&EqPredicate{Field: p.Field, Value: v, def: p.def}).Match(data) {
+            return true
+        }
+    }
+    return false
+}
+
+func (p *RangePredicate) Match(data map[string]interface{}) bool {
+    for _, s := range fieldScalars(data, p.Field) {
+        if p.matchValue(s) {
+            return true
+        }
+    }
+    return false
+}
+
+func (p *RangePredicate) matchValue(s string) bool {
+    var cmp int
+    if want, ok := toFloat(p.Value); ok {
+        got, ok := toFloat(s)
+        if !ok {
+            return false
+        }
+        cmp = compareFloats(got, want)
+    } else {
+        got, ok := p.def.normalize(s)
+        if !ok {
+            return false
+        }
+        want, ok := p.def.normalize(p.Value)
+        if !ok {
+            return false
+        }
+        cmp = strings.Compare(got, want)
+    }
+    
+    switch p.Op {
+    case "<":
+        return cmp < 0
+    case "<=":
+        return cmp <= 0
+    case ">":
+        return cmp > 0
+    case ">=":
+        return cmp >= 0
+    }
+    return false
+}
+
+func compareFloats(a, b float64) int {
+    switch {
+    case a < b:
+        return -1
+    case a > b:
+        return 1
+    }
+    return 0
+}
+
+func (p *AndPredicate) Match(data map[string]interface{}) bool {
+    for _, pred := range p.Preds {
+        if !pred.Match(data) {
+            return false
+        }
+    }
+    return true
+}
+
+func (p *OrPredicate) Match(data map[string]interface{}) bool {
+    for _, pred := range p.Preds {
+        if pred.Match(data) {
+            return true
+        }
+    }
+    return false
+}
+
+func (p *NotPredicate) Match(data map[string]interface{}) bool {
+    return !p.Pred.Match(data)
+}
+
+// bindIndexDefs associe à chaque prédicat de champ la définition de son index:
+// filtres et parcours appliquent alors le normaliseur déclaré, comme l'index
+func (idx *Indexer) bindIndexDefs(recordType string, pred Predicate) {
+    switch pr := pred.(type) {
+    case *EqPredicate:
+        pr.def = idx.valueIndexDef(recordType, pr.Field)
+    case *InPredicate:
+        pr.def = idx.valueIndexDef(recordType, pr.Field)
+    case *RangePredicate:
+        pr.def = idx.valueIndexDef(recordType, pr.Field)
+    case *AndPredicate:
+        for _, child := range pr.Preds {
+            idx.bindIndexDefs(recordType, child)
+        }
+    case *OrPredicate:
+        for _, child := range pr.Preds {
+            idx.bindIndexDefs(recordType, child)
+        }
+    case *NotPredicate:
+        idx.bindIndexDefs(recordType, pr.Pred)
+    }
+}
+
+// valueIndexDef retourne la définition de l'index de valeurs d'un champ, ou nil
+func (idx *Indexer) valueIndexDef(recordType, field string) *IndexDef {
+    def, err := idx.GetIndexDef(recordType, field)
+    if err != nil || def == nil || def.Kind != IndexKindValue || def.Field != field {
+        return nil
+    }
+    return def
+}
+
+// Query décrit une recherche sur un type d'enregistrement
+type Query struct {
+    RecordType string
+    Where      Predicate
+    Limit      int
+}
+
+// Cursor parcourt les clés primaires d'un résultat de requête, en ordre de clé
+type Cursor struct {
+    stream keyStream
+    limit  int
+    count  int
+    plan   string
+    step   *PlanStep // racine du plan détaillé (voir Explain)
+}
+
+func (c *Cursor) Next() bool {
+    if c.limit > 0 && c.count >= c.limit {
+        return false
+    }
+    if !c.stream.Next() {
+        return false
+    }
+    c.count++
+    return true
+}
+
+func (c *Cursor) Key() string {
+    return c.stream.Key()
+}
+
+func (c *Cursor) Error() error {
+    return c.stream.Error()
+}
+
+// Plan décrit le chemin d'accès retenu par le planificateur
+func (c *Cursor) Plan() string {
+    return c.plan
+}
+
+func (c *Cursor) Close() {
+    c.stream.Release()
+}
+
+// Query planifie et exécute une requête. Les prédicats sur des champs indexés
+// sont résolus par flux de clés triées (intersection pour AND, union pour OR/IN);
+// les autres sont appliqués en filtre, avec repli sur un parcours complet.
+func (idx *Indexer) Query(q Query) (*Cursor, error) {
+    return idx.query(q, false)
+}
+
+// query planifie une requête; avec trace, chaque flux mesure ses lignes et son temps
+func (idx *Indexer) query(q Query, trace bool) (*Cursor, error) {
+    if q.RecordType == "" {
+        return nil, fmt.Errorf("type d'enregistrement requis")
+    }
+    
+    p := &planner{idx: idx, recordType: q.RecordType, indexed: make(map[string]bool), trace: trace}
+    
+    var node *planNode
+    if q.Where != nil {
+        idx.bindIndexDefs(q.RecordType, q.Where)
+        node = p.plan(q.Where)
+    }
+    
+    var stream keyStream
+    var plan string
+    var step *PlanStep
+    
+    switch {
+    case node == nil:
+        step = &PlanStep{Op: "SCAN", Detail: q.RecordType, Estimate: -1}
+        scan := newScanStream(idx.db, q.RecordType, q.Where)
+        scan.step = step
+        stream = p.traced(scan, step)
+        plan = fmt.Sprintf("SCAN %s", q.RecordType)
+    case node.exact:
+        stream = node.stream
+        plan = node.desc
+        step = node.step
+    default:
+        step = &PlanStep{Op: "FILTER", Detail: "prédicat complet relu sur chaque document", Estimate: node.estimate, Children: []*PlanStep{node.step}}
+        stream = p.traced(&filterStream{inner: node.stream, pred: q.Where, db: idx.db, step: step}, step)
+        plan = fmt.Sprintf("FILTER(%s)", node.desc)
+    }
+    
+    return &Cursor{stream: stream, limit: q.Limit, plan: plan, step: step}, nil
+}
+
+// Find exécute une requête et retourne les clés primaires correspondantes
+func (idx *Indexer) Find(q Query) ([]string, error) {
+    cursor, err := idx.Query(q)
+    if err != nil {
+        return nil, err
+    }
+    defer cursor.Close()
+    
+    var keys []string
+    for cursor.Next() {
+        keys = append(keys, cursor.Key())
+    }
+    
+    if err := cursor.Error(); err != nil {
+        return nil, fmt.Errorf("erreur exécution requête: %v", err)
+    }
+    
+    return keys, nil
+}
+
+// planNode est un accès indexé pour un sous-arbre de prédicats
+type planNode struct {
+    stream   keyStream
+    estimate int
+    exact    bool // le flux satisfait exactement le sous-arbre, sans filtre
+    desc     string
+    step     *PlanStep
+}
+
+type planner struct {
+    idx        *Indexer
+    recordType string
+    indexed    map[string]bool
+    trace      bool
+}
+
+// traced enveloppe un flux pour compter ses lignes et son temps (mode trace)
+func (p *planner) traced(stream keyStream, step *PlanStep) keyStream {
+    if !p.trace {
+        return stream
+    }
+    return &tracedStream{inner: stream, step: step}
+}
+
+// plan retourne un accès indexé pour pred, ou nil si aucun index ne s'applique
+func (p *planner) plan(pred Predicate) *planNode {
+    switch pr := pred.(type) {
+    case *EqPredicate:
+        if !p.isIndexed(pr.Field) {
+            return nil
+        }
+        return p.valueNode(pr.Field, pr.Value)
+    
+    case *InPredicate:
+        if !p.isIndexed(pr.Field) || len(pr.Values) == 0 {
+            return nil
+        }
+        var nodes []*planNode
+        for _, v := range pr.Values {
+            nodes = append(nodes, p.valueNode(pr.Field, v))
+        }
+        return p.unionNode(nodes)
+    
+    case *RangePredicate:
+        return p.rangeNode(pr)
+    
+    case *OrPredicate:
+        var nodes []*planNode
+        for _, child := range pr.Preds {
+            node := p.plan(child)
+            if node == nil {
+                // Une branche non indexée impose un parcours complet
+                releaseNodes(nodes)
+                return nil
+            }
+            nodes = append(nodes, node)
+        }
+        if len(nodes) == 0 {
+            return nil
+        }
+        return p.unionNode(nodes)
+    
+    case *AndPredicate:
+        var nodes []*planNode
+        exact := true
+        for _, child := range pr.Preds {
+            node := p.plan(child)
+            if node == nil {
+                exact = false
+                continue
+            }
+            if !node.exact {
+                exact = false
+            }
+            nodes = append(nodes, node)
+        }
+        if len(nodes) == 0 {
+            return nil
+        }
+        
+        // Les flux les plus sélectifs en tête pour guider l'intersection
+        sort.Slice(nodes, func(i, j int) bool { return nodes[i].estimate < nodes[j].estimate })
+        
+        if len(nodes) == 1 {
+            nodes[0].exact = exact
+            return nodes[0]
+        }
+        
+        streams := make([]keyStream, len(nodes))
+        descs := make([]string, len(nodes))
+        step := &PlanStep{Op: "INTERSECT", Estimate: nodes[0].estimate}
+        for i, n := range nodes {
+            streams[i] = n.stream
+            descs[i] = n.desc
+            step.Children = append(step.Children, n.step)
+        }
+        return &planNode{
+            stream:   p.traced(&intersectStream{children: streams}, step),
+            estimate: nodes[0].estimate,
+            exact:    exact,
+            desc:     fmt.Sprintf("INTERSECT(%s)", strings.Join(descs, ", ")),
+            step:     step,
+        }
+    }
+    
+    return nil
+}
+
+func (p *planner) valueNode(field, value string) *planNode {
+    normalizedValue := p.idx.searchValue(p.recordType, field, value)
+    prefix := indexValuePrefix(p.recordType, field, normalizedValue)
+    estimate := p.idx.estimateValue(p.recordType, field, normalizedValue)
+    
+    step := &PlanStep{Op: "INDEX", Detail: fmt.Sprintf("%s.%s=%s", p.recordType, field, normalizedValue), Estimate: estimate}
+    stream := newIndexStream(p.idx.db, prefix)
+    stream.step = step
+    
+    return &planNode{
+        stream:   p.traced(stream, step),
+        estimate: estimate,
+        exact:    true,
+        desc:     fmt.Sprintf("INDEX %s.%s=%s (~%d)", p.recordType, field, normalizedValue, estimate),
+        step:     step,
+    }
+}
+
+// Une plage d'au plus rangeMergeValues valeurs distinctes est fusionnée en flux,
+// un itérateur par valeur. Au-delà, ses clés primaires sont lues et triées en
+// mémoire, dans la limite de rangeMaxKeys: une plage plus grande n'est pas
+// résolue par l'index mais appliquée en filtre.
+const (
+    rangeMergeValues = 64
+    rangeMaxKeys     = 100000
+)
+
+// rangeNode résout une plage sur un index numérique (normaliseur numeric): les
+// valeurs y sont encodées à largeur fixe, l'ordre des clés est l'ordre numérique.
+// Chaque valeur donne ses clés primaires triées; la plage est leur union.
+func (p *planner) rangeNode(pr *RangePredicate) *planNode {
+    if _, numeric := toFloat(pr.Value); !numeric || !p.isIndexed(pr.Field) {
+        return nil
+    }
+    def, err := p.idx.GetIndexDef(p.recordType, pr.Field)
+    if err != nil || def == nil || def.Normalizer != NormalizerNumeric {
+        return nil
+    }
+    
+    prefix := indexPrefix(p.recordType, pr.Field)
+    bound := prefix + p.idx.searchValue(p.recordType, pr.Field, pr.Value)
+    
+    // ':' sépare la valeur de la clé primaire, ';' le suit immédiatement
+    rng := util.BytesPrefix([]byte(prefix))
+    switch pr.Op {
+    case ">":
+        rng.Start = []byte(bound + ";")
+    case ">=":
+        rng.Start = []byte(bound + ":")
+    case "<":
+        rng.Limit = []byte(bound + ":")
+    case "<=":
+        rng.Limit = []byte(bound + ";")
+    default:
+        return nil
+    }
+    
+    // Les valeurs distinctes sont lues dès la planification: leurs pas
+    // d'itérateur sont comptés ici, le temps dans celui de la planification
+    step := &PlanStep{Op: "RANGE", Detail: fmt.Sprintf("%s.%s%s%s", p.recordType, pr.Field, pr.Op, pr.Value)}
+    values, err := rangeValues(p.idx.db, prefix, rng, rangeMergeValues+1, step)
+    if err != nil {
+        return nil
+    }
+    
+    var stream keyStream
+    estimate := 0
+    if len(values) <= rangeMergeValues {
+        streams := make([]keyStream, len(values))
+        for i, v := range values {
+            child := newIndexStream(p.idx.db, prefix+v+":")
+            child.step = step
+            streams[i] = child
+        }
+        stream = &unionStream{children: streams, alive: make([]bool, len(streams))}
+        estimate = p.idx.estimateRange(p.recordType, pr.Field, values, rng)
+    } else {
+        keys, ok, err := rangeKeys(p.idx.db, rng, rangeMaxKeys, step)
+        if !ok {
+            return nil
+        }
+        stream = &sliceStream{keys: keys, pos: -1, err: err}
+        estimate = len(keys)
+    }
+    step.Estimate = estimate
+    
+    return &planNode{
+        stream:   p.traced(stream, step),
+        estimate: estimate,
+        exact:    true,
+        desc:     fmt.Sprintf("RANGE %s.%s%s%s (~%d)", p.recordType, pr.Field, pr.Op, pr.Value, estimate),
+        step:     step,
+    }
+}
+
+// rangeValues retourne les valeurs distinctes (au plus max) des entrées d'une
+// plage d'index, en sautant d'une valeur à la suivante sans lire ses entrées
+func rangeValues(db *leveldb.DB, prefix string, rng *util.Range, max int, step *PlanStep) ([]string, error) {
+    iter := db.NewIterator(rng, nil)
+    defer iter.Release()
+    
+    var values []string
+    for ok := iter.Next(); ok && len(values) < max; {
+        step.Steps++
+        rest := string(iter.Key()[len(prefix):])
+        i := strings.Index(rest, ":")
+        if i < 0 {
+            ok = iter.Next()
+            continue
+        }
+        values = append(values, rest[:i])
+        ok = iter.Seek([]byte(prefix + rest[:i] + ";"))
+    }
+    return values, iter.Error()
+}
+
+// rangeKeys lit et trie les clés primaires d'une plage; faux au-delà de max
+func rangeKeys(db *leveldb.DB, rng *util.Range, max int, step *PlanStep) ([]string, bool, error) {
+    iter := db.NewIterator(rng, nil)
+    defer iter.Release()
+    
+    var keys []string
+    for iter.Next() {
+        step.Steps++
+        if len(keys) >= max {
+            return nil, false, nil
+        }
+        primaryKey, _ := decodeIndexValue(iter.Value())
+        keys = append(keys, primaryKey)
+    }
+    
+    sort.Strings(keys)
+    return keys, true, iter.Error()
+}
+
+// isIndexed indique si un index existe pour le champ (déclaré ou présent sur disque)
+func (p *planner) isIndexed(field string) bool {
+    if indexed, ok := p.indexed[field]; ok {
+        return indexed
+    }
+    
+    indexed := false
+    if def, err := p.idx.GetIndexDef(p.recordType, field); err == nil && def != nil {
+        // Un index géographique ne contient pas les valeurs du champ
+        indexed = def.Kind == IndexKindValue && def.Field == field
+    } else {
+        iter := p.idx.db.NewIterator(util.BytesPrefix([]byte(indexPrefix(p.recordType, field))), nil)
+        indexed = iter.Next()
+        iter.Release()
+    }
+    
+    p.indexed[field] = indexed
+    return indexed
+}
+
+func (p *planner) unionNode(nodes []*planNode) *planNode {
+    if len(nodes) == 1 {
+        return nodes[0]
+    }
+    
+    streams := make([]keyStream, len(nodes))
+    descs := make([]string, len(nodes))
+    step := &PlanStep{Op: "UNION"}
+    estimate := 0
+    exact := true
+    for i, n := range nodes {
+        streams[i] = n.stream
+        descs[i] = n.desc
+        step.Children = append(step.Children, n.step)
+        estimate += n.estimate
+        if !n.exact {
+            exact = false
+        }
+    }
+    step.Estimate = estimate
+    
+    return &planNode{
+        stream:   p.traced(&unionStream{children: streams, alive: make([]bool, len(streams))}, step),
+        estimate: estimate,
+        exact:    exact,
+        desc:     fmt.Sprintf("UNION(%s)", strings.Join(descs, ", ")),
+        step:     step,
+    }
+}
+
+func releaseNodes(nodes []*planNode) {
+    for _, n := range nodes {
+        n.stream.Release()
+    }
+}
+
+// countPrefix compte les clés d'un préfixe (estimation de cardinalité)
+func countPrefix(db *leveldb.DB, prefix string) int {
+    iter := db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    count := 0
+    for iter.Next() {
+        count++
+    }
+    return count
+}
+
+// keyStream est un flux de clés primaires triées, avec positionnement
+type keyStream interface {
+    Next() bool
+    // Seek positionne le flux sur la première clé >= target
+    Seek(target string) bool
+    Key() string
+    Error() error
+    Release()
+}
+
+// indexStream lit les clés primaires d'un préfixe d'index (triées par clé primaire)
+type indexStream struct {
+    iter   iterator.Iterator
+    prefix string
+    key    string
+    valid  bool
+    step   *PlanStep // pas d'itérateur comptés (peut être nil)
+}
+
+func newIndexStream(db *leveldb.DB, prefix string) *indexStream {
+    return &indexStream{
+        iter:   db.NewIterator(util.BytesPrefix([]byte(prefix)), nil),
+        prefix: prefix,
+    }
+}
+
+func (s *indexStream) Next() bool {
+    s.countStep()
+    s.valid = s.iter.Next()
+    if s.valid {
+        s.key, _ = decodeIndexValue(s.iter.Value())
+    }
+    return s.valid
+}
+
+func (s *indexStream) Seek(target string) bool {
+    if s.valid && s.key >= target {
+        return true
+    }
+    s.countStep()
+    s.valid = s.iter.Seek([]byte(s.prefix + target))
+    if s.valid {
+        s.key, _ = decodeIndexValue(s.iter.Value())
+    }
+    return s.valid
+}
+
+func (s *indexStream) countStep() {
+    if s.step != nil {
+        s.step.Steps++
+    }
+}
+
+func (s *indexStream) Key() string   { return s.key }
+func (s *indexStream) Error() error  { return s.iter.Error() }
+func (s *indexStream) Release()      { s.iter.Release() }
+
+// sliceStream parcourt des clés primaires déjà triées en mémoire
+type sliceStream struct {
+    keys []string
+    pos  int
+    err  error
+}
+
+func (s *sliceStream) Next() bool {
+    for s.pos++; s.pos < len(s.keys); s.pos++ {
+        // Une clé peut apparaître sous plusieurs valeurs (tableau)
+        if s.pos == 0 || s.keys[s.pos] != s.keys[s.pos-1] {
+            return true
+        }
+    }
+    return false
+}
+
+func (s *sliceStream) Seek(target string) bool {
+    if s.pos >= 0 && s.pos < len(s.keys) && s.keys[s.pos] >= target {
+        return true
+    }
+    s.pos = sort.SearchStrings(s.keys, target)
+    return s.pos < len(s.keys)
+}
+
+func (s *sliceStream) Key() string   { return s.keys[s.pos] }
+func (s *sliceStream) Error() error  { return s.err }
+func (s *sliceStream) Release()      {}
+
+// unionStream fusionne des flux triés en éliminant les doublons
+type unionStream struct {
+    children []keyStream
+    alive    []bool
+    started  bool
+    key      string
+}
+
+func (s *unionStream) Next() bool {
+    if !s.started {
+        for i, c := range s.children {
+            s.alive[i] = c.Next()
+        }
+        s.started = true
+    } else {
+        for i, c := range s.children {
+            if s.alive[i] && c.Key() == s.key {
+                s.alive[i] = c.Next()
+            }
+        }
+    }
+    return s.pick()
+}
+
+func (s *unionStream) Seek(target string) bool {
+    for i, c := range s.children {
+        if !s.started || (s.alive[i] && c.Key() < target) {
+            s.alive[i] = c.Seek(target)
+        }
+    }
+    s.started = true
+    return s.pick()
+}
+
+func (s *unionStream) pick() bool {
+    found := false
+    for i, c := range s.children {
+        if s.alive[i] && (!found || c.Key() < s.key) {
+            s.key = c.Key()
+            found = true
+        }
+    }
+    return found
+}
+
+func (s *unionStream) Key() string { return s.key }
+
+func (s *unionStream) Error() error {
+    for _, c := range s.children {
+        if err := c.Error(); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (s *unionStream) Release() {
+    for _, c := range s.children {
+        c.Release()
+    }
+}
+
+// intersectStream ne retient que les clés présentes dans tous les flux
+// (algorithme leapfrog: chaque flux est positionné sur la plus grande clé courante)
+type intersectStream struct {
+    children []keyStream
+    started  bool
+    key      string
+}
+
+func (s *intersectStream) Next() bool {
+    if !s.started {
+        s.started = true
+        for _, c := range s.children {
+            if !c.Next() {
+                return false
+            }
+        }
+    } else if !s.children[0].Next() {
+        return false
+    }
+    return s.align()
+}
+
+func (s *intersectStream) Seek(target string) bool {
+    s.started = true
+    for _, c := range s.children {
+        if !c.Seek(target) {
+            return false
+        }
+    }
+    return s.align()
+}
+
+func (s *intersectStream) align() bool {
+    for {
+        max := s.children[0].Key()
+        for _, c := range s.children[1:] {
+            if c.Key() > max {
+                max = c.Key()
+            }
+        }
+        
+        aligned := true
+        for _, c := range s.children {
+            if c.Key() == max {
+                continue
+            }
+            if !c.Seek(max) {
+                return false
+            }
+            if c.Key() != max {
+                aligned = false
+            }
+        }
+        
+        if aligned {
+            s.key = max
+            return true
+        }
+    }
+}
+
+func (s *intersectStream) Key() string { return s.key }
+
+func (s *intersectStream) Error() error {
+    for _, c := range s.children {
+        if err := c.Error(); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (s *intersectStream) Release() {
+    for _, c := range s.children {
+        c.Release()
+    }
+}
+
+// filterStream applique un prédicat résiduel en lisant chaque document candidat
+type filterStream struct {
+    inner keyStream
+    pred  Predicate
+    db    *leveldb.DB
+    err   error
+    step  *PlanStep // lectures ponctuelles comptées (peut être nil)
+}
+
+func (s *filterStream) Next() bool {
+    for s.inner.Next() {
+        if s.accept(s.inner.Key()) {
+            return true
+        }
+    }
+    return false
+}
+
+func (s *filterStream) Seek(target string) bool {
+    if !s.inner.Seek(target) {
+        return false
+    }
+    if s.accept(s.inner.Key()) {
+        return true
+    }
+    return s.Next()
+}
+
+func (s *filterStream) accept(primaryKey string) bool {
+    if s.step != nil {
+        s.step.Reads++
+    }
+    value, err := s.db.Get([]byte(primaryKey), nil)
+    if err != nil {
+        if err != leveldb.ErrNotFound {
+            s.err = err
+        }
+        return false
+    }
+    
+    data, err := decodeDocument(value)
+    if err != nil {
+        return false
+    }
+    return s.pred.Match(data)
+}
+
+func (s *filterStream) Key() string { return s.inner.Key() }
+
+func (s *filterStream) Error() error {
+    if s.err != nil {
+        return s.err
+    }
+    return s.inner.Error()
+}
+
+func (s *filterStream) Release() { s.inner.Release() }
+
+// scanStream parcourt tous les documents d'un type et applique le prédicat
+type scanStream struct {
+    iter iterator.Iterator
+    pred Predicate
+    key  string
+    step *PlanStep // pas d'itérateur comptés (peut être nil)
+}
+
+func newScanStream(db *leveldb.DB, recordType string, pred Predicate) *scanStream {
+    return &scanStream{
+        iter: db.NewIterator(util.BytesPrefix([]byte(recordType+":")), nil),
+        pred: pred,
+    }
+}
+
+func (s *scanStream) Next() bool {
+    for s.iter.Next() {
+        s.countStep()
+        if s.accept() {
+            return true
+        }
+    }
+    return false
+}
+
+func (s *scanStream) Seek(target string) bool {
+    s.countStep()
+    if !s.iter.Seek([]byte(target)) {
+        return false
+    }
+    if s.accept() {
+        return true
+    }
+    return s.Next()
+}
+
+func (s *scanStream) countStep() {
+    if s.step != nil {
+        s.step.Steps++
+    }
+}
+
+func (s *scanStream) accept() bool {
+    if s.pred != nil {
+        data, err := decodeDocument(s.iter.Value())
+        if err != nil || !s.pred.Match(data) {
+            return false
+        }
+    }
+    s.key = string(s.iter.Key())
+    return true
+}
+
+func (s *scanStream) Key() string  { return s.key }
+func (s *scanStream) Error() error { return s.iter.Error() }
+func (s *scanStream) Release()     { s.iter.Release() }
\ No newline at end of file
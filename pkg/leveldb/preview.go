@@ -0,0 +1,146 @@
+// pkg/leveldb/preview.go
+// Simulation d'une écriture (put, delete): lot préparé comme par le Client mais jamais appliqué
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+)
+
+// WritePreview décrit l'effet d'une écriture sans l'appliquer: ancienne
+// version, hash de la nouvelle et entrées d'index ajoutées ou retirées.
+// Une entrée réécrite à l'identique (valeur d'index inchangée) n'apparaît pas.
+// Unmaintained liste les index du type présents sur disque mais non déclarés:
+// l'écriture ne les met pas à jour.
+type WritePreview struct {
+    Key          string   `json:"key"`
+    Op           string   `json:"op"` // "put" ou "delete"
+    Old          *Entry   `json:"old,omitempty"`
+    Data         []byte   `json:"data,omitempty"` // nouvelle version (put)
+    Hash         string   `json:"hash,omitempty"`
+    IndexAdded   []string `json:"index_added"`
+    IndexRemoved []string `json:"index_removed"`
+    Unmaintained []string `json:"unmaintained,omitempty"`
+}
+
+// PreviewPut prépare l'écriture de data sous key comme Put, contraintes
+// d'unicité comprises (une violation est retournée en ConstraintError)
+func (c *Client) PreviewPut(key string, data interface{}) (*WritePreview, error) {
+    dataBytes, err := json.Marshal(data)
+    if err != nil {
+        return nil, fmt.Errorf("erreur sérialisation: %v", err)
+    }
+    
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    
+    preview := &WritePreview{Key: key, Op: "put", Data: dataBytes, Hash: calculateHash(dataBytes)}
+    if preview.Old, err = c.current(key); err != nil {
+        return nil, err
+    }
+    
+    batch := new(leveldb.Batch)
+    if err := newIndexTxn(c.db, batch).put(key, dataBytes); err != nil {
+        return nil, err
+    }
+    if err := preview.indexChanges(batch); err != nil {
+        return nil, err
+    }
+    if preview.Unmaintained, err = NewIndexer(c.db).UnmaintainedIndexes(recordTypeOf(key)); err != nil {
+        return nil, err
+    }
+    return preview, nil
+}
+
+// PreviewDelete prépare la suppression de key comme Delete
+func (c *Client) PreviewDelete(key string) (*WritePreview, error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    
+    preview := &WritePreview{Key: key, Op: "delete"}
+    var err error
+    if preview.Old, err = c.current(key); err != nil {
+        return nil, err
+    }
+    
+    batch := new(leveldb.Batch)
+    if err := newIndexTxn(c.db, batch).delete(key); err != nil {
+        return nil, err
+    }
+    if err := preview.indexChanges(batch); err != nil {
+        return nil, err
+    }
+    if preview.Unmaintained, err = NewIndexer(c.db).UnmaintainedIndexes(recordTypeOf(key)); err != nil {
+        return nil, err
+    }
+    return preview, nil
+}
+
+// current lit la version actuelle de key (nil si absente)
+func (c *Client) current(key string) (*Entry, error) {
+    value, err := c.db.Get([]byte(key), nil)
+    if err == leveldb.ErrNotFound {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    
+    var entry Entry
+    if err := json.Unmarshal(value, &entry); err != nil {
+        return nil, fmt.Errorf("erreur désérialisation: %v", err)
+    }
+    return &entry, nil
+}
+
+// indexChanges relit le lot: une entrée supprimée puis remise est inchangée
+func (p *WritePreview) indexChanges(batch *leveldb.Batch) error {
+    r := &previewReplay{first: make(map[string]bool), last: make(map[string]bool)}
+    if err := batch.Replay(r); err != nil {
+        return fmt.Errorf("erreur relecture du lot: %v", err)
+    }
+    
+    for key, put := range r.last {
+        switch {
+        case put && r.first[key]:
+            p.IndexAdded = append(p.IndexAdded, key)
+        case put:
+            // Supprimée puis remise: inchangée
+        default:
+            p.IndexRemoved = append(p.IndexRemoved, key)
+        }
+    }
+    sort.Strings(p.IndexAdded)
+    sort.Strings(p.IndexRemoved)
+    return nil
+}
+
+// previewReplay retient, pour chaque entrée idx:, si sa première et sa
+// dernière opération dans le lot sont des suppressions (false) ou des écritures (true)
+type previewReplay struct {
+    first map[string]bool // vrai si la première opération est une écriture
+    last  map[string]bool
+}
+
+func (r *previewReplay) Put(key, value []byte) {
+    r.record(string(key), true)
+}
+
+func (r *previewReplay) Delete(key []byte) {
+    r.record(string(key), false)
+}
+
+func (r *previewReplay) record(key string, put bool) {
+    if !strings.HasPrefix(key, "idx:") {
+        return
+    }
+    if _, ok := r.first[key]; !ok {
+        r.first[key] = put
+    }
+    r.last[key] = put
+}
\ No newline at end of file
@@ -0,0 +1,444 @@
+// pkg/leveldb/reindex.go
+// Reconstruction des index secondaires et vérification de cohérence
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Taille des lots d'écriture pendant une reconstruction ou une réparation
+const rebuildBatchSize = 1000
+
+// Préfixe des entrées d'un index en cours de reconstruction (_rebuild:idx:...)
+const rebuildPrefix = "_rebuild:"
+
+// IndexProblem décrit une entrée d'index incohérente
+type IndexProblem struct {
+    IndexKey   string `json:"index_key"`
+    RecordType string `json:"record_type"`
+    Field      string `json:"field"`
+    Value      string `json:"value"`
+    PrimaryKey string `json:"primary_key"`
+    Reason     string `json:"reason"`
+    
+    entryValue []byte // valeur à écrire lors de la réparation d'une entrée manquante
+}
+
+// IndexReport est le résultat d'une vérification de cohérence des index
+type IndexReport struct {
+    CheckedEntries   int            `json:"checked_entries"`
+    CheckedDocuments int            `json:"checked_documents"`
+    Orphans          []IndexProblem `json:"orphans"`
+    Missing          []IndexProblem `json:"missing"`
+}
+
+// OK indique qu'aucune incohérence n'a été détectée
+func (r *IndexReport) OK() bool {
+    return len(r.Orphans) == 0 && len(r.Missing) == 0
+}
+
+// Rebuild reconstruit l'index recordType/name à partir d'un parcours complet
+// des documents, puis enregistre sa définition. Une définition existante (projection,
+// unicité, type d'index) est conservée; sinon name est le champ indexé.
+// Retourne le nombre d'entrées créées.
+func (idx *Indexer) Rebuild(recordType, name string) (int, error) {
+    def, err := idx.GetIndexDef(recordType, name)
+    if err != nil {
+        return 0, err
+    }
+    if def == nil {
+        def = &IndexDef{RecordType: recordType, Field: name}
+    }
+    
+    return idx.RebuildIndex(*def)
+}
+
+// RebuildIndex reconstruit un index selon sa définition et l'enregistre
+func (idx *Indexer) RebuildIndex(def IndexDef) (int, error) {
+    recordType, name := def.RecordType, def.IndexName()
+    
+    if recordType == "" || recordType == "idx" || strings.HasPrefix(recordType, "_") {
+        return 0, fmt.Errorf("type d'enregistrement invalide: %q", recordType)
+    }
+    if err := def.validate(); err != nil {
+        return 0, err
+    }
+    for _, field := range def.sourceFields() {
+        if !isIndexableField(field) {
+            return 0, fmt.Errorf("champ système non indexable: %s", field)
+        }
+    }
+    
+    // Les entrées sont construites sous un préfixe temporaire: l'index en place
+    // reste intact tant que le parcours (et la vérification d'unicité) n'a pas abouti
+    tmpPrefix := rebuildPrefix + indexPrefix(recordType, name)
+    if err := idx.deletePrefix(tmpPrefix); err != nil {
+        return 0, fmt.Errorf("erreur purge index temporaire %s:%s: %v", recordType, name, err)
+    }
+    
+    batch := new(leveldb.Batch)
+    created := 0
+    
+    // Pour un index unique: valeur → première clé primaire rencontrée
+    seen := make(map[string]string)
+    
+    // Compteurs par valeur pour les statistiques de l'index
+    counts := make(map[string]int)
+    
+    err := idx.scanDocuments(recordType, func(primaryKey string, data map[string]interface{}) error {
+        for _, normalizedValue := range idx.indexValues(&def, data) {
+            if def.Unique {
+                if existing, dup := seen[normalizedValue]; dup && existing != primaryKey {
+                    return &ConstraintError{
+                        RecordType:  recordType,
+                        Field:       name,
+                        Value:       normalizedValue,
+                        Key:         primaryKey,
+                        ExistingKey: existing,
+                    }
+                }
+                seen[normalizedValue] = primaryKey
+            }
+            batch.Put([]byte(rebuildPrefix+indexKey(recordType, name, normalizedValue, primaryKey)), def.entryValue(primaryKey, data))
+            counts[normalizedValue]++
+            created++
+        }
+        
+        if batch.Len() >= rebuildBatchSize {
+            if err := idx.db.Write(batch, nil); err != nil {
+                return fmt.Errorf("erreur écriture lot: %v", err)
+            }
+            batch.Reset()
+        }
+        return nil
+    })
+    if err == nil && batch.Len() > 0 {
+        if err = idx.db.Write(batch, nil); err != nil {
+            err = fmt.Errorf("erreur écriture lot: %v", err)
+        }
+    }
+    if err != nil {
+        // L'ancien index et ses compteurs sont conservés
+        idx.deletePrefix(tmpPrefix)
+        return 0, err
+    }
+    
+    // Remplacer les anciennes entrées par les nouvelles
+    if err := idx.deletePrefix(indexPrefix(recordType, name)); err != nil {
+        return 0, fmt.Errorf("erreur purge index %s:%s: %v", recordType, name, err)
+    }
+    if err := idx.movePrefix(tmpPrefix, rebuildPrefix); err != nil {
+        return 0, fmt.Errorf("erreur installation index %s:%s: %v", recordType, name, err)
+    }
+    
+    if err := idx.writeStats(recordType, name, counts); err != nil {
+        return 0, err
+    }
+    
+    if err := idx.DefineIndex(def); err != nil {
+        return 0, err
+    }
+    
+    return created, nil
+}
+
+// Check parcourt toutes les entrées idx: et signale celles qui pointent vers un
+// document absent ou dont la valeur ne correspond plus (orphelines), puis, pour
+// chaque index déclaré, les documents sans entrée d'index (manquantes).
+func (idx *Indexer) Check() (*IndexReport, error) {
+    report := &IndexReport{}
+    
+    defs, err := idx.ListIndexDefs("")
+    if err != nil {
+        return nil, err
+    }
+    
+    defsByName := make(map[string]*IndexDef, len(defs))
+    for i := range defs {
+        defsByName[defs[i].RecordType+":"+defs[i].IndexName()] = &defs[i]
+    }
+    
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte("idx:")), nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        key := string(iter.Key())
+        primaryKey, _ := decodeIndexValue(iter.Value())
+        report.CheckedEntries++
+        
+        problem := IndexProblem{IndexKey: key, PrimaryKey: primaryKey}
+        
+        parts := strings.SplitN(strings.TrimPrefix(key, "idx:"), ":", 3)
+        if len(parts) < 3 || !strings.HasSuffix(parts[2], ":"+primaryKey) {
+            problem.Reason = "clé d'index malformée"
+            report.Orphans = append(report.Orphans, problem)
+            continue
+        }
+        
+        problem.RecordType = parts[0]
+        problem.Field = parts[1]
+        problem.Value = strings.TrimSuffix(parts[2], ":"+primaryKey)
+        
+        valueBytes, err := idx.db.Get([]byte(primaryKey), nil)
+        if err == leveldb.ErrNotFound {
+            problem.Reason = "document absent"
+            report.Orphans = append(report.Orphans, problem)
+            continue
+        }
+        if err != nil {
+            return nil, fmt.Errorf("erreur lecture %s: %v", primaryKey, err)
+        }
+        
+        data, err := decodeDocument(valueBytes)
+        if err != nil {
+            problem.Reason = "document illisible"
+            report.Orphans = append(report.Orphans, problem)
+            continue
+        }
+        
+        var expected []string
+        if def, ok := defsByName[problem.RecordType+":"+problem.Field]; ok {
+            expected = idx.indexValues(def, data)
+        } else {
+            expected = expectedIndexValues(data, problem.Field)
+        }
+        
+        if !containsString(expected, problem.Value) {
+            problem.Reason = "valeur obsolète"
+            report.Orphans = append(report.Orphans, problem)
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur itération: %v", err)
+    }
+    
+    for i := range defs {
+        def := &defs[i]
+        err := idx.scanDocuments(def.RecordType, func(primaryKey string, data map[string]interface{}) error {
+            report.CheckedDocuments++
+            
+            for _, normalizedValue := range idx.indexValues(def, data) {
+                key := indexKey(def.RecordType, def.IndexName(), normalizedValue, primaryKey)
+                
+                exists, err := idx.db.Has([]byte(key), nil)
+                if err != nil {
+                    return fmt.Errorf("erreur lecture %s: %v", key, err)
+                }
+                if !exists {
+                    report.Missing = append(report.Missing, IndexProblem{
+                        IndexKey:   key,
+                        RecordType: def.RecordType,
+                        Field:      def.IndexName(),
+                        Value:      normalizedValue,
+                        PrimaryKey: primaryKey,
+                        Reason:     "entrée manquante",
+                        entryValue: def.entryValue(primaryKey, data),
+                    })
+                }
+            }
+            return nil
+        })
+        if err != nil {
+            return nil, err
+        }
+    }
+    
+    return report, nil
+}
+
+// Repair corrige les problèmes d'un rapport: suppression des entrées orphelines
+// et création des entrées manquantes. Retourne le nombre de corrections.
+func (idx *Indexer) Repair(report *IndexReport) (int, error) {
+    batch := new(leveldb.Batch)
+    fixed := 0
+    
+    flush := func() error {
+        if batch.Len() == 0 {
+            return nil
+        }
+        if err := idx.db.Write(batch, nil); err != nil {
+            return fmt.Errorf("erreur écriture lot: %v", err)
+        }
+        batch.Reset()
+        return nil
+    }
+    
+    for _, problem := range report.Orphans {
+        batch.Delete([]byte(problem.IndexKey))
+        fixed++
+        if batch.Len() >= rebuildBatchSize {
+            if err := flush(); err != nil {
+                return 0, err
+            }
+        }
+    }
+    
+    for _, problem := range report.Missing {
+        value := problem.entryValue
+        if value == nil {
+            value = []byte(problem.PrimaryKey)
+        }
+        batch.Put([]byte(problem.IndexKey), value)
+        fixed++
+        if batch.Len() >= rebuildBatchSize {
+            if err := flush(); err != nil {
+                return 0, err
+            }
+        }
+    }
+    
+    if err := flush(); err != nil {
+        return 0, err
+    }
+    
+    // Recalculer les compteurs des index réparés qui en tiennent
+    refreshed := make(map[string]bool)
+    for _, problems := range [][]IndexProblem{report.Orphans, report.Missing} {
+        for _, problem := range problems {
+            name := problem.RecordType + ":" + problem.Field
+            if problem.RecordType == "" || refreshed[name] {
+                continue
+            }
+            refreshed[name] = true
+            
+            header, err := idx.readStatsHeader(problem.RecordType, problem.Field)
+            if err != nil {
+                return fixed, err
+            }
+            if header != nil {
+                if err := idx.RefreshStats(problem.RecordType, problem.Field); err != nil {
+                    return fixed, err
+                }
+            }
+        }
+    }
+    
+    return fixed, nil
+}
+
+// scanDocuments appelle fn pour chaque document décodable du type donné
+func (idx *Indexer) scanDocuments(recordType string, fn func(primaryKey string, data map[string]interface{}) error) error {
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte(recordType+":")), nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        data, err := decodeDocument(iter.Value())
+        if err != nil {
+            continue
+        }
+        
+        if err := fn(string(iter.Key()), data); err != nil {
+            return err
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return fmt.Errorf("erreur itération: %v", err)
+    }
+    
+    return nil
+}
+
+// deletePrefix supprime par lots toutes les clés d'un préfixe
+func (idx *Indexer) deletePrefix(prefix string) error {
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    batch := new(leveldb.Batch)
+    for iter.Next() {
+        batch.Delete(append([]byte(nil), iter.Key()...))
+        if batch.Len() >= rebuildBatchSize {
+            if err := idx.db.Write(batch, nil); err != nil {
+                return err
+            }
+            batch.Reset()
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return err
+    }
+    
+    if batch.Len() > 0 {
+        return idx.db.Write(batch, nil)
+    }
+    return nil
+}
+
+// movePrefix déplace par lots les clés d'un préfixe en retirant trim de leur nom
+func (idx *Indexer) movePrefix(prefix, trim string) error {
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    batch := new(leveldb.Batch)
+    for iter.Next() {
+        key := string(iter.Key())
+        batch.Put([]byte(strings.TrimPrefix(key, trim)), append([]byte(nil), iter.Value()...))
+        batch.Delete([]byte(key))
+        if batch.Len() >= rebuildBatchSize {
+            if err := idx.db.Write(batch, nil); err != nil {
+                return err
+            }
+            batch.Reset()
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return err
+    }
+    
+    if batch.Len() > 0 {
+        return idx.db.Write(batch, nil)
+    }
+    return nil
+}
+
+// decodeDocument décode une Entry stockée et retourne ses données
+func decodeDocument(value []byte) (map[string]interface{}, error) {
+    var entry Entry
+    if err := json.Unmarshal(value, &entry); err != nil {
+        return nil, err
+    }
+    
+    var data map[string]interface{}
+    if err := json.Unmarshal(entry.Data, &data); err != nil {
+        return nil, err
+    }
+    
+    return data, nil
+}
+
+// expectedIndexValues retourne les valeurs normalisées qu'un document doit
+// produire pour un champ (simple ou composite "a-b")
+func expectedIndexValues(data map[string]interface{}, field string) []string {
+    if values := indexFieldValues(data, field); len(values) > 0 {
+        return values
+    }
+    
+    // Index composite créé par CreateCompositeIndex sans définition enregistrée
+    if !strings.Contains(field, compositeSeparator) {
+        return nil
+    }
+    
+    values, ok := compositeValues(data, strings.Split(field, compositeSeparator), caseFold)
+    if !ok {
+        return nil
+    }
+    return []string{encodeTuple(values)}
+}
+
+func containsString(values []string, s string) bool {
+    for _, v := range values {
+        if v == s {
+            return true
+        }
+    }
+    return false
+}
\ No newline at end of file
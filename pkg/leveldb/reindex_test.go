@@ -0,0 +1,75 @@
+// pkg/leveldb/reindex_test.go
+// Tests de la reconstruction des index
+
+package leveldb
+
+import (
+    "testing"
+    
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// newTestClient ouvre un nœud vide dans un répertoire temporaire
+func newTestClient(t *testing.T) *Client {
+    t.Helper()
+    client, err := NewClient(t.TempDir())
+    if err != nil {
+        t.Fatalf("NewClient: %v", err)
+    }
+    t.Cleanup(func() { client.Close() })
+    return client
+}
+
+// countKeys compte les clés d'un préfixe
+func countKeys(t *testing.T, client *Client, prefix string) int {
+    t.Helper()
+    iter := client.GetDB().NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    n := 0
+    for iter.Next() {
+        n++
+    }
+    return n
+}
+
+// Une reconstruction refusée (doublon sur un index unique) laisse l'index en
+// place intact: entrées, compteurs et définition
+func TestRebuildIndexFailureKeepsIndex(t *testing.T) {
+    client := newTestClient(t)
+    idx := NewIndexer(client.GetDB())
+    
+    for key, email := range map[string]string{"user:1": "a@x", "user:2": "a@x", "user:3": "b@x"} {
+        if err := client.Put(key, map[string]interface{}{"email": email}); err != nil {
+            t.Fatalf("Put %s: %v", key, err)
+        }
+    }
+    
+    if n, err := idx.RebuildIndex(IndexDef{RecordType: "user", Field: "email"}); err != nil || n != 3 {
+        t.Fatalf("RebuildIndex: %d, %v", n, err)
+    }
+    
+    _, err := idx.RebuildIndex(IndexDef{RecordType: "user", Field: "email", Unique: true})
+    if _, ok := err.(*ConstraintError); !ok {
+        t.Fatalf("RebuildIndex unique: ConstraintError attendue, obtenu %v", err)
+    }
+    
+    if n := countKeys(t, client, indexPrefix("user", "email")); n != 3 {
+        t.Errorf("entrées après échec: %d, attendu 3", n)
+    }
+    if n := countKeys(t, client, rebuildPrefix); n != 0 {
+        t.Errorf("entrées temporaires restantes: %d", n)
+    }
+    def, err := idx.GetIndexDef("user", "email")
+    if err != nil || def == nil || def.Unique {
+        t.Errorf("définition après échec: %+v, %v", def, err)
+    }
+    header, err := idx.readStatsHeader("user", "email")
+    if err != nil || header == nil || header.Entries != 3 || header.Distinct != 2 {
+        t.Errorf("compteurs après échec: %+v, %v", header, err)
+    }
+    
+    keys, err := idx.SearchByIndex("user", "email", "A@x")
+    if err != nil || len(keys) != 2 {
+        t.Errorf("SearchByIndex après échec: %v, %v", keys, err)
+    }
+}
\ No newline at end of file
@@ -0,0 +1,45 @@
+// pkg/leveldb/sortable.go
+// Encodage des nombres en chaînes dont l'ordre lexicographique est l'ordre numérique
+
+package leveldb
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+)
+
+// encodeSortableFloat encode un nombre sur 16 chiffres hexadécimaux: le bit de
+// signe est inversé pour les positifs, tous les bits pour les négatifs
+func encodeSortableFloat(f float64) string {
+    if f == 0 {
+        f = 0 // -0 et +0 ont le même encodage
+    }
+    
+    bits := math.Float64bits(f)
+    if f >= 0 {
+        bits ^= 1 << 63
+    } else {
+        bits = ^bits
+    }
+    return fmt.Sprintf("%016x", bits)
+}
+
+// decodeSortableFloat est l'inverse de encodeSortableFloat
+func decodeSortableFloat(s string) (float64, bool) {
+    if len(s) != 16 {
+        return 0, false
+    }
+    
+    bits, err := strconv.ParseUint(s, 16, 64)
+    if err != nil {
+        return 0, false
+    }
+    
+    if bits&(1<<63) != 0 {
+        bits ^= 1 << 63
+    } else {
+        bits = ^bits
+    }
+    return math.Float64frombits(bits), true
+}
\ No newline at end of file
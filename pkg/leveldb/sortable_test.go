@@ -0,0 +1,64 @@
+// pkg/leveldb/sortable_test.go
+// Tests de l'encodage triable des nombres: aller-retour et ordre numérique
+
+package leveldb
+
+import (
+    "math"
+    "testing"
+)
+
+// Valeurs dans l'ordre numérique croissant
+var sortableFloats = []float64{
+    math.Inf(-1),
+    -math.MaxFloat64,
+    -1e10,
+    -2.5,
+    -1,
+    -math.SmallestNonzeroFloat64,
+    0,
+    math.SmallestNonzeroFloat64,
+    0.5,
+    1,
+    2,
+    10,
+    123.45,
+    1e10,
+    math.MaxFloat64,
+    math.Inf(1),
+}
+
+func TestSortableFloatRoundTrip(t *testing.T) {
+    for _, f := range sortableFloats {
+        enc := encodeSortableFloat(f)
+        if len(enc) != 16 {
+            t.Errorf("encodeSortableFloat(%v) = %q: 16 caractères attendus", f, enc)
+        }
+        decoded, ok := decodeSortableFloat(enc)
+        if !ok || decoded != f {
+            t.Errorf("aller-retour de %v: obtenu %v, %v", f, decoded, ok)
+        }
+    }
+}
+
+func TestSortableFloatOrder(t *testing.T) {
+    for i := 1; i < len(sortableFloats); i++ {
+        prev, cur := encodeSortableFloat(sortableFloats[i-1]), encodeSortableFloat(sortableFloats[i])
+        if prev >= cur {
+            t.Errorf("encodeSortableFloat(%v) = %q devrait précéder encodeSortableFloat(%v) = %q",
+                sortableFloats[i-1], prev, sortableFloats[i], cur)
+        }
+    }
+    
+    if encodeSortableFloat(math.Copysign(0, -1)) != encodeSortableFloat(0) {
+        t.Errorf("-0 et +0 devraient avoir le même encodage")
+    }
+}
+
+func TestDecodeSortableFloatInvalid(t *testing.T) {
+    for _, s := range []string{"", "abc", "zzzzzzzzzzzzzzzz", "80000000000000000", "campinas"} {
+        if f, ok := decodeSortableFloat(s); ok {
+            t.Errorf("decodeSortableFloat(%q) devrait échouer, obtenu %v", s, f)
+        }
+    }
+}
\ No newline at end of file
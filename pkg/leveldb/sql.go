@@ -0,0 +1,578 @@
+// pkg/leveldb/sql.go
+// Langage de requête de type SQL: SELECT ... FROM type WHERE ... ORDER BY ... LIMIT n
+
+package leveldb
+
+import (
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Grammaire (mots-clés insensibles à la casse):
+//
+//     requête := SELECT colonnes FROM type [WHERE cond] [ORDER BY champ [ASC|DESC]] [LIMIT n]
+//     colonnes := '*' | COUNT(*) | champ (',' champ)*
+//     cond    := et (OR et)*
+//     et      := non (AND non)*
+//     non     := NOT non | '(' cond ')' | champ op valeur | champ [NOT] IN '(' valeur (',' valeur)* ')'
+//     op      := '=' | '!=' | '<>' | '<' | '<=' | '>' | '>='
+//     valeur  := 'chaîne' | nombre | mot
+//
+// La colonne "key" désigne la clé primaire. Les égalités et IN sur champs indexés
+// et les plages sur index numériques passent par le planificateur (Query).
+//
+//     SELECT key, category FROM product WHERE weight_g > 500 AND category = 'perfumaria' ORDER BY weight_g LIMIT 20
+
+// SQLQuery est une requête analysée
+type SQLQuery struct {
+    Columns    []string // vide = '*'
+    Count      bool     // SELECT COUNT(*)
+    RecordType string
+    Where      Predicate
+    OrderBy    string
+    Desc       bool
+    Limit      int
+}
+
+// SQLResult contient les lignes d'une requête, colonne par colonne
+type SQLResult struct {
+    Columns []string
+    Rows    [][]interface{}
+    Plan    string
+}
+
+// ExecSQL analyse et exécute une requête
+func (idx *Indexer) ExecSQL(src string) (*SQLResult, error) {
+    q, err := ParseSQL(src)
+    if err != nil {
+        return nil, err
+    }
+    return idx.RunSQL(q)
+}
+
+// RunSQL exécute une requête analysée. Sans ORDER BY, les lignes suivent l'ordre
+// des clés primaires et LIMIT arrête le parcours; avec ORDER BY, le résultat est
+// trié en mémoire avant d'être tronqué.
+func (idx *Indexer) RunSQL(q *SQLQuery) (*SQLResult, error) {
+    result, _, err := idx.runSQL(q, false)
+    return result, err
+}
+
+// runSQL exécute une requête et décrit son exécution; avec trace, les flux du
+// planificateur mesurent aussi leurs lignes et leur temps (voir ExplainSQL)
+func (idx *Indexer) runSQL(q *SQLQuery, trace bool) (*SQLResult, *Explain, error) {
+    query := Query{RecordType: q.RecordType, Where: q.Where}
+    if q.OrderBy == "" && !q.Count {
+        query.Limit = q.Limit
+    }
+    
+    explain := &Explain{}
+    start := time.Now()
+    cursor, err := idx.query(query, trace)
+    if err != nil {
+        return nil, nil, err
+    }
+    defer cursor.Close()
+    explain.AddStage("planification", time.Since(start))
+    
+    result := &SQLResult{Plan: cursor.Plan()}
+    
+    start = time.Now()
+    if q.Count {
+        n := 0
+        for cursor.Next() {
+            n++
+        }
+        if err := cursor.Error(); err != nil {
+            return nil, nil, fmt.Errorf("erreur exécution requête: %v", err)
+        }
+        elapsed := time.Since(start)
+        explain.AddStage("exécution", elapsed)
+        explain.Plan = &PlanStep{Op: "COUNT", Estimate: 1, Rows: 1, Duration: elapsed, Children: []*PlanStep{cursor.planStep()}}
+        explain.Rows = 1
+        
+        result.Columns = []string{"count"}
+        result.Rows = [][]interface{}{{n}}
+        return result, explain, nil
+    }
+    
+    fetch := &PlanStep{Op: "FETCH", Detail: "lecture des documents"}
+    var docs []joinDoc
+    for cursor.Next() {
+        fetch.Reads++
+        data, err := idx.getDocument(cursor.Key())
+        if err != nil {
+            return nil, nil, err
+        }
+        if data != nil {
+            docs = append(docs, joinDoc{key: cursor.Key(), data: data})
+        }
+    }
+    if err := cursor.Error(); err != nil {
+        return nil, nil, fmt.Errorf("erreur exécution requête: %v", err)
+    }
+    fetch.Duration = time.Since(start)
+    fetch.Rows = len(docs)
+    fetch.Children = []*PlanStep{cursor.planStep()}
+    fetch.Estimate = fetch.Children[0].Estimate
+    explain.AddStage("exécution", fetch.Duration)
+    explain.Plan = fetch
+    
+    if q.OrderBy != "" {
+        start = time.Now()
+        sortDocs(docs, q.OrderBy, q.Desc)
+        elapsed := time.Since(start)
+        explain.AddStage("tri", elapsed)
+        
+        detail := q.OrderBy
+        if q.Desc {
+            detail += " DESC"
+        }
+        explain.Plan = &PlanStep{Op: "SORT", Detail: detail, Estimate: fetch.Estimate, Rows: len(docs), Duration: fetch.Duration + elapsed, Children: []*PlanStep{fetch}}
+        
+        if q.Limit > 0 && len(docs) > q.Limit {
+            docs = docs[:q.Limit]
+        }
+        if q.Limit > 0 {
+            estimate := q.Limit
+            if fetch.Estimate >= 0 && fetch.Estimate < estimate {
+                estimate = fetch.Estimate
+            }
+            explain.Plan = &PlanStep{Op: "LIMIT", Detail: strconv.Itoa(q.Limit), Estimate: estimate, Rows: len(docs), Duration: explain.Plan.Duration, Children: []*PlanStep{explain.Plan}}
+        }
+    }
+    
+    start = time.Now()
+    result.Columns = q.Columns
+    if len(result.Columns) == 0 {
+        result.Columns = starColumns(docs)
+    }
+    
+    for _, doc := range docs {
+        row := make([]interface{}, len(result.Columns))
+        for i, col := range result.Columns {
+            row[i] = columnValue(doc, col)
+        }
+        result.Rows = append(result.Rows, row)
+    }
+    explain.AddStage("projection", time.Since(start))
+    explain.Rows = len(result.Rows)
+    
+    return result, explain, nil
+}
+
+
+// starColumns retourne "key" suivi des champs présents dans les documents, triés
+func starColumns(docs []joinDoc) []string {
+    seen := make(map[string]bool)
+    var fields []string
+    for _, doc := range docs {
+        for f := range doc.data {
+            if !seen[f] {
+                seen[f] = true
+                fields = append(fields, f)
+            }
+        }
+    }
+    sort.Strings(fields)
+    return append([]string{"key"}, fields...)
+}
+
+// columnValue retourne la valeur d'une colonne: clé primaire, champ, ou valeurs
+// atteintes par un chemin JSON (une seule ou une liste)
+func columnValue(doc joinDoc, col string) interface{} {
+    if col == "key" {
+        return doc.key
+    }
+    return FieldValue(doc.data, col)
+}
+
+// sortDocs trie par un champ: numériquement si les deux valeurs sont des nombres,
+// sinon comme chaînes; les documents sans valeur viennent en dernier
+func sortDocs(docs []joinDoc, field string, desc bool) {
+    sort.SliceStable(docs, func(i, j int) bool {
+        a, aok := sortKey(docs[i], field)
+        b, bok := sortKey(docs[j], field)
+        if !aok || !bok {
+            return aok && !bok
+        }
+        
+        var cmp int
+        fa, aNum := toFloat(a)
+        fb, bNum := toFloat(b)
+        if aNum && bNum {
+            cmp = compareFloats(fa, fb)
+        } else {
+            cmp = strings.Compare(a, b)
+        }
+        if desc {
+            return cmp > 0
+        }
+        return cmp < 0
+    })
+}
+
+func sortKey(doc joinDoc, field string) (string, bool) {
+    if field == "key" {
+        return doc.key, true
+    }
+    values := fieldScalars(doc.data, field)
+    if len(values) == 0 {
+        return "", false
+    }
+    return values[0], true
+}
+
+// Jetons du langage
+type sqlToken struct {
+    kind byte // 'i' identifiant/mot-clé, 's' chaîne, 'n' nombre, 'p' ponctuation/opérateur
+    text string
+    pos  int
+}
+
+type sqlParser struct {
+    tokens []sqlToken
+    pos    int
+}
+
+// ParseSQL analyse une requête
+func ParseSQL(src string) (*SQLQuery, error) {
+    tokens, err := lexSQL(src)
+    if err != nil {
+        return nil, err
+    }
+    
+    p := &sqlParser{tokens: tokens}
+    q, err := p.parseQuery()
+    if err != nil {
+        return nil, err
+    }
+    if t, ok := p.peek(); ok {
+        return nil, p.errorf(t, "élément inattendu %q", t.text)
+    }
+    return q, nil
+}
+
+func lexSQL(src string) ([]sqlToken, error) {
+    var tokens []sqlToken
+    i := 0
+    for i < len(src) {
+        c := src[i]
+        switch {
+        case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+            i++
+        
+        case c == '\'':
+            // '' dans une chaîne représente une apostrophe
+            var sb strings.Builder
+            j := i + 1
+            for {
+                if j >= len(src) {
+                    return nil, fmt.Errorf("requête invalide (position %d): chaîne non terminée", i)
+                }
+                if src[j] == '\'' {
+                    if j+1 < len(src) && src[j+1] == '\'' {
+                        sb.WriteByte('\'')
+                        j += 2
+                        continue
+                    }
+                    break
+                }
+                sb.WriteByte(src[j])
+                j++
+            }
+            tokens = append(tokens, sqlToken{kind: 's', text: sb.String(), pos: i})
+            i = j + 1
+        
+        case c >= '0' && c <= '9' || c == '-' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+            j := i + 1
+            for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+                j++
+            }
+            tokens = append(tokens, sqlToken{kind: 'n', text: src[i:j], pos: i})
+            i = j
+        
+        case isIdentByte(c) || c == '$':
+            j := i
+            for j < len(src) {
+                d := src[j]
+                if isIdentByte(d) || d >= '0' && d <= '9' || d == '.' || d == '$' {
+                    j++
+                } else if d == '[' {
+                    end := strings.IndexByte(src[j:], ']')
+                    if end < 0 {
+                        return nil, fmt.Errorf("requête invalide (position %d): ']' attendu", j)
+                    }
+                    j += end + 1
+                } else {
+                    break
+                }
+            }
+            tokens = append(tokens, sqlToken{kind: 'i', text: src[i:j], pos: i})
+            i = j
+        
+        default:
+            op := string(c)
+            if i+1 < len(src) {
+                switch two := src[i : i+2]; two {
+                case "<=", ">=", "!=", "<>":
+                    op = two
+                }
+            }
+            if !strings.Contains("=<>!(),*", op[:1]) || op == "!" {
+                return nil, fmt.Errorf("requête invalide (position %d): caractère inattendu %q", i, c)
+            }
+            tokens = append(tokens, sqlToken{kind: 'p', text: op, pos: i})
+            i += len(op)
+        }
+    }
+    return tokens, nil
+}
+
+func (p *sqlParser) errorf(t sqlToken, format string, args ...interface{}) error {
+    return fmt.Errorf("requête invalide (position %d): %s", t.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *sqlParser) peek() (sqlToken, bool) {
+    if p.pos >= len(p.tokens) {
+        return sqlToken{}, false
+    }
+    return p.tokens[p.pos], true
+}
+
+// keyword consomme le mot-clé kw s'il est le prochain jeton
+func (p *sqlParser) keyword(kw string) bool {
+    t, ok := p.peek()
+    if ok && t.kind == 'i' && strings.EqualFold(t.text, kw) {
+        p.pos++
+        return true
+    }
+    return false
+}
+
+// punct consomme la ponctuation s si elle est le prochain jeton
+func (p *sqlParser) punct(s string) bool {
+    t, ok := p.peek()
+    if ok && t.kind == 'p' && t.text == s {
+        p.pos++
+        return true
+    }
+    return false
+}
+
+func (p *sqlParser) expect(what string, ok bool) error {
+    if ok {
+        return nil
+    }
+    if t, more := p.peek(); more {
+        return p.errorf(t, "attendu: %s, obtenu %q", what, t.text)
+    }
+    return fmt.Errorf("requête invalide: fin de requête inattendue (attendu: %s)", what)
+}
+
+func (p *sqlParser) ident(what string) (string, error) {
+    t, ok := p.peek()
+    if !ok || t.kind != 'i' {
+        return "", p.expect(what, false)
+    }
+    p.pos++
+    if isJSONPath(t.text) {
+        if _, err := parsePath(t.text); err != nil {
+            return "", err
+        }
+    }
+    return t.text, nil
+}
+
+func (p *sqlParser) parseQuery() (*SQLQuery, error) {
+    q := &SQLQuery{}
+    if err := p.expect("SELECT", p.keyword("select")); err != nil {
+        return nil, err
+    }
+    
+    switch {
+    case p.punct("*"):
+    case p.keyword("count"):
+        if err := p.expect("(*)", p.punct("(") && p.punct("*") && p.punct(")")); err != nil {
+            return nil, err
+        }
+        q.Count = true
+    default:
+        for {
+            col, err := p.ident("colonne")
+            if err != nil {
+                return nil, err
+            }
+            q.Columns = append(q.Columns, col)
+            if !p.punct(",") {
+                break
+            }
+        }
+    }
+    
+    if err := p.expect("FROM", p.keyword("from")); err != nil {
+        return nil, err
+    }
+    recordType, err := p.ident("type d'enregistrement")
+    if err != nil {
+        return nil, err
+    }
+    q.RecordType = recordType
+    
+    if p.keyword("where") {
+        if q.Where, err = p.parseOr(); err != nil {
+            return nil, err
+        }
+    }
+    
+    if p.keyword("order") {
+        if err := p.expect("BY", p.keyword("by")); err != nil {
+            return nil, err
+        }
+        if q.OrderBy, err = p.ident("champ de tri"); err != nil {
+            return nil, err
+        }
+        if p.keyword("desc") {
+            q.Desc = true
+        } else {
+            p.keyword("asc")
+        }
+    }
+    
+    if p.keyword("limit") {
+        t, ok := p.peek()
+        n, convErr := strconv.Atoi(t.text)
+        if !ok || t.kind != 'n' || convErr != nil || n < 0 {
+            return nil, p.expect("nombre après LIMIT", false)
+        }
+        p.pos++
+        q.Limit = n
+    }
+    
+    return q, nil
+}
+
+func (p *sqlParser) parseOr() (Predicate, error) {
+    var preds []Predicate
+    for {
+        pred, err := p.parseAnd()
+        if err != nil {
+            return nil, err
+        }
+        preds = append(preds, pred)
+        if !p.keyword("or") {
+            break
+        }
+    }
+    if len(preds) == 1 {
+        return preds[0], nil
+    }
+    return Or(preds...), nil
+}
+
+func (p *sqlParser) parseAnd() (Predicate, error) {
+    var preds []Predicate
+    for {
+        pred, err := p.parseNot()
+        if err != nil {
+            return nil, err
+        }
+        preds = append(preds, pred)
+        if !p.keyword("and") {
+            break
+        }
+    }
+    if len(preds) == 1 {
+        return preds[0], nil
+    }
+    return And(preds...), nil
+}
+
+func (p *sqlParser) parseNot() (Predicate, error) {
+    if p.keyword("not") {
+        pred, err := p.parseNot()
+        if err != nil {
+            return nil, err
+        }
+        return Not(pred), nil
+    }
+    
+    if p.punct("(") {
+        pred, err := p.parseOr()
+        if err != nil {
+            return nil, err
+        }
+        return pred, p.expect("')'", p.punct(")"))
+    }
+    
+    field, err := p.ident("champ")
+    if err != nil {
+        return nil, err
+    }
+    
+    negate := p.keyword("not")
+    if p.keyword("in") {
+        values, err := p.parseList()
+        if err != nil {
+            return nil, err
+        }
+        if negate {
+            return Not(In(field, values...)), nil
+        }
+        return In(field, values...), nil
+    }
+    if negate {
+        return nil, p.expect("IN après NOT", false)
+    }
+    
+    t, ok := p.peek()
+    if !ok || t.kind != 'p' {
+        return nil, p.expect("opérateur", false)
+    }
+    p.pos++
+    
+    value, err := p.parseValue()
+    if err != nil {
+        return nil, err
+    }
+    
+    switch t.text {
+    case "=":
+        return Eq(field, value), nil
+    case "!=", "<>":
+        return Not(Eq(field, value)), nil
+    case "<", "<=", ">", ">=":
+        return Range(field, t.text, value), nil
+    }
+    return nil, p.errorf(t, "opérateur inconnu %q", t.text)
+}
+
+func (p *sqlParser) parseList() ([]string, error) {
+    if err := p.expect("'('", p.punct("(")); err != nil {
+        return nil, err
+    }
+    var values []string
+    for {
+        v, err := p.parseValue()
+        if err != nil {
+            return nil, err
+        }
+        values = append(values, v)
+        if !p.punct(",") {
+            break
+        }
+    }
+    return values, p.expect("')'", p.punct(")"))
+}
+
+func (p *sqlParser) parseValue() (string, error) {
+    t, ok := p.peek()
+    if !ok || t.kind == 'p' {
+        return "", p.expect("valeur", false)
+    }
+    p.pos++
+    return t.text, nil
+}
\ No newline at end of file
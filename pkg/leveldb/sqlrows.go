@@ -0,0 +1,188 @@
+// pkg/leveldb/sqlrows.go
+// Lecture en flux des lignes d'une requête, avec les métadonnées des documents (hash, timestamp, nœud)
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+)
+
+// SQLRows parcourt les lignes d'une requête au fil du curseur, sans les
+// matérialiser. Deux cas restent en mémoire: ORDER BY (tri avant la première
+// ligne) et COUNT(*). Pour SELECT *, les colonnes sont celles du premier
+// document (RunSQL prend l'union des champs de tous les documents).
+type SQLRows struct {
+    idx      *Indexer
+    cursor   *Cursor
+    columns  []string
+    pending  []joinDoc        // lignes lues d'avance (tri, premier document)
+    entries  map[string]*Entry // métadonnées des lignes lues d'avance
+    buffered bool             // toutes les lignes sont dans pending
+    countRow []interface{}
+    
+    key    string
+    entry  *Entry
+    values []interface{}
+    err    error
+}
+
+// OpenSQL analyse une requête et ouvre ses lignes; l'appelant doit appeler Close
+func (idx *Indexer) OpenSQL(src string) (*SQLRows, error) {
+    q, err := ParseSQL(src)
+    if err != nil {
+        return nil, err
+    }
+    
+    query := Query{RecordType: q.RecordType, Where: q.Where}
+    if q.OrderBy == "" && !q.Count {
+        query.Limit = q.Limit
+    }
+    cursor, err := idx.query(query, false)
+    if err != nil {
+        return nil, err
+    }
+    
+    rows := &SQLRows{idx: idx, cursor: cursor, columns: q.Columns, entries: make(map[string]*Entry)}
+    switch {
+    case q.Count:
+        n := 0
+        for cursor.Next() {
+            n++
+        }
+        rows.columns = []string{"count"}
+        rows.countRow = []interface{}{n}
+        rows.buffered = true
+    case q.OrderBy != "":
+        for rows.readAhead() {
+        }
+        sortDocs(rows.pending, q.OrderBy, q.Desc)
+        if q.Limit > 0 && len(rows.pending) > q.Limit {
+            rows.pending = rows.pending[:q.Limit]
+        }
+        rows.buffered = true
+    case len(rows.columns) == 0:
+        rows.readAhead()
+    }
+    
+    if err := cursor.Error(); err != nil {
+        cursor.Close()
+        return nil, fmt.Errorf("erreur exécution requête: %v", err)
+    }
+    if rows.err != nil {
+        cursor.Close()
+        return nil, rows.err
+    }
+    if len(rows.columns) == 0 {
+        rows.columns = starColumns(rows.pending)
+    }
+    return rows, nil
+}
+
+// readAhead lit le document suivant du curseur dans pending
+func (r *SQLRows) readAhead() bool {
+    doc, entry, ok := r.read()
+    if ok {
+        r.pending = append(r.pending, doc)
+        r.entries[doc.key] = entry
+    }
+    return ok
+}
+
+// read avance le curseur jusqu'au prochain document lisible
+func (r *SQLRows) read() (joinDoc, *Entry, bool) {
+    for r.cursor.Next() {
+        key := r.cursor.Key()
+        value, err := r.idx.db.Get([]byte(key), nil)
+        if err == leveldb.ErrNotFound {
+            continue
+        }
+        if err != nil {
+            r.err = fmt.Errorf("erreur lecture %s: %v", key, err)
+            return joinDoc{}, nil, false
+        }
+        
+        var entry Entry
+        if err := json.Unmarshal(value, &entry); err != nil {
+            continue
+        }
+        var data map[string]interface{}
+        if err := json.Unmarshal(entry.Data, &data); err != nil {
+            continue
+        }
+        return joinDoc{key: key, data: data}, &entry, true
+    }
+    return joinDoc{}, nil, false
+}
+
+// Next passe à la ligne suivante
+func (r *SQLRows) Next() bool {
+    if r.err != nil {
+        return false
+    }
+    if r.countRow != nil {
+        r.values, r.countRow = r.countRow, nil
+        return true
+    }
+    
+    var doc joinDoc
+    switch {
+    case len(r.pending) > 0:
+        doc, r.pending = r.pending[0], r.pending[1:]
+        r.entry = r.entries[doc.key]
+        delete(r.entries, doc.key)
+    case r.buffered:
+        return false
+    default:
+        var ok bool
+        if doc, r.entry, ok = r.read(); !ok {
+            if r.err == nil {
+                r.err = r.cursor.Error()
+            }
+            return false
+        }
+    }
+    
+    r.key = doc.key
+    r.values = make([]interface{}, len(r.columns))
+    for i, col := range r.columns {
+        r.values[i] = columnValue(doc, col)
+    }
+    return true
+}
+
+// Columns retourne les noms de colonnes
+func (r *SQLRows) Columns() []string {
+    return r.columns
+}
+
+// Values retourne les valeurs de la ligne courante, dans l'ordre des colonnes
+func (r *SQLRows) Values() []interface{} {
+    return r.values
+}
+
+// Key retourne la clé primaire de la ligne courante ("" pour COUNT(*))
+func (r *SQLRows) Key() string {
+    return r.key
+}
+
+// Entry retourne l'entrée stockée de la ligne courante (hash, timestamp,
+// nœud), nil pour COUNT(*)
+func (r *SQLRows) Entry() *Entry {
+    return r.entry
+}
+
+// Plan décrit le chemin d'accès choisi
+func (r *SQLRows) Plan() string {
+    return r.cursor.Plan()
+}
+
+func (r *SQLRows) Error() error {
+    return r.err
+}
+
+func (r *SQLRows) Close() {
+    r.cursor.Close()
+}
\ No newline at end of file
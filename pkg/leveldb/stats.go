@@ -0,0 +1,361 @@
+// pkg/leveldb/stats.go
+// Statistiques des index: cardinalité, valeurs fréquentes et taille, tenues à jour par compteurs
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Les compteurs d'un index déclaré sont maintenus dans le même lot que ses entrées:
+//
+//     _istats:<type>:<nom>          → {"entries":N,"distinct":D}
+//     _icount:<type>:<nom>:<valeur> → nombre d'entrées de la valeur
+//
+// Stats n'a alors qu'à lire l'en-tête et un compteur par valeur distincte.
+const (
+    statsHeaderPrefix = "_istats:"
+    statsCountPrefix  = "_icount:"
+)
+
+// ValueCount est le nombre d'entrées d'une valeur d'index
+type ValueCount struct {
+    Value string `json:"value"`
+    Count int    `json:"count"`
+}
+
+// IndexStats décrit la sélectivité et l'encombrement d'un index
+type IndexStats struct {
+    RecordType  string       `json:"record_type"`
+    Field       string       `json:"field"`
+    Entries     int          `json:"entries"`  // entrées d'index
+    Distinct    int          `json:"distinct"` // valeurs distinctes
+    AvgPerValue float64      `json:"avg_per_value"`
+    Top         []ValueCount `json:"top"`
+    ApproxBytes int64        `json:"approx_bytes"` // taille sur disque de la plage idx: (db.SizeOf)
+    Maintained  bool         `json:"maintained"`   // compteurs tenus à jour (sinon calculé par parcours)
+    UpdatedAt   string       `json:"updated_at,omitempty"`
+}
+
+// statsHeader est l'en-tête _istats: d'un index
+type statsHeader struct {
+    Entries   int    `json:"entries"`
+    Distinct  int    `json:"distinct"`
+    UpdatedAt string `json:"updated_at"`
+}
+
+func statsHeaderKey(recordType, name string) string {
+    return fmt.Sprintf("%s%s:%s", statsHeaderPrefix, recordType, name)
+}
+
+func statsCountKeyPrefix(recordType, name string) string {
+    return fmt.Sprintf("%s%s:%s:", statsCountPrefix, recordType, name)
+}
+
+// Stats retourne les statistiques d'un index et ses topN valeurs les plus
+// fréquentes. Sans compteurs (index non déclaré ou antérieur), l'index est parcouru.
+func (idx *Indexer) Stats(recordType, field string, topN int) (*IndexStats, error) {
+    stats := &IndexStats{RecordType: recordType, Field: field}
+    
+    def, err := idx.GetIndexDef(recordType, field)
+    if err != nil {
+        return nil, err
+    }
+    
+    header, err := idx.readStatsHeader(recordType, field)
+    if err != nil {
+        return nil, err
+    }
+    
+    var counts []ValueCount
+    if header != nil {
+        stats.Maintained = true
+        stats.Entries = header.Entries
+        stats.Distinct = header.Distinct
+        stats.UpdatedAt = header.UpdatedAt
+        counts, err = idx.readValueCounts(recordType, field)
+    } else {
+        counts, err = idx.countIndexValues(recordType, field)
+        for _, vc := range counts {
+            stats.Entries += vc.Count
+        }
+        stats.Distinct = len(counts)
+    }
+    if err != nil {
+        return nil, err
+    }
+    
+    if stats.Distinct > 0 {
+        stats.AvgPerValue = float64(stats.Entries) / float64(stats.Distinct)
+    }
+    
+    sort.SliceStable(counts, func(i, j int) bool {
+        return counts[i].Count > counts[j].Count
+    })
+    if topN >= 0 && len(counts) > topN {
+        counts = counts[:topN]
+    }
+    for i := range counts {
+        counts[i].Value = def.displayValue(counts[i].Value)
+    }
+    stats.Top = counts
+    
+    sizes, err := idx.db.SizeOf([]util.Range{*util.BytesPrefix([]byte(indexPrefix(recordType, field)))})
+    if err == nil {
+        stats.ApproxBytes = sizes.Sum()
+    }
+    
+    return stats, nil
+}
+
+// RefreshStats recalcule les compteurs d'un index à partir de ses entrées
+// (après une réparation ou pour un index créé avant les compteurs)
+func (idx *Indexer) RefreshStats(recordType, field string) error {
+    counts, err := idx.countIndexValues(recordType, field)
+    if err != nil {
+        return err
+    }
+    
+    m := make(map[string]int, len(counts))
+    for _, vc := range counts {
+        m[vc.Value] = vc.Count
+    }
+    return idx.writeStats(recordType, field, m)
+}
+
+// writeStats remplace les compteurs d'un index
+func (idx *Indexer) writeStats(recordType, name string, counts map[string]int) error {
+    if err := idx.deletePrefix(statsCountKeyPrefix(recordType, name)); err != nil {
+        return err
+    }
+    
+    batch := new(leveldb.Batch)
+    header := statsHeader{Distinct: len(counts), UpdatedAt: time.Now().Format(time.RFC3339)}
+    
+    for value, n := range counts {
+        header.Entries += n
+        batch.Put([]byte(statsCountKeyPrefix(recordType, name)+value), []byte(strconv.Itoa(n)))
+        
+        if batch.Len() >= rebuildBatchSize {
+            if err := idx.db.Write(batch, nil); err != nil {
+                return fmt.Errorf("erreur écriture lot: %v", err)
+            }
+            batch.Reset()
+        }
+    }
+    
+    headerBytes, _ := json.Marshal(header)
+    batch.Put([]byte(statsHeaderKey(recordType, name)), headerBytes)
+    
+    if err := idx.db.Write(batch, nil); err != nil {
+        return fmt.Errorf("erreur écriture lot: %v", err)
+    }
+    return nil
+}
+
+// dropStats supprime les compteurs d'un index
+func (idx *Indexer) dropStats(recordType, name string) error {
+    if err := idx.deletePrefix(statsCountKeyPrefix(recordType, name)); err != nil {
+        return err
+    }
+    return idx.db.Delete([]byte(statsHeaderKey(recordType, name)), nil)
+}
+
+func (idx *Indexer) readStatsHeader(recordType, name string) (*statsHeader, error) {
+    value, err := idx.db.Get([]byte(statsHeaderKey(recordType, name)), nil)
+    if err == leveldb.ErrNotFound {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("erreur lecture statistiques: %v", err)
+    }
+    
+    var header statsHeader
+    if err := json.Unmarshal(value, &header); err != nil {
+        return nil, fmt.Errorf("statistiques invalides pour %s:%s: %v", recordType, name, err)
+    }
+    return &header, nil
+}
+
+// estimateValue retourne le nombre d'entrées d'une valeur d'index pour le
+// planificateur: le compteur _icount: si l'index en tient, sinon un parcours
+// des entrées de la valeur
+func (idx *Indexer) estimateValue(recordType, name, normalizedValue string) int {
+    if header, err := idx.readStatsHeader(recordType, name); err == nil && header != nil {
+        raw, err := idx.db.Get([]byte(statsCountKeyPrefix(recordType, name)+normalizedValue), nil)
+        if err == leveldb.ErrNotFound {
+            return 0
+        }
+        if err == nil {
+            if n, err := strconv.Atoi(string(raw)); err == nil {
+                return n
+            }
+        }
+    }
+    return countPrefix(idx.db, indexValuePrefix(recordType, name, normalizedValue))
+}
+
+// estimateRange estime le nombre d'entrées d'une plage d'index: somme des
+// compteurs de ses valeurs si l'index en tient, sinon taille de la plage sur
+// disque (db.SizeOf) rapportée à celle d'une entrée échantillon
+func (idx *Indexer) estimateRange(recordType, name string, values []string, rng *util.Range) int {
+    if header, err := idx.readStatsHeader(recordType, name); err == nil && header != nil {
+        total := 0
+        for _, v := range values {
+            if raw, err := idx.db.Get([]byte(statsCountKeyPrefix(recordType, name)+v), nil); err == nil {
+                n, _ := strconv.Atoi(string(raw))
+                total += n
+            }
+        }
+        return total
+    }
+    
+    iter := idx.db.NewIterator(rng, nil)
+    entryBytes := 0
+    if iter.Next() {
+        entryBytes = len(iter.Key()) + len(iter.Value())
+    }
+    iter.Release()
+    
+    // Une plage encore en mémoire (memtable) n'a pas de taille sur disque
+    estimate := len(values)
+    if sizes, err := idx.db.SizeOf([]util.Range{*rng}); err == nil && entryBytes > 0 {
+        if n := int(sizes.Sum()) / entryBytes; n > estimate {
+            estimate = n
+        }
+    }
+    return estimate
+}
+
+// readValueCounts lit les compteurs par valeur
+func (idx *Indexer) readValueCounts(recordType, name string) ([]ValueCount, error) {
+    prefix := statsCountKeyPrefix(recordType, name)
+    
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    var counts []ValueCount
+    for iter.Next() {
+        n, err := strconv.Atoi(string(iter.Value()))
+        if err != nil {
+            continue
+        }
+        counts = append(counts, ValueCount{Value: string(iter.Key()[len(prefix):]), Count: n})
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur itération: %v", err)
+    }
+    return counts, nil
+}
+
+// countIndexValues compte les entrées par valeur en parcourant l'index; les
+// entrées d'une même valeur sont contiguës
+func (idx *Indexer) countIndexValues(recordType, name string) ([]ValueCount, error) {
+    prefix := indexPrefix(recordType, name)
+    
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    
+    var counts []ValueCount
+    for iter.Next() {
+        primaryKey, _ := decodeIndexValue(iter.Value())
+        value := strings.TrimSuffix(strings.TrimPrefix(string(iter.Key()), prefix), ":"+primaryKey)
+        
+        if n := len(counts); n > 0 && counts[n-1].Value == value {
+            counts[n-1].Count++
+            continue
+        }
+        counts = append(counts, ValueCount{Value: value, Count: 1})
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur itération: %v", err)
+    }
+    return counts, nil
+}
+
+// statsDelta accumule les variations de compteurs d'un lot d'écriture
+type statsDelta struct {
+    counts map[string]map[string]int // "<type>:<nom>" → valeur → variation
+}
+
+func (d *statsDelta) add(def *IndexDef, value string, n int) {
+    if d.counts == nil {
+        d.counts = make(map[string]map[string]int)
+    }
+    
+    name := def.RecordType + ":" + def.IndexName()
+    if d.counts[name] == nil {
+        d.counts[name] = make(map[string]int)
+    }
+    d.counts[name][value] += n
+}
+
+// apply ajoute au lot les compteurs et en-têtes mis à jour. Les index sans
+// en-tête (compteurs jamais initialisés) sont ignorés.
+func (d *statsDelta) apply(db *leveldb.DB, batch *leveldb.Batch) error {
+    for name, deltas := range d.counts {
+        headerKey := []byte(statsHeaderPrefix + name)
+        
+        value, err := db.Get(headerKey, nil)
+        if err == leveldb.ErrNotFound {
+            continue
+        }
+        if err != nil {
+            return fmt.Errorf("erreur lecture statistiques: %v", err)
+        }
+        
+        var header statsHeader
+        if err := json.Unmarshal(value, &header); err != nil {
+            continue
+        }
+        
+        for v, delta := range deltas {
+            if delta == 0 {
+                continue
+            }
+            
+            countKey := []byte(statsCountPrefix + name + ":" + v)
+            current := 0
+            if raw, err := db.Get(countKey, nil); err == nil {
+                current, _ = strconv.Atoi(string(raw))
+            } else if err != leveldb.ErrNotFound {
+                return fmt.Errorf("erreur lecture statistiques: %v", err)
+            }
+            
+            updated := current + delta
+            if updated < 0 {
+                updated = 0
+            }
+            
+            switch {
+            case current == 0 && updated > 0:
+                header.Distinct++
+            case current > 0 && updated == 0:
+                header.Distinct--
+            }
+            header.Entries += updated - current
+            
+            if updated == 0 {
+                batch.Delete(countKey)
+            } else {
+                batch.Put(countKey, []byte(strconv.Itoa(updated)))
+            }
+        }
+        
+        header.UpdatedAt = time.Now().Format(time.RFC3339)
+        headerBytes, _ := json.Marshal(header)
+        batch.Put(headerKey, headerBytes)
+    }
+    
+    return nil
+}
\ No newline at end of file
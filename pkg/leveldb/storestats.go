@@ -0,0 +1,262 @@
+// pkg/leveldb/storestats.go
+// Statistiques de la base: préfixes de clés, distribution des tailles et état interne de goleveldb
+
+package leveldb
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// PrefixStats décrit les clés d'un préfixe (order, idx:seller, _mtime...).
+// ApproxBytes vient de db.SizeOf: taille compressée dans les tables, sans le
+// journal ni la memtable (0 tant que les écritures récentes ne sont pas compactées).
+type PrefixStats struct {
+    Prefix      string `json:"prefix"`
+    Keys        int    `json:"keys"`
+    KeyBytes    int64  `json:"key_bytes"`
+    ValueBytes  int64  `json:"value_bytes"`
+    ApproxBytes int64  `json:"approx_bytes"`
+    
+    exact bool // clé sans ':' (_config): le préfixe est la clé elle-même
+}
+
+// SizeHistogram est une distribution de tailles en classes de puissances de 2:
+// Buckets[i] compte les tailles dans ]2^(i-1), 2^i] (Buckets[0]: 0 ou 1 octet)
+type SizeHistogram struct {
+    Count   int   `json:"count"`
+    Min     int   `json:"min"`
+    Max     int   `json:"max"`
+    Total   int64 `json:"total"`
+    Buckets []int `json:"buckets"`
+}
+
+func (h *SizeHistogram) add(n int) {
+    if h.Count == 0 || n < h.Min {
+        h.Min = n
+    }
+    if n > h.Max {
+        h.Max = n
+    }
+    h.Count++
+    h.Total += int64(n)
+    
+    b := 0
+    for 1<<uint(b) < n {
+        b++
+    }
+    for len(h.Buckets) <= b {
+        h.Buckets = append(h.Buckets, 0)
+    }
+    h.Buckets[b]++
+}
+
+// Avg retourne la taille moyenne
+func (h *SizeHistogram) Avg() float64 {
+    if h.Count == 0 {
+        return 0
+    }
+    return float64(h.Total) / float64(h.Count)
+}
+
+// BucketLimit retourne la borne haute (incluse) de la classe i
+func (h *SizeHistogram) BucketLimit(i int) int {
+    return 1 << uint(i)
+}
+
+// LevelStats décrit un niveau de l'arbre LSM et ses compactions
+type LevelStats struct {
+    Level      int           `json:"level"`
+    Tables     int           `json:"tables"`
+    SizeBytes  int64         `json:"size_bytes"`
+    ReadBytes  int64         `json:"compaction_read_bytes"`
+    WriteBytes int64         `json:"compaction_write_bytes"`
+    Duration   time.Duration `json:"compaction_duration_ns"`
+}
+
+// EngineStats regroupe l'état interne de goleveldb (DB.Stats et propriétés
+// leveldb.*). goleveldb v1.0.0 n'expose pas de compteur de succès du cache de
+// blocs: seuls sa taille et les réutilisations du pool de tampons
+// (leveldb.blockpool) sont disponibles.
+type EngineStats struct {
+    Levels           []LevelStats  `json:"levels"`
+    IOReadBytes      uint64        `json:"io_read_bytes"`
+    IOWriteBytes     uint64        `json:"io_write_bytes"`
+    WriteDelayCount  int32         `json:"write_delay_count"`
+    WriteDelay       time.Duration `json:"write_delay_ns"`
+    WritePaused      bool          `json:"write_paused"`
+    AliveSnapshots   int32         `json:"alive_snapshots"`
+    AliveIterators   int32         `json:"alive_iterators"`
+    OpenedTables     int           `json:"opened_tables"`
+    BlockCacheBytes  int           `json:"block_cache_bytes"`
+    BufferPoolGets   int64         `json:"buffer_pool_gets"`
+    BufferPoolMisses int64         `json:"buffer_pool_misses"`
+    Stats            string        `json:"leveldb_stats"`    // propriété leveldb.stats
+    SSTables         string        `json:"leveldb_sstables"` // propriété leveldb.sstables
+}
+
+// BufferPoolHits retourne les tampons servis par le pool sans allocation
+func (e *EngineStats) BufferPoolHits() int64 {
+    return e.BufferPoolGets - e.BufferPoolMisses
+}
+
+// StoreStats est le résultat de Client.StoreStats
+type StoreStats struct {
+    Prefixes   []PrefixStats `json:"prefixes"`
+    KeySizes   SizeHistogram `json:"key_sizes"`
+    ValueSizes SizeHistogram `json:"value_sizes"`
+    Engine     EngineStats   `json:"engine"`
+}
+
+// StoreStats parcourt toutes les clés une fois: les préfixes sont découverts
+// (segment avant le premier ':', deux segments pour idx:<type>), puis leur
+// taille sur disque est estimée par db.SizeOf
+func (c *Client) StoreStats() (*StoreStats, error) {
+    stats := &StoreStats{}
+    byPrefix := make(map[string]*PrefixStats)
+    
+    iter := c.db.NewIterator(nil, nil)
+    for iter.Next() {
+        key, value := iter.Key(), iter.Value()
+        stats.KeySizes.add(len(key))
+        stats.ValueSizes.add(len(value))
+    
+        prefix, exact := keyPrefix(string(key))
+        ps, ok := byPrefix[prefix]
+        if !ok {
+            ps = &PrefixStats{Prefix: prefix, exact: exact}
+            byPrefix[prefix] = ps
+        }
+        ps.Keys++
+        ps.KeyBytes += int64(len(key))
+        ps.ValueBytes += int64(len(value))
+    }
+    
+    // Libéré avant la lecture de l'état interne, qui compte les itérateurs actifs
+    err := iter.Error()
+    iter.Release()
+    if err != nil {
+        return nil, fmt.Errorf("erreur itération: %v", err)
+    }
+    
+    // Un seul appel à SizeOf pour tous les préfixes
+    ranges := make([]util.Range, 0, len(byPrefix))
+    for _, ps := range byPrefix {
+        stats.Prefixes = append(stats.Prefixes, *ps)
+    }
+    sort.Slice(stats.Prefixes, func(i, j int) bool { return stats.Prefixes[i].Prefix < stats.Prefixes[j].Prefix })
+    for _, ps := range stats.Prefixes {
+        if ps.exact {
+            ranges = append(ranges, util.Range{Start: []byte(ps.Prefix), Limit: []byte(ps.Prefix + "\x00")})
+        } else {
+            ranges = append(ranges, *util.BytesPrefix([]byte(ps.Prefix + ":")))
+        }
+    }
+    sizes, err := c.db.SizeOf(ranges)
+    if err != nil {
+        return nil, fmt.Errorf("erreur estimation des tailles: %v", err)
+    }
+    for i := range stats.Prefixes {
+        stats.Prefixes[i].ApproxBytes = sizes[i]
+    }
+    
+    if err := engineStats(c.db, &stats.Engine); err != nil {
+        return nil, err
+    }
+    
+    return stats, nil
+}
+
+// keyPrefix retourne le préfixe de regroupement d'une clé, et vrai si la clé
+// n'a pas de ':' (elle forme alors son propre préfixe)
+func keyPrefix(key string) (string, bool) {
+    i := strings.IndexByte(key, ':')
+    if i < 0 {
+        return key, true
+    }
+    if key[:i] == "idx" {
+        if j := strings.IndexByte(key[i+1:], ':'); j >= 0 {
+            return key[:i+1+j], false
+        }
+    }
+    return key[:i], false
+}
+
+// engineStats lit DB.Stats et les propriétés leveldb.*
+func engineStats(db *leveldb.DB, e *EngineStats) error {
+    var s leveldb.DBStats
+    if err := db.Stats(&s); err != nil {
+        return fmt.Errorf("erreur lecture statistiques internes: %v", err)
+    }
+    
+    var err error
+    if e.Stats, err = db.GetProperty("leveldb.stats"); err != nil {
+        return fmt.Errorf("erreur lecture leveldb.stats: %v", err)
+    }
+    
+    // DB.Stats omet les niveaux vides sans compaction: leur numéro est relu
+    // dans la première colonne de leveldb.stats, qui applique la même règle
+    levels := statsLevels(e.Stats)
+    for i := range s.LevelSizes {
+        level := i
+        if len(levels) == len(s.LevelSizes) {
+            level = levels[i]
+        }
+        e.Levels = append(e.Levels, LevelStats{
+            Level:      level,
+            Tables:     s.LevelTablesCounts[i],
+            SizeBytes:  s.LevelSizes[i],
+            ReadBytes:  s.LevelRead[i],
+            WriteBytes: s.LevelWrite[i],
+            Duration:   s.LevelDurations[i],
+        })
+    }
+    e.IOReadBytes = s.IORead
+    e.IOWriteBytes = s.IOWrite
+    e.WriteDelayCount = s.WriteDelayCount
+    e.WriteDelay = s.WriteDelayDuration
+    e.WritePaused = s.WritePaused
+    e.AliveSnapshots = s.AliveSnapshots
+    e.AliveIterators = s.AliveIterators
+    e.OpenedTables = s.OpenedTablesCount
+    e.BlockCacheBytes = s.BlockCacheSize
+    
+    if e.SSTables, err = db.GetProperty("leveldb.sstables"); err != nil {
+        return fmt.Errorf("erreur lecture leveldb.sstables: %v", err)
+    }
+    
+    // BufferPool{B·%d Z·%v Zm·%v Zh·%v G·%d P·%d H·%d <·%d =·%d >·%d M·%d}
+    if pool, err := db.GetProperty("leveldb.blockpool"); err == nil {
+        for _, field := range strings.Fields(strings.TrimSuffix(strings.TrimPrefix(pool, "BufferPool{"), "}")) {
+            if strings.HasPrefix(field, "G·") {
+                fmt.Sscanf(strings.TrimPrefix(field, "G·"), "%d", &e.BufferPoolGets)
+            } else if strings.HasPrefix(field, "M·") {
+                fmt.Sscanf(strings.TrimPrefix(field, "M·"), "%d", &e.BufferPoolMisses)
+            }
+        }
+    }
+    
+    return nil
+}
+
+// statsLevels lit les numéros de niveau du tableau de leveldb.stats
+//
+//     Compactions
+//      Level |   Tables   |    Size(MB)   | ...
+//     -------+------------+---------------+ ...
+//        0   |          2 |       0.00123 | ...
+func statsLevels(text string) []int {
+    var levels []int
+    for _, line := range strings.Split(text, "\n") {
+        var level int
+        if _, err := fmt.Sscanf(line, " %d |", &level); err == nil {
+            levels = append(levels, level)
+        }
+    }
+    return levels
+}
\ No newline at end of file
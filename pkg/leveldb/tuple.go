@@ -0,0 +1,65 @@
+// pkg/leveldb/tuple.go
+// Encodage de tuples de chaînes préservant l'ordre, utilisable dans les clés LevelDB
+
+package leveldb
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Chaque élément est terminé par \x00\x01; un \x00 interne est échappé en \x00\xff.
+// L'encodage est sans préfixe ambigu et respecte l'ordre lexicographique élément
+// par élément: ("a") < ("a", "b") < ("ab").
+const (
+    tupleEnd    = "\x00\x01"
+    tupleEscape = "\x00\xff"
+)
+
+// encodeTuple encode une liste d'éléments
+func encodeTuple(elems []string) string {
+    var b strings.Builder
+    for _, e := range elems {
+        b.WriteString(strings.ReplaceAll(e, "\x00", tupleEscape))
+        b.WriteString(tupleEnd)
+    }
+    return b.String()
+}
+
+// decodeTuple décode un tuple complet
+func decodeTuple(s string) ([]string, error) {
+    var elems []string
+    var cur strings.Builder
+    
+    for i := 0; i < len(s); i++ {
+        if s[i] != 0 {
+            cur.WriteByte(s[i])
+            continue
+        }
+        if i+1 >= len(s) {
+            return nil, fmt.Errorf("tuple tronqué")
+        }
+        switch s[i+1] {
+        case 0x01:
+            elems = append(elems, cur.String())
+            cur.Reset()
+        case 0xff:
+            cur.WriteByte(0)
+        default:
+            return nil, fmt.Errorf("séquence d'échappement invalide dans un tuple")
+        }
+        i++
+    }
+    
+    if cur.Len() > 0 {
+        return nil, fmt.Errorf("tuple non terminé")
+    }
+    return elems, nil
+}
+
+// tupleUpperBound retourne la borne (exclusive) couvrant tous les tuples qui
+// commencent par les éléments encodés dans prefix
+func tupleUpperBound(prefix string) string {
+    // 0xff n'apparaît jamais en UTF-8 ni comme premier octet après un terminateur
+    return prefix + "\xff"
+}
\ No newline at end of file
@@ -0,0 +1,104 @@
+// pkg/leveldb/tuple_test.go
+// Tests de l'encodage des tuples: aller-retour, ordre et bornes de préfixe
+
+package leveldb
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestTupleRoundTrip(t *testing.T) {
+    cases := [][]string{
+        {"a"},
+        {"a", "b"},
+        {""},
+        {"", ""},
+        {"sp", "são paulo"},
+        {"a\x00b", "\x00"},
+        {"x:y", "a+b", "\x01\xff"},
+    }
+    
+    for _, elems := range cases {
+        decoded, err := decodeTuple(encodeTuple(elems))
+        if err != nil {
+            t.Errorf("decodeTuple(encodeTuple(%q)): %v", elems, err)
+            continue
+        }
+        if !reflect.DeepEqual(decoded, elems) {
+            t.Errorf("aller-retour de %q: obtenu %q", elems, decoded)
+        }
+    }
+    
+    if decoded, err := decodeTuple(encodeTuple(nil)); err != nil || len(decoded) != 0 {
+        t.Errorf("tuple vide: obtenu %q, %v", decoded, err)
+    }
+}
+
+// L'ordre des tuples encodés est l'ordre lexicographique élément par élément
+func TestTupleOrder(t *testing.T) {
+    ordered := [][]string{
+        nil,
+        {""},
+        {"", "a"},
+        {"a"},
+        {"a", ""},
+        {"a", "b"},
+        {"a", "b", "c"},
+        {"a", "c"},
+        {"a\x00"},
+        {"a\x00b"},
+        {"ab"},
+        {"b"},
+    }
+    
+    for i := 1; i < len(ordered); i++ {
+        prev, cur := encodeTuple(ordered[i-1]), encodeTuple(ordered[i])
+        if prev >= cur {
+            t.Errorf("encodeTuple(%q) = %q devrait précéder encodeTuple(%q) = %q", ordered[i-1], prev, ordered[i], cur)
+        }
+    }
+}
+
+func TestDecodeTupleErrors(t *testing.T) {
+    cases := map[string]string{
+        "non terminé":          "a",
+        "tronqué":              "a\x00",
+        "échappement invalide": "a\x00\x02",
+        "reste non terminé":    "a\x00\x01b",
+    }
+    
+    for name, s := range cases {
+        if _, err := decodeTuple(s); err == nil {
+            t.Errorf("%s: decodeTuple(%q) devrait échouer", name, s)
+        }
+    }
+}
+
+// tupleUpperBound couvre exactement les tuples qui prolongent le préfixe
+func TestTupleUpperBound(t *testing.T) {
+    prefix := encodeTuple([]string{"sp"})
+    bound := tupleUpperBound(prefix)
+    
+    cases := []struct {
+        elems  []string
+        inside bool
+    }{
+        {[]string{"sp"}, true},
+        {[]string{"sp", ""}, true},
+        {[]string{"sp", "campinas"}, true},
+        {[]string{"sp", "zzz", "é"}, true},
+        {[]string{"s"}, false},
+        {[]string{"sp\x00"}, false},
+        {[]string{"spa"}, false},
+        {[]string{"sq"}, false},
+    }
+    
+    for _, c := range cases {
+        enc := encodeTuple(c.elems)
+        inside := enc >= prefix && enc < bound
+        if inside != c.inside {
+            t.Errorf("%q dans [%q, %q): obtenu %v, attendu %v", c.elems, prefix, bound, inside, c.inside)
+        }
+    }
+}
\ No newline at end of file
@@ -0,0 +1,177 @@
+// pkg/leveldb/types.go
+// Découverte des types d'enregistrement à partir des préfixes de clés et des entrées _namespace:
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+    
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Préfixe des entrées de namespace écrites par cmd/setup:
+//
+//     _namespace:orders → {"name":"orders","type":"commercial_transaction","prefix":"order","fields":[...]}
+const namespacePrefix = "_namespace:"
+
+// RecordType décrit un type d'enregistrement (order pour les clés order:xxx)
+type RecordType struct {
+    Name        string   `json:"name"`
+    Namespace   string   `json:"namespace,omitempty"`
+    Description string   `json:"description,omitempty"`
+    Fields      []string `json:"fields"`    // champs affichés pour un résultat
+    Documents   bool     `json:"documents"` // au moins une clé <type>:... existe
+    
+    ledgerType string // ledger_type du premier document, pour rattacher le namespace
+}
+
+// RecordTypes liste les types présents dans la base, triés par nom.
+//
+// Les préfixes de clés sont lus par sauts (un Seek par type, sans parcourir les
+// documents). Les champs affichés viennent du namespace s'il en déclare, sinon
+// du premier document du type. Un namespace est rattaché au type de son champ
+// "prefix", sinon au type dont les documents ont le même ledger_type, sinon au
+// singulier de son nom (orders → order).
+func (idx *Indexer) RecordTypes() ([]RecordType, error) {
+    byName := make(map[string]*RecordType)
+    
+    iter := idx.db.NewIterator(nil, nil)
+    defer iter.Release()
+    
+    for ok := iter.First(); ok; {
+        key := string(iter.Key())
+        name := recordTypeOf(key)
+    
+        switch {
+        case strings.HasPrefix(key, "_"):
+            // Clés système: sauter tout l'espace "_"
+            ok = iter.Seek(util.BytesPrefix([]byte("_")).Limit)
+        case name == "":
+            ok = iter.Next()
+        case name == "idx":
+            ok = iter.Seek(util.BytesPrefix([]byte("idx:")).Limit)
+        default:
+            rt := &RecordType{Name: name, Documents: true}
+            if data, err := decodeDocument(iter.Value()); err == nil {
+                rt.Fields = sampleFields(key, data)
+                rt.ledgerType, _ = data["ledger_type"].(string)
+            }
+            byName[name] = rt
+            ok = iter.Seek(util.BytesPrefix([]byte(name + ":")).Limit)
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur itération: %v", err)
+    }
+    
+    if err := idx.attachNamespaces(byName); err != nil {
+        return nil, err
+    }
+    
+    types := make([]RecordType, 0, len(byName))
+    for _, rt := range byName {
+        types = append(types, *rt)
+    }
+    sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+    return types, nil
+}
+
+// attachNamespaces rattache chaque entrée _namespace: à un type, créé s'il
+// n'a pas encore de documents
+func (idx *Indexer) attachNamespaces(byName map[string]*RecordType) error {
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte(namespacePrefix)), nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        var ns struct {
+            Name        string   `json:"name"`
+            Description string   `json:"description"`
+            Type        string   `json:"type"`
+            Prefix      string   `json:"prefix"`
+            Fields      []string `json:"fields"`
+        }
+        if err := json.Unmarshal(iter.Value(), &ns); err != nil {
+            continue
+        }
+        if ns.Name == "" {
+            ns.Name = strings.TrimPrefix(string(iter.Key()), namespacePrefix)
+        }
+    
+        name := ns.Prefix
+        if name == "" && ns.Type != "" {
+            for _, rt := range byName {
+                if rt.ledgerType == ns.Type {
+                    name = rt.Name
+                    break
+                }
+            }
+        }
+        if name == "" {
+            name = strings.TrimSuffix(ns.Name, "s")
+        }
+    
+        rt, ok := byName[name]
+        if !ok {
+            rt = &RecordType{Name: name}
+            byName[name] = rt
+        }
+        rt.Namespace = ns.Name
+        rt.Description = ns.Description
+        if len(ns.Fields) > 0 {
+            rt.Fields = ns.Fields
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return fmt.Errorf("erreur lecture namespaces: %v", err)
+    }
+    return nil
+}
+
+// sampleFields retourne les champs d'un document, triés, sans ledger_type ni
+// le champ identifiant (celui qui répète la clé: product_id pour product:<id>)
+func sampleFields(key string, data map[string]interface{}) []string {
+    id := key[strings.Index(key, ":")+1:]
+    
+    var fields []string
+    for f, v := range data {
+        if f == "ledger_type" || v == id {
+            continue
+        }
+        fields = append(fields, f)
+    }
+    sort.Strings(fields)
+    return fields
+}
+
+// TypesWithIndex retourne les types qui ont un index nommé name (définition
+// enregistrée ou entrées idx:<type>:<name>:)
+func (idx *Indexer) TypesWithIndex(name string) ([]string, error) {
+    types, err := idx.RecordTypes()
+    if err != nil {
+        return nil, err
+    }
+    
+    var found []string
+    for _, rt := range types {
+        def, err := idx.GetIndexDef(rt.Name, name)
+        if err != nil {
+            return nil, err
+        }
+        if def != nil || idx.hasPrefix(indexPrefix(rt.Name, name)) {
+            found = append(found, rt.Name)
+        }
+    }
+    return found, nil
+}
+
+// hasPrefix indique si au moins une clé commence par prefix
+func (idx *Indexer) hasPrefix(prefix string) bool {
+    iter := idx.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+    defer iter.Release()
+    return iter.Next()
+}
\ No newline at end of file
@@ -0,0 +1,566 @@
+// pkg/leveldb/views.go
+// Vues map/reduce maintenues incrémentalement à partir du journal des changements
+
+package leveldb
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "strings"
+    
+    "github.com/syndtr/goleveldb/leveldb"
+    "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Nombre de changements appliqués par lot lors de la mise à jour d'une vue
+const viewBatchSize = 1000
+
+// EmitFunc publie une ligne de vue: clé composée et valeur
+type EmitFunc func(key []string, value interface{})
+
+// MapFunc est appelée pour chaque document écrit; elle émet zéro ou plusieurs lignes
+type MapFunc func(key string, doc map[string]interface{}, emit EmitFunc)
+
+// Reducer réduit les valeurs d'une clé (Reduce) puis combine des résultats
+// partiels (Rereduce) lors des regroupements par niveau ou par plage
+type Reducer interface {
+    Reduce(values []interface{}) interface{}
+    Rereduce(partials []interface{}) interface{}
+}
+
+// View décrit une vue: fonction map, réduction optionnelle et filtre de type
+type View struct {
+    Name       string
+    RecordType string // limite la vue aux clés <type>:... (vide = tous les documents)
+    Map        MapFunc
+    Reduce     Reducer
+}
+
+// ViewQuery sélectionne des lignes d'une vue. StartKey et EndKey sont inclusifs;
+// EndKey couvre aussi toutes les clés plus longues qui la prolongent.
+type ViewQuery struct {
+    StartKey   []string
+    EndKey     []string
+    Reduce     bool // appliquer la réduction (sinon lignes brutes)
+    Group      bool // regrouper par clé exacte
+    GroupLevel int  // regrouper par les N premiers éléments de la clé
+}
+
+// ViewRow est une ligne de résultat: ID est la clé du document (lignes brutes)
+type ViewRow struct {
+    Key   []string    `json:"key"`
+    Value interface{} `json:"value"`
+    ID    string      `json:"id,omitempty"`
+}
+
+func viewPrefix(name string) string {
+    return fmt.Sprintf("_view:%s:", name)
+}
+
+func viewRowPrefix(name string) string    { return viewPrefix(name) + "row:" }
+func viewReducePrefix(name string) string { return viewPrefix(name) + "red:" }
+func viewDocKey(name, docKey string) string { return viewPrefix(name) + "doc:" + docKey }
+func viewSeqKey(name string) string       { return viewPrefix(name) + "seq" }
+
+// RegisterView déclare une vue sur le client et la met à jour depuis le journal.
+// Une vue n'est pas persistée (ses fonctions sont du code Go): elle doit être
+// déclarée à chaque ouverture; son état (lignes, réductions, position) l'est.
+func (c *Client) RegisterView(v *View) error {
+    if v.Name == "" || strings.Contains(v.Name, ":") || v.Map == nil {
+        return fmt.Errorf("vue invalide: nom (sans ':') et fonction map requis")
+    }
+    
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    
+    c.views[v.Name] = v
+    return c.updateView(v)
+}
+
+// ResetView efface l'état d'une vue pour la reconstruire depuis le début du
+// journal, ou depuis les documents si le journal a été purgé
+func (c *Client) ResetView(name string) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    
+    if err := NewIndexer(c.db).deletePrefix(viewPrefix(name)); err != nil {
+        return fmt.Errorf("erreur réinitialisation vue %s: %v", name, err)
+    }
+    
+    if v, ok := c.views[name]; ok {
+        return c.updateView(v)
+    }
+    return nil
+}
+
+// QueryView met la vue à jour puis retourne les lignes demandées
+func (c *Client) QueryView(name string, q ViewQuery) ([]ViewRow, error) {
+    c.mu.Lock()
+    v, ok := c.views[name]
+    var err error
+    if ok {
+        err = c.updateView(v)
+    }
+    c.mu.Unlock()
+    
+    if !ok {
+        return nil, fmt.Errorf("vue inconnue: %s", name)
+    }
+    if err != nil {
+        return nil, err
+    }
+    
+    if q.Reduce && v.Reduce != nil {
+        return c.queryReduced(v, q)
+    }
+    return c.queryRows(v, q)
+}
+
+// updateViews applique les nouveaux changements à toutes les vues (c.mu tenu),
+// après une écriture déjà validée: un échec est conservé pour ViewError
+func (c *Client) updateViews() {
+    c.viewErr = nil
+    for _, v := range c.views {
+        if err := c.updateView(v); err != nil {
+            c.viewErr = fmt.Errorf("erreur mise à jour vue %s: %v", v.Name, err)
+        }
+    }
+}
+
+// ViewError retourne l'échec de la mise à jour des vues après la dernière
+// écriture (nil si elles sont à jour). L'écriture elle-même a réussi: la vue
+// reprendra depuis sa position dans le journal à la prochaine mise à jour.
+func (c *Client) ViewError() error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.viewErr
+}
+
+// updateView consomme le journal depuis la position de la vue (c.mu tenu)
+func (c *Client) updateView(v *View) error {
+    since := uint64(0)
+    if raw, err := c.db.Get([]byte(viewSeqKey(v.Name)), nil); err == nil {
+        json.Unmarshal(raw, &since)
+    }
+    
+    // Journal purgé avant la position de la vue (vue nouvelle ou réinitialisée):
+    // la vue est construite depuis les documents actuels
+    if since < c.seq {
+        first, err := firstChangeSeq(c.db)
+        if err != nil {
+            return fmt.Errorf("erreur lecture journal: %v", err)
+        }
+        if first > since+1 {
+            return c.buildView(v)
+        }
+    }
+    
+    for since < c.seq {
+        changes, err := readChanges(c.db, since, viewBatchSize)
+        if err != nil {
+            return err
+        }
+        if len(changes) == 0 {
+            break
+        }
+        
+        batch := new(leveldb.Batch)
+        affected := make(map[string][]string) // clé encodée → clé émise
+        pending := make(map[string][][]string) // document → clés émises, non encore écrites
+        
+        for _, change := range changes {
+            if err := c.applyViewChange(v, batch, change, affected, pending); err != nil {
+                return err
+            }
+            since = change.Seq
+        }
+        
+        // Écrire les lignes avant de recalculer les réductions des clés touchées
+        if err := c.db.Write(batch, nil); err != nil {
+            return err
+        }
+        batch.Reset()
+        
+        for encoded, key := range affected {
+            if err := c.rereduceKey(v, batch, encoded, key); err != nil {
+                return err
+            }
+        }
+        
+        seqBytes, _ := json.Marshal(since)
+        batch.Put([]byte(viewSeqKey(v.Name)), seqBytes)
+        
+        if err := c.db.Write(batch, nil); err != nil {
+            return err
+        }
+    }
+    
+    return nil
+}
+
+// buildView reconstruit une vue depuis les documents stockés et la place à la
+// position actuelle du journal (c.mu tenu)
+func (c *Client) buildView(v *View) error {
+    if err := NewIndexer(c.db).deletePrefix(viewPrefix(v.Name)); err != nil {
+        return fmt.Errorf("erreur réinitialisation vue %s: %v", v.Name, err)
+    }
+    
+    var rng *util.Range
+    if v.RecordType != "" {
+        rng = util.BytesPrefix([]byte(v.RecordType + ":"))
+    }
+    
+    iter := c.db.NewIterator(rng, nil)
+    defer iter.Release()
+    
+    batch := new(leveldb.Batch)
+    affected := make(map[string][]string)
+    pending := make(map[string][][]string)
+    
+    // Écrire les lignes d'un lot puis recalculer les réductions des clés touchées
+    flush := func() error {
+        if err := c.db.Write(batch, nil); err != nil {
+            return err
+        }
+        batch.Reset()
+        for encoded, key := range affected {
+            if err := c.rereduceKey(v, batch, encoded, key); err != nil {
+                return err
+            }
+        }
+        if err := c.db.Write(batch, nil); err != nil {
+            return err
+        }
+        batch.Reset()
+        affected = make(map[string][]string)
+        pending = make(map[string][][]string)
+        return nil
+    }
+    
+    n := 0
+    for iter.Next() {
+        key := string(iter.Key())
+        if key == "" || key[0] == '_' || strings.HasPrefix(key, "idx:") {
+            continue
+        }
+        
+        change := Change{Op: "put", Key: key, Entry: append([]byte(nil), iter.Value()...)}
+        if err := c.applyViewChange(v, batch, change, affected, pending); err != nil {
+            return err
+        }
+        
+        n++
+        if n%viewBatchSize == 0 {
+            if err := flush(); err != nil {
+                return err
+            }
+        }
+    }
+    if err := iter.Error(); err != nil {
+        return fmt.Errorf("erreur itération: %v", err)
+    }
+    if err := flush(); err != nil {
+        return err
+    }
+    
+    seqBytes, _ := json.Marshal(c.seq)
+    return c.db.Put([]byte(viewSeqKey(v.Name)), seqBytes, nil)
+}
+
+// applyViewChange retire les lignes de l'ancienne version du document et émet celles de la nouvelle
+func (c *Client) applyViewChange(v *View, batch *leveldb.Batch, change Change, affected map[string][]string, pending map[string][][]string) error {
+    if v.RecordType != "" && recordTypeOf(change.Key) != v.RecordType {
+        return nil
+    }
+    
+    docKey := viewDocKey(v.Name, change.Key)
+    
+    // Lignes émises précédemment par ce document (dans ce lot ou sur disque)
+    oldKeys, inBatch := pending[change.Key]
+    if !inBatch {
+        if raw, err := c.db.Get([]byte(docKey), nil); err == nil {
+            json.Unmarshal(raw, &oldKeys)
+        }
+    }
+    for _, key := range oldKeys {
+        encoded := encodeTuple(key)
+        batch.Delete([]byte(viewRowPrefix(v.Name) + encodeTuple(append(key, change.Key))))
+        affected[encoded] = key
+    }
+    batch.Delete([]byte(docKey))
+    pending[change.Key] = nil
+    
+    if change.Op != "put" {
+        return nil
+    }
+    
+    var entry Entry
+    if err := json.Unmarshal(change.Entry, &entry); err != nil {
+        return nil
+    }
+    var doc map[string]interface{}
+    if err := json.Unmarshal(entry.Data, &doc); err != nil {
+        return nil
+    }
+    
+    // Plusieurs émissions d'une même clé par un document sont regroupées dans une ligne
+    emitted := make(map[string][]interface{})
+    var keys [][]string
+    
+    v.Map(change.Key, doc, func(key []string, value interface{}) {
+        encoded := encodeTuple(key)
+        if _, seen := emitted[encoded]; !seen {
+            keys = append(keys, append([]string(nil), key...))
+        }
+        emitted[encoded] = append(emitted[encoded], value)
+    })
+    
+    for _, key := range keys {
+        encoded := encodeTuple(key)
+        valuesBytes, err := json.Marshal(emitted[encoded])
+        if err != nil {
+            return fmt.Errorf("valeur émise non sérialisable pour %s: %v", change.Key, err)
+        }
+        batch.Put([]byte(viewRowPrefix(v.Name)+encodeTuple(append(key, change.Key))), valuesBytes)
+        affected[encoded] = key
+    }
+    
+    if len(keys) > 0 {
+        keysBytes, _ := json.Marshal(keys)
+        batch.Put([]byte(docKey), keysBytes)
+    }
+    pending[change.Key] = keys
+    
+    return nil
+}
+
+// rereduceKey recalcule la réduction stockée pour une clé émise exacte à partir
+// de ses lignes; rejouer un changement après une interruption reste sans effet
+func (c *Client) rereduceKey(v *View, batch *leveldb.Batch, encoded string, key []string) error {
+    if v.Reduce == nil {
+        return nil
+    }
+    
+    var values []interface{}
+    
+    iter := c.db.NewIterator(util.BytesPrefix([]byte(viewRowPrefix(v.Name)+encoded)), nil)
+    for iter.Next() {
+        rowKey, err := decodeTuple(strings.TrimPrefix(string(iter.Key()), viewRowPrefix(v.Name)))
+        if err != nil || len(rowKey) != len(key)+1 {
+            continue // clé plus longue qui prolonge la clé réduite
+        }
+        var rowValues []interface{}
+        if err := json.Unmarshal(iter.Value(), &rowValues); err == nil {
+            values = append(values, rowValues...)
+        }
+    }
+    iter.Release()
+    if err := iter.Error(); err != nil {
+        return err
+    }
+    
+    reduceKey := []byte(viewReducePrefix(v.Name) + encoded)
+    if len(values) == 0 {
+        batch.Delete(reduceKey)
+        return nil
+    }
+    
+    reducedBytes, err := json.Marshal(v.Reduce.Reduce(values))
+    if err != nil {
+        return fmt.Errorf("réduction non sérialisable: %v", err)
+    }
+    batch.Put(reduceKey, reducedBytes)
+    return nil
+}
+
+// viewRange construit la plage d'itération [StartKey, EndKey] sous un préfixe
+func viewRange(prefix string, q ViewQuery) *util.Range {
+    r := util.BytesPrefix([]byte(prefix))
+    if q.StartKey != nil {
+        r.Start = []byte(prefix + encodeTuple(q.StartKey))
+    }
+    if q.EndKey != nil {
+        r.Limit = []byte(tupleUpperBound(prefix + encodeTuple(q.EndKey)))
+    }
+    return r
+}
+
+func (c *Client) queryRows(v *View, q ViewQuery) ([]ViewRow, error) {
+    prefix := viewRowPrefix(v.Name)
+    
+    var rows []ViewRow
+    
+    iter := c.db.NewIterator(viewRange(prefix, q), nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        elems, err := decodeTuple(strings.TrimPrefix(string(iter.Key()), prefix))
+        if err != nil || len(elems) == 0 {
+            continue
+        }
+        var values []interface{}
+        if err := json.Unmarshal(iter.Value(), &values); err != nil {
+            continue
+        }
+        for _, value := range values {
+            rows = append(rows, ViewRow{Key: elems[:len(elems)-1], Value: value, ID: elems[len(elems)-1]})
+        }
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur itération: %v", err)
+    }
+    return rows, nil
+}
+
+// queryReduced combine les réductions stockées par clé exacte selon le niveau de regroupement
+func (c *Client) queryReduced(v *View, q ViewQuery) ([]ViewRow, error) {
+    prefix := viewReducePrefix(v.Name)
+    
+    var rows []ViewRow
+    var groupKey []string
+    var partials []interface{}
+    
+    flush := func() {
+        if len(partials) > 0 {
+            rows = append(rows, ViewRow{Key: groupKey, Value: v.Reduce.Rereduce(partials)})
+        }
+        partials = nil
+    }
+    
+    iter := c.db.NewIterator(viewRange(prefix, q), nil)
+    defer iter.Release()
+    
+    for iter.Next() {
+        key, err := decodeTuple(strings.TrimPrefix(string(iter.Key()), prefix))
+        if err != nil {
+            continue
+        }
+        var partial interface{}
+        if err := json.Unmarshal(iter.Value(), &partial); err != nil {
+            continue
+        }
+        
+        var group []string
+        switch {
+        case q.GroupLevel > 0 && len(key) > q.GroupLevel:
+            group = key[:q.GroupLevel]
+        case q.GroupLevel > 0 || q.Group:
+            group = key
+        default:
+            group = nil // réduction globale
+        }
+        
+        if partials != nil && encodeTuple(group) != encodeTuple(groupKey) {
+            flush()
+        }
+        groupKey = group
+        partials = append(partials, partial)
+    }
+    
+    if err := iter.Error(); err != nil {
+        return nil, fmt.Errorf("erreur itération: %v", err)
+    }
+    
+    flush()
+    return rows, nil
+}
+
+// ReduceCount compte les lignes
+var ReduceCount Reducer = countReducer{}
+
+// ReduceSum additionne les valeurs numériques
+var ReduceSum Reducer = sumReducer{}
+
+// ReduceStats calcule count, sum, min, max et sumsqr des valeurs numériques
+var ReduceStats Reducer = statsReducer{}
+
+// ReduceFunc adapte deux fonctions en Reducer personnalisé
+type ReduceFunc struct {
+    ReduceFn   func(values []interface{}) interface{}
+    RereduceFn func(partials []interface{}) interface{}
+}
+
+func (r ReduceFunc) Reduce(values []interface{}) interface{}    { return r.ReduceFn(values) }
+func (r ReduceFunc) Rereduce(partials []interface{}) interface{} { return r.RereduceFn(partials) }
+
+type countReducer struct{}
+
+func (countReducer) Reduce(values []interface{}) interface{} {
+    return float64(len(values))
+}
+
+func (countReducer) Rereduce(partials []interface{}) interface{} {
+    return sumReducer{}.Reduce(partials)
+}
+
+type sumReducer struct{}
+
+func (sumReducer) Reduce(values []interface{}) interface{} {
+    sum := 0.0
+    for _, v := range values {
+        if n, ok := toFloat(v); ok {
+            sum += n
+        }
+    }
+    return sum
+}
+
+func (r sumReducer) Rereduce(partials []interface{}) interface{} {
+    return r.Reduce(partials)
+}
+
+// ViewStats est le résultat de ReduceStats
+type ViewStats struct {
+    Count  float64 `json:"count"`
+    Sum    float64 `json:"sum"`
+    Min    float64 `json:"min"`
+    Max    float64 `json:"max"`
+    SumSqr float64 `json:"sumsqr"`
+}
+
+type statsReducer struct{}
+
+func (statsReducer) Reduce(values []interface{}) interface{} {
+    s := ViewStats{Min: math.Inf(1), Max: math.Inf(-1)}
+    for _, v := range values {
+        n, ok := toFloat(v)
+        if !ok {
+            continue
+        }
+        s.Count++
+        s.Sum += n
+        s.SumSqr += n * n
+        s.Min = math.Min(s.Min, n)
+        s.Max = math.Max(s.Max, n)
+    }
+    if s.Count == 0 {
+        s.Min, s.Max = 0, 0
+    }
+    return s
+}
+
+func (statsReducer) Rereduce(partials []interface{}) interface{} {
+    total := ViewStats{}
+    first := true
+    for _, p := range partials {
+        // Les résultats partiels relus depuis LevelDB sont des objets JSON décodés
+        var s ViewStats
+        raw, _ := json.Marshal(p)
+        if err := json.Unmarshal(raw, &s); err != nil || s.Count == 0 {
+            continue
+        }
+        if first || s.Min < total.Min {
+            total.Min = s.Min
+        }
+        if first || s.Max > total.Max {
+            total.Max = s.Max
+        }
+        first = false
+        total.Count += s.Count
+        total.Sum += s.Sum
+        total.SumSqr += s.SumSqr
+    }
+    return total
+}
\ No newline at end of file